@@ -3,6 +3,8 @@ import TaskList from "../views/TaskList.vue";
 import TaskCreate from "../views/TaskCreate.vue";
 import TaskDetail from "../views/TaskDetail.vue";
 import Settings from "../views/Settings.vue";
+import Login from "../views/Login.vue";
+import { useAuthStore } from "../stores/auth";
 
 const router = createRouter({
   history: createWebHistory(import.meta.env.BASE_URL),
@@ -33,7 +35,20 @@ const router = createRouter({
       name: "settings",
       component: Settings,
     },
+    {
+      path: "/login",
+      name: "login",
+      component: Login,
+      meta: { public: true },
+    },
   ],
 });
 
+router.beforeEach((to) => {
+  const authStore = useAuthStore();
+  if (!to.meta.public && !authStore.isAuthenticated) {
+    return { name: "login", query: { redirect: to.fullPath } };
+  }
+});
+
 export default router;
@@ -0,0 +1,132 @@
+import { defineStore } from "pinia";
+import { ref, computed } from "vue";
+import axios from "axios";
+
+const API_BASE_URL = "http://localhost:8080/api/v1";
+const STORAGE_KEY = "task-manager-auth";
+
+const loadSession = () => {
+  try {
+    return JSON.parse(localStorage.getItem(STORAGE_KEY)) || {};
+  } catch {
+    return {};
+  }
+};
+
+export const useAuthStore = defineStore("auth", () => {
+  const session = loadSession();
+  const accessToken = ref(session.accessToken || "");
+  const refreshToken = ref(session.refreshToken || "");
+  const user = ref(session.user || null);
+  const loading = ref(false);
+  const error = ref(null);
+
+  const api = axios.create({
+    baseURL: API_BASE_URL,
+    headers: {
+      "Content-Type": "application/json",
+    },
+  });
+
+  const isAuthenticated = computed(() => !!accessToken.value);
+
+  const saveSession = (data) => {
+    accessToken.value = data.access_token;
+    refreshToken.value = data.refresh_token;
+    user.value = data.user;
+    localStorage.setItem(
+      STORAGE_KEY,
+      JSON.stringify({
+        accessToken: accessToken.value,
+        refreshToken: refreshToken.value,
+        user: user.value,
+      })
+    );
+  };
+
+  const login = async (username, password) => {
+    loading.value = true;
+    error.value = null;
+    try {
+      const response = await api.post("/auth/login", { username, password });
+      saveSession(response.data);
+      return response.data;
+    } catch (err) {
+      error.value = err.response?.data?.error || "Failed to log in";
+      throw err;
+    } finally {
+      loading.value = false;
+    }
+  };
+
+  const register = async (username, password) => {
+    loading.value = true;
+    error.value = null;
+    try {
+      await api.post("/auth/register", { username, password });
+    } catch (err) {
+      error.value = err.response?.data?.error || "Failed to register";
+      throw err;
+    } finally {
+      loading.value = false;
+    }
+    return login(username, password);
+  };
+
+  const refresh = async () => {
+    const response = await api.post("/auth/refresh", {
+      refresh_token: refreshToken.value,
+    });
+    saveSession(response.data);
+    return response.data.access_token;
+  };
+
+  const logout = () => {
+    accessToken.value = "";
+    refreshToken.value = "";
+    user.value = null;
+    localStorage.removeItem(STORAGE_KEY);
+  };
+
+  // Adds the access token to every request made through client and
+  // refreshes it once when the server answers 401.
+  const attach = (client) => {
+    client.interceptors.request.use((config) => {
+      if (accessToken.value) {
+        config.headers.Authorization = `Bearer ${accessToken.value}`;
+      }
+      return config;
+    });
+
+    client.interceptors.response.use(undefined, async (err) => {
+      const original = err.config;
+      if (err.response?.status !== 401 || original._retried) {
+        throw err;
+      }
+      original._retried = true;
+      try {
+        const token = await refresh();
+        original.headers.Authorization = `Bearer ${token}`;
+        return client(original);
+      } catch {
+        logout();
+        window.location.assign("/login");
+        throw err;
+      }
+    });
+
+    return client;
+  };
+
+  return {
+    accessToken,
+    user,
+    loading,
+    error,
+    isAuthenticated,
+    login,
+    register,
+    logout,
+    attach,
+  };
+});
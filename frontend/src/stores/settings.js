@@ -1,6 +1,7 @@
 import { defineStore } from "pinia";
 import { ref } from "vue";
 import axios from "axios";
+import { useAuthStore } from "./auth";
 
 const API_BASE_URL = "http://localhost:8080/api/v1";
 
@@ -11,12 +12,14 @@ export const useSettingsStore = defineStore("settings", () => {
   const loading = ref(false);
   const error = ref(null);
 
-  const api = axios.create({
-    baseURL: API_BASE_URL,
-    headers: {
-      "Content-Type": "application/json",
-    },
-  });
+  const api = useAuthStore().attach(
+    axios.create({
+      baseURL: API_BASE_URL,
+      headers: {
+        "Content-Type": "application/json",
+      },
+    })
+  );
 
   const fetchSettings = async () => {
     loading.value = true;
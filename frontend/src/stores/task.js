@@ -1,6 +1,7 @@
 import { defineStore } from "pinia";
 import { ref, computed } from "vue";
 import axios from "axios";
+import { useAuthStore } from "./auth";
 
 const API_BASE_URL = "http://localhost:8080/api/v1";
 
@@ -10,12 +11,14 @@ export const useTaskStore = defineStore("task", () => {
   const loading = ref(false);
   const error = ref(null);
 
-  const api = axios.create({
-    baseURL: API_BASE_URL,
-    headers: {
-      "Content-Type": "application/json",
-    },
-  });
+  const api = useAuthStore().attach(
+    axios.create({
+      baseURL: API_BASE_URL,
+      headers: {
+        "Content-Type": "application/json",
+      },
+    })
+  );
 
   const isOpenAIError = computed(() => {
     if (!error.value) return false;
@@ -0,0 +1,708 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// View is the JSON shape of the configuration returned by the API. Secrets
+// are masked and durations are rendered as Go duration strings.
+type View struct {
+	Server        ServerView        `json:"server"`
+	Database      DatabaseView      `json:"database"`
+	OpenAI        OpenAIView        `json:"openai"`
+	Trash         TrashView         `json:"trash"`
+	Scheduling    SchedulingView    `json:"scheduling"`
+	Notifications NotificationsView `json:"notifications"`
+	Auth          AuthView          `json:"auth"`
+	Workload      WorkloadView      `json:"workload"`
+	Estimation    EstimationView    `json:"estimation"`
+	Forecast      ForecastView      `json:"forecast"`
+	Recurrence    RecurrenceView    `json:"recurrence"`
+	RateLimit     RateLimitView     `json:"rate_limit"`
+	Redaction     RedactionView     `json:"redaction"`
+	Urgency       UrgencyView       `json:"urgency"`
+}
+
+type ServerView struct {
+	Port                string              `json:"port"`
+	ConfigWatchInterval string              `json:"config_watch_interval"`
+	MaxBodyBytes        int64               `json:"max_body_bytes"`
+	TrustedProxies      []string            `json:"trusted_proxies"`
+	CORS                CORSView            `json:"cors"`
+	TLS                 TLSView             `json:"tls"`
+	SecurityHeaders     SecurityHeadersView `json:"security_headers"`
+}
+
+type CORSView struct {
+	AllowedOrigins   []string `json:"allowed_origins"`
+	AllowedMethods   []string `json:"allowed_methods"`
+	AllowedHeaders   []string `json:"allowed_headers"`
+	AllowCredentials bool     `json:"allow_credentials"`
+	MaxAge           string   `json:"max_age"`
+}
+
+// TLSView is read-only through the API; TLS changes need a restart.
+type TLSView struct {
+	Enabled      bool   `json:"enabled"`
+	CertFile     string `json:"cert_file"`
+	KeyFile      string `json:"key_file"`
+	RedirectHTTP bool   `json:"redirect_http"`
+	HTTPPort     string `json:"http_port"`
+	ReadOnly     bool   `json:"read_only"`
+}
+
+type SecurityHeadersView struct {
+	HSTSMaxAge            string `json:"hsts_max_age"`
+	ContentSecurityPolicy string `json:"content_security_policy"`
+	FrameOptions          string `json:"frame_options"`
+}
+
+// DatabaseView is read-only through the API.
+type DatabaseView struct {
+	Type     string     `json:"type"`
+	Name     string     `json:"name"`
+	Backup   BackupView `json:"backup"`
+	ReadOnly bool       `json:"read_only"`
+}
+
+type BackupView struct {
+	Dir      string `json:"dir"`
+	Interval string `json:"interval"`
+	Keep     int    `json:"keep"`
+}
+
+type OpenAIView struct {
+	APIKey    string `json:"api_key"`
+	APIKeySet bool   `json:"api_key_set"`
+	Model     string `json:"model"`
+	BaseURL   string `json:"base_url"`
+	UseLLM    bool   `json:"use_llm"`
+}
+
+type TrashView struct {
+	RetentionDays int    `json:"retention_days"`
+	PurgeInterval string `json:"purge_interval"`
+}
+
+type SchedulingView struct {
+	UrgentWithin        string `json:"urgent_within"`
+	HighWithin          string `json:"high_within"`
+	MediumWithin        string `json:"medium_within"`
+	RecalculateInterval string `json:"recalculate_interval"`
+}
+
+type NotificationsView struct {
+	WebhookURL    string   `json:"webhook_url"`
+	WebhookURLSet bool     `json:"webhook_url_set"`
+	Events        []string `json:"events"`
+}
+
+type AuthView struct {
+	AccessTokenTTL    string `json:"access_token_ttl"`
+	RefreshTokenTTL   string `json:"refresh_token_ttl"`
+	AllowRegistration bool   `json:"allow_registration"`
+	DefaultRole       string `json:"default_role"`
+}
+
+type WorkloadView struct {
+	WeeklyCapacityHours int `json:"weekly_capacity_hours"`
+}
+
+type EstimationView struct {
+	Calibrate  bool    `json:"calibrate"`
+	MinSamples int     `json:"min_samples"`
+	MaxSamples int     `json:"max_samples"`
+	MaxFactor  float64 `json:"max_factor"`
+}
+
+type ForecastView struct {
+	Trials     int `json:"trials"`
+	MinSamples int `json:"min_samples"`
+	MaxSamples int `json:"max_samples"`
+}
+
+type RecurrenceView struct {
+	UseLLM        bool   `json:"use_llm"`
+	LeadTime      string `json:"lead_time"`
+	CheckInterval string `json:"check_interval"`
+}
+
+type RateLimitView struct {
+	Enabled bool              `json:"enabled"`
+	Default RateLimitRuleView `json:"default"`
+	LLM     RateLimitRuleView `json:"llm"`
+}
+
+type RateLimitRuleView struct {
+	Requests int    `json:"requests"`
+	Period   string `json:"period"`
+	Burst    int    `json:"burst"`
+}
+
+type RedactionView struct {
+	Enabled        bool               `json:"enabled"`
+	Detectors      []string           `json:"detectors"`
+	CustomPatterns []RedactionPattern `json:"custom_patterns"`
+}
+
+type UrgencyView struct {
+	DefaultPolicy string              `json:"default_policy"`
+	Policies      []UrgencyPolicyView `json:"policies"`
+}
+
+// UrgencyPolicyView is also accepted in patches. Empty threshold durations
+// fall back to the scheduling thresholds.
+type UrgencyPolicyView struct {
+	Name                string             `json:"name"`
+	Curve               string             `json:"curve"`
+	Horizon             string             `json:"horizon"`
+	RemainingWorkWeight float64            `json:"remaining_work_weight"`
+	ManualPriorityBoost float64            `json:"manual_priority_boost"`
+	BlockedWeight       float64            `json:"blocked_weight"`
+	StatusWeights       map[string]float64 `json:"status_weights"`
+	UrgentWithin        string             `json:"urgent_within,omitempty"`
+	HighWithin          string             `json:"high_within,omitempty"`
+	MediumWithin        string             `json:"medium_within,omitempty"`
+}
+
+func (p UrgencyPolicyConfig) view() UrgencyPolicyView {
+	threshold := func(d time.Duration) string {
+		if d == 0 {
+			return ""
+		}
+		return d.String()
+	}
+	return UrgencyPolicyView{
+		Name:                p.Name,
+		Curve:               p.Curve,
+		Horizon:             p.Horizon.String(),
+		RemainingWorkWeight: p.RemainingWorkWeight,
+		ManualPriorityBoost: p.ManualPriorityBoost,
+		BlockedWeight:       p.BlockedWeight,
+		StatusWeights:       p.StatusWeights,
+		UrgentWithin:        threshold(p.UrgentWithin),
+		HighWithin:          threshold(p.HighWithin),
+		MediumWithin:        threshold(p.MediumWithin),
+	}
+}
+
+func (r RateLimitRule) view() RateLimitRuleView {
+	return RateLimitRuleView{
+		Requests: r.Requests,
+		Period:   r.Period.String(),
+		Burst:    r.Burst,
+	}
+}
+
+func (c *Config) View() View {
+	events := c.Notifications.Events
+	if events == nil {
+		events = []string{}
+	}
+	origins := c.Server.CORS.AllowedOrigins
+	if origins == nil {
+		origins = []string{}
+	}
+	proxies := c.Server.TrustedProxies
+	if proxies == nil {
+		proxies = []string{}
+	}
+	policies := make([]UrgencyPolicyView, 0, len(c.Urgency.Policies))
+	for _, policy := range c.Urgency.Policies {
+		policies = append(policies, policy.view())
+	}
+	patterns := c.Redaction.CustomPatterns
+	if patterns == nil {
+		patterns = []RedactionPattern{}
+	}
+
+	return View{
+		Server: ServerView{
+			Port:                c.Server.Port,
+			ConfigWatchInterval: c.Server.ConfigWatchInterval.String(),
+			MaxBodyBytes:        c.Server.MaxBodyBytes,
+			TrustedProxies:      proxies,
+			CORS: CORSView{
+				AllowedOrigins:   origins,
+				AllowedMethods:   c.Server.CORS.AllowedMethods,
+				AllowedHeaders:   c.Server.CORS.AllowedHeaders,
+				AllowCredentials: c.Server.CORS.AllowCredentials,
+				MaxAge:           c.Server.CORS.MaxAge.String(),
+			},
+			TLS: TLSView{
+				Enabled:      c.Server.TLS.Enabled,
+				CertFile:     c.Server.TLS.CertFile,
+				KeyFile:      c.Server.TLS.KeyFile,
+				RedirectHTTP: c.Server.TLS.RedirectHTTP,
+				HTTPPort:     c.Server.TLS.HTTPPort,
+				ReadOnly:     true,
+			},
+			SecurityHeaders: SecurityHeadersView{
+				HSTSMaxAge:            c.Server.SecurityHeaders.HSTSMaxAge.String(),
+				ContentSecurityPolicy: c.Server.SecurityHeaders.ContentSecurityPolicy,
+				FrameOptions:          c.Server.SecurityHeaders.FrameOptions,
+			},
+		},
+		Database: DatabaseView{
+			Type: c.Database.Type,
+			Name: c.Database.Name,
+			Backup: BackupView{
+				Dir:      c.Database.Backup.Dir,
+				Interval: c.Database.Backup.Interval.String(),
+				Keep:     c.Database.Backup.Keep,
+			},
+			ReadOnly: true,
+		},
+		OpenAI: OpenAIView{
+			APIKey:    MaskSecret(c.OpenAI.APIKey),
+			APIKeySet: c.OpenAI.APIKey != "",
+			Model:     c.OpenAI.Model,
+			BaseURL:   c.OpenAI.BaseURL,
+			UseLLM:    c.OpenAI.UseLLM,
+		},
+		Trash: TrashView{
+			RetentionDays: c.Trash.RetentionDays,
+			PurgeInterval: c.Trash.PurgeInterval.String(),
+		},
+		Scheduling: SchedulingView{
+			UrgentWithin:        c.Scheduling.UrgentWithin.String(),
+			HighWithin:          c.Scheduling.HighWithin.String(),
+			MediumWithin:        c.Scheduling.MediumWithin.String(),
+			RecalculateInterval: c.Scheduling.RecalculateInterval.String(),
+		},
+		Notifications: NotificationsView{
+			WebhookURL:    MaskURL(c.Notifications.WebhookURL),
+			WebhookURLSet: c.Notifications.WebhookURL != "",
+			Events:        events,
+		},
+		Auth: AuthView{
+			AccessTokenTTL:    c.Auth.AccessTokenTTL.String(),
+			RefreshTokenTTL:   c.Auth.RefreshTokenTTL.String(),
+			AllowRegistration: c.Auth.AllowRegistration,
+			DefaultRole:       c.Auth.DefaultRole,
+		},
+		Workload: WorkloadView{
+			WeeklyCapacityHours: c.Workload.WeeklyCapacityHours,
+		},
+		Estimation: EstimationView{
+			Calibrate:  c.Estimation.Calibrate,
+			MinSamples: c.Estimation.MinSamples,
+			MaxSamples: c.Estimation.MaxSamples,
+			MaxFactor:  c.Estimation.MaxFactor,
+		},
+		Forecast: ForecastView{
+			Trials:     c.Forecast.Trials,
+			MinSamples: c.Forecast.MinSamples,
+			MaxSamples: c.Forecast.MaxSamples,
+		},
+		Recurrence: RecurrenceView{
+			UseLLM:        c.Recurrence.UseLLM,
+			LeadTime:      c.Recurrence.LeadTime.String(),
+			CheckInterval: c.Recurrence.CheckInterval.String(),
+		},
+		RateLimit: RateLimitView{
+			Enabled: c.RateLimit.Enabled,
+			Default: c.RateLimit.Default.view(),
+			LLM:     c.RateLimit.LLM.view(),
+		},
+		Redaction: RedactionView{
+			Enabled:        c.Redaction.Enabled,
+			Detectors:      c.Redaction.Detectors,
+			CustomPatterns: patterns,
+		},
+		Urgency: UrgencyView{
+			DefaultPolicy: c.Urgency.DefaultPolicy,
+			Policies:      policies,
+		},
+	}
+}
+
+// Patch is a partial update accepted by the API. Only fields that are
+// present are changed. The OpenAI key is write-only and is stored through
+// SetOpenAIAPIKey rather than applied here.
+type Patch struct {
+	Server        *ServerPatch        `json:"server"`
+	Database      json.RawMessage     `json:"database"`
+	OpenAI        *OpenAIPatch        `json:"openai"`
+	Trash         *TrashPatch         `json:"trash"`
+	Scheduling    *SchedulingPatch    `json:"scheduling"`
+	Notifications *NotificationsPatch `json:"notifications"`
+	Auth          *AuthPatch          `json:"auth"`
+	Workload      *WorkloadPatch      `json:"workload"`
+	Estimation    *EstimationPatch    `json:"estimation"`
+	Forecast      *ForecastPatch      `json:"forecast"`
+	Recurrence    *RecurrencePatch    `json:"recurrence"`
+	RateLimit     *RateLimitPatch     `json:"rate_limit"`
+	Redaction     *RedactionPatch     `json:"redaction"`
+	Urgency       *UrgencyPatch       `json:"urgency"`
+}
+
+type ServerPatch struct {
+	Port                *string               `json:"port"`
+	ConfigWatchInterval *string               `json:"config_watch_interval"`
+	MaxBodyBytes        *int64                `json:"max_body_bytes"`
+	CORS                *CORSPatch            `json:"cors"`
+	TLS                 json.RawMessage       `json:"tls"`
+	SecurityHeaders     *SecurityHeadersPatch `json:"security_headers"`
+}
+
+type CORSPatch struct {
+	AllowedOrigins   *[]string `json:"allowed_origins"`
+	AllowedMethods   *[]string `json:"allowed_methods"`
+	AllowedHeaders   *[]string `json:"allowed_headers"`
+	AllowCredentials *bool     `json:"allow_credentials"`
+	MaxAge           *string   `json:"max_age"`
+}
+
+type SecurityHeadersPatch struct {
+	HSTSMaxAge            *string `json:"hsts_max_age"`
+	ContentSecurityPolicy *string `json:"content_security_policy"`
+	FrameOptions          *string `json:"frame_options"`
+}
+
+type OpenAIPatch struct {
+	APIKey  *string `json:"api_key"`
+	Model   *string `json:"model"`
+	BaseURL *string `json:"base_url"`
+	UseLLM  *bool   `json:"use_llm"`
+}
+
+type TrashPatch struct {
+	RetentionDays *int    `json:"retention_days"`
+	PurgeInterval *string `json:"purge_interval"`
+}
+
+type SchedulingPatch struct {
+	UrgentWithin        *string `json:"urgent_within"`
+	HighWithin          *string `json:"high_within"`
+	MediumWithin        *string `json:"medium_within"`
+	RecalculateInterval *string `json:"recalculate_interval"`
+}
+
+type NotificationsPatch struct {
+	WebhookURL *string   `json:"webhook_url"`
+	Events     *[]string `json:"events"`
+}
+
+// AuthPatch cannot change the JWT secret; rotate it in the secrets file.
+type AuthPatch struct {
+	AccessTokenTTL    *string `json:"access_token_ttl"`
+	RefreshTokenTTL   *string `json:"refresh_token_ttl"`
+	AllowRegistration *bool   `json:"allow_registration"`
+	DefaultRole       *string `json:"default_role"`
+}
+
+type WorkloadPatch struct {
+	WeeklyCapacityHours *int `json:"weekly_capacity_hours"`
+}
+
+type EstimationPatch struct {
+	Calibrate  *bool    `json:"calibrate"`
+	MinSamples *int     `json:"min_samples"`
+	MaxSamples *int     `json:"max_samples"`
+	MaxFactor  *float64 `json:"max_factor"`
+}
+
+type ForecastPatch struct {
+	Trials     *int `json:"trials"`
+	MinSamples *int `json:"min_samples"`
+	MaxSamples *int `json:"max_samples"`
+}
+
+type RecurrencePatch struct {
+	UseLLM        *bool   `json:"use_llm"`
+	LeadTime      *string `json:"lead_time"`
+	CheckInterval *string `json:"check_interval"`
+}
+
+type RateLimitPatch struct {
+	Enabled *bool               `json:"enabled"`
+	Default *RateLimitRulePatch `json:"default"`
+	LLM     *RateLimitRulePatch `json:"llm"`
+}
+
+type RateLimitRulePatch struct {
+	Requests *int    `json:"requests"`
+	Period   *string `json:"period"`
+	Burst    *int    `json:"burst"`
+}
+
+type RedactionPatch struct {
+	Enabled        *bool               `json:"enabled"`
+	Detectors      *[]string           `json:"detectors"`
+	CustomPatterns *[]RedactionPattern `json:"custom_patterns"`
+}
+
+// UrgencyPatch replaces the whole policy list when Policies is present.
+type UrgencyPatch struct {
+	DefaultPolicy *string              `json:"default_policy"`
+	Policies      *[]UrgencyPolicyView `json:"policies"`
+}
+
+// Apply writes the patch into cfg. It reports fields that cannot be set,
+// such as read-only sections and malformed durations; the resulting config
+// still needs Validate.
+func (p *Patch) Apply(cfg *Config) ValidationError {
+	var errs ValidationError
+
+	setDuration := func(field string, raw *string, dst *time.Duration) {
+		if raw == nil {
+			return
+		}
+		d, err := time.ParseDuration(*raw)
+		if err != nil {
+			errs.add(field, "must be a duration such as \"30s\" or \"24h\", got %q", *raw)
+			return
+		}
+		*dst = d
+	}
+
+	if len(p.Database) > 0 && string(p.Database) != "null" {
+		errs.add("database", "is read-only, edit the config file and restart instead")
+	}
+
+	if s := p.Server; s != nil {
+		if s.Port != nil {
+			cfg.Server.Port = *s.Port
+		}
+		setDuration("server.config_watch_interval", s.ConfigWatchInterval, &cfg.Server.ConfigWatchInterval)
+		if s.MaxBodyBytes != nil {
+			cfg.Server.MaxBodyBytes = *s.MaxBodyBytes
+		}
+		if len(s.TLS) > 0 && string(s.TLS) != "null" {
+			errs.add("server.tls", "is read-only, edit the config file and restart instead")
+		}
+		if cors := s.CORS; cors != nil {
+			if cors.AllowedOrigins != nil {
+				cfg.Server.CORS.AllowedOrigins = *cors.AllowedOrigins
+			}
+			if cors.AllowedMethods != nil {
+				cfg.Server.CORS.AllowedMethods = *cors.AllowedMethods
+			}
+			if cors.AllowedHeaders != nil {
+				cfg.Server.CORS.AllowedHeaders = *cors.AllowedHeaders
+			}
+			if cors.AllowCredentials != nil {
+				cfg.Server.CORS.AllowCredentials = *cors.AllowCredentials
+			}
+			setDuration("server.cors.max_age", cors.MaxAge, &cfg.Server.CORS.MaxAge)
+		}
+		if h := s.SecurityHeaders; h != nil {
+			setDuration("server.security_headers.hsts_max_age", h.HSTSMaxAge, &cfg.Server.SecurityHeaders.HSTSMaxAge)
+			if h.ContentSecurityPolicy != nil {
+				cfg.Server.SecurityHeaders.ContentSecurityPolicy = *h.ContentSecurityPolicy
+			}
+			if h.FrameOptions != nil {
+				cfg.Server.SecurityHeaders.FrameOptions = *h.FrameOptions
+			}
+		}
+	}
+
+	if o := p.OpenAI; o != nil {
+		if o.Model != nil {
+			cfg.OpenAI.Model = *o.Model
+		}
+		if o.BaseURL != nil {
+			cfg.OpenAI.BaseURL = *o.BaseURL
+		}
+		if o.UseLLM != nil {
+			cfg.OpenAI.UseLLM = *o.UseLLM
+		}
+	}
+
+	if t := p.Trash; t != nil {
+		if t.RetentionDays != nil {
+			cfg.Trash.RetentionDays = *t.RetentionDays
+		}
+		setDuration("trash.purge_interval", t.PurgeInterval, &cfg.Trash.PurgeInterval)
+	}
+
+	if s := p.Scheduling; s != nil {
+		setDuration("scheduling.urgent_within", s.UrgentWithin, &cfg.Scheduling.UrgentWithin)
+		setDuration("scheduling.high_within", s.HighWithin, &cfg.Scheduling.HighWithin)
+		setDuration("scheduling.medium_within", s.MediumWithin, &cfg.Scheduling.MediumWithin)
+		setDuration("scheduling.recalculate_interval", s.RecalculateInterval, &cfg.Scheduling.RecalculateInterval)
+	}
+
+	if n := p.Notifications; n != nil {
+		if n.WebhookURL != nil {
+			cfg.Notifications.WebhookURL = *n.WebhookURL
+		}
+		if n.Events != nil {
+			cfg.Notifications.Events = *n.Events
+		}
+	}
+
+	if a := p.Auth; a != nil {
+		setDuration("auth.access_token_ttl", a.AccessTokenTTL, &cfg.Auth.AccessTokenTTL)
+		setDuration("auth.refresh_token_ttl", a.RefreshTokenTTL, &cfg.Auth.RefreshTokenTTL)
+		if a.AllowRegistration != nil {
+			cfg.Auth.AllowRegistration = *a.AllowRegistration
+		}
+		if a.DefaultRole != nil {
+			cfg.Auth.DefaultRole = *a.DefaultRole
+		}
+	}
+
+	if w := p.Workload; w != nil && w.WeeklyCapacityHours != nil {
+		cfg.Workload.WeeklyCapacityHours = *w.WeeklyCapacityHours
+	}
+
+	if e := p.Estimation; e != nil {
+		if e.Calibrate != nil {
+			cfg.Estimation.Calibrate = *e.Calibrate
+		}
+		if e.MinSamples != nil {
+			cfg.Estimation.MinSamples = *e.MinSamples
+		}
+		if e.MaxSamples != nil {
+			cfg.Estimation.MaxSamples = *e.MaxSamples
+		}
+		if e.MaxFactor != nil {
+			cfg.Estimation.MaxFactor = *e.MaxFactor
+		}
+	}
+
+	if f := p.Forecast; f != nil {
+		if f.Trials != nil {
+			cfg.Forecast.Trials = *f.Trials
+		}
+		if f.MinSamples != nil {
+			cfg.Forecast.MinSamples = *f.MinSamples
+		}
+		if f.MaxSamples != nil {
+			cfg.Forecast.MaxSamples = *f.MaxSamples
+		}
+	}
+
+	if r := p.Recurrence; r != nil {
+		if r.UseLLM != nil {
+			cfg.Recurrence.UseLLM = *r.UseLLM
+		}
+		setDuration("recurrence.lead_time", r.LeadTime, &cfg.Recurrence.LeadTime)
+		setDuration("recurrence.check_interval", r.CheckInterval, &cfg.Recurrence.CheckInterval)
+	}
+
+	if r := p.RateLimit; r != nil {
+		if r.Enabled != nil {
+			cfg.RateLimit.Enabled = *r.Enabled
+		}
+		applyRule := func(field string, patch *RateLimitRulePatch, rule *RateLimitRule) {
+			if patch == nil {
+				return
+			}
+			if patch.Requests != nil {
+				rule.Requests = *patch.Requests
+			}
+			if patch.Burst != nil {
+				rule.Burst = *patch.Burst
+			}
+			setDuration(field+".period", patch.Period, &rule.Period)
+		}
+		applyRule("rate_limit.default", r.Default, &cfg.RateLimit.Default)
+		applyRule("rate_limit.llm", r.LLM, &cfg.RateLimit.LLM)
+	}
+
+	if r := p.Redaction; r != nil {
+		if r.Enabled != nil {
+			cfg.Redaction.Enabled = *r.Enabled
+		}
+		if r.Detectors != nil {
+			cfg.Redaction.Detectors = *r.Detectors
+		}
+		if r.CustomPatterns != nil {
+			cfg.Redaction.CustomPatterns = *r.CustomPatterns
+		}
+	}
+
+	if u := p.Urgency; u != nil {
+		if u.DefaultPolicy != nil {
+			cfg.Urgency.DefaultPolicy = *u.DefaultPolicy
+		}
+		if u.Policies != nil {
+			policies := make([]UrgencyPolicyConfig, 0, len(*u.Policies))
+			for i, view := range *u.Policies {
+				field := fmt.Sprintf("urgency.policies[%d]", i)
+				policy := UrgencyPolicyConfig{
+					Name:                view.Name,
+					Curve:               view.Curve,
+					RemainingWorkWeight: view.RemainingWorkWeight,
+					ManualPriorityBoost: view.ManualPriorityBoost,
+					BlockedWeight:       view.BlockedWeight,
+					StatusWeights:       view.StatusWeights,
+				}
+				optional := func(name, raw string, dst *time.Duration) {
+					if raw != "" {
+						setDuration(field+"."+name, &raw, dst)
+					}
+				}
+				optional("horizon", view.Horizon, &policy.Horizon)
+				optional("urgent_within", view.UrgentWithin, &policy.UrgentWithin)
+				optional("high_within", view.HighWithin, &policy.HighWithin)
+				optional("medium_within", view.MediumWithin, &policy.MediumWithin)
+				policy.applyDefaults()
+				policies = append(policies, policy)
+			}
+			cfg.Urgency.Policies = policies
+		}
+	}
+
+	return errs
+}
+
+// NewAPIKey returns the OpenAI key carried by the patch, if any. A masked key
+// echoed back from View is not a change.
+func (p *Patch) NewAPIKey(current string) string {
+	if p.OpenAI == nil || p.OpenAI.APIKey == nil {
+		return ""
+	}
+	if *p.OpenAI.APIKey == MaskSecret(current) {
+		return ""
+	}
+	return *p.OpenAI.APIKey
+}
+
+// ApplyPatch validates p against the current configuration and, unless
+// dryRun is set, persists it to the config and secrets files and publishes
+// it. It returns the resulting configuration.
+func ApplyPatch(p *Patch, dryRun bool) (*Config, error) {
+	current := GetConfig()
+	apiKey := p.NewAPIKey(current.OpenAI.APIKey)
+	// A masked webhook URL echoed back from View is not a change
+	if n := p.Notifications; n != nil && n.WebhookURL != nil && *n.WebhookURL == MaskURL(current.Notifications.WebhookURL) {
+		n.WebhookURL = nil
+	}
+
+	next := current.clone()
+	errs := p.Apply(next)
+	if apiKey != "" {
+		next.OpenAI.APIKey = apiKey
+	}
+	if err := next.Validate(); err != nil {
+		errs = append(errs, err.(ValidationError)...)
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	if dryRun {
+		return next, nil
+	}
+
+	apply := func(cfg *Config) error {
+		if errs := p.Apply(cfg); len(errs) > 0 {
+			return errs
+		}
+		return nil
+	}
+	c := change{apply: apply}
+	if apiKey != "" {
+		c = setOpenAIAPIKey(apiKey, apply)
+	}
+	if err := commit(c); err != nil {
+		return nil, err
+	}
+
+	return GetConfig(), nil
+}
@@ -4,25 +4,88 @@ import (
 	"fmt"
 	"gopkg.in/yaml.v2"
 	"os"
+	"time"
 )
 
 type Config struct {
 	Server   ServerConfig   `yaml:"server"`
 	Database DatabaseConfig `yaml:"database"`
 	OpenAI   OpenAIConfig   `yaml:"openai"`
+	Trash    TrashConfig    `yaml:"trash"`
+	Secrets  SecretsConfig  `yaml:"secrets"`
+
+	Scheduling    SchedulingConfig    `yaml:"scheduling"`
+	Notifications NotificationsConfig `yaml:"notifications"`
+	Auth          AuthConfig          `yaml:"auth"`
+	Workload      WorkloadConfig      `yaml:"workload"`
+	Estimation    EstimationConfig    `yaml:"estimation"`
+	Forecast      ForecastConfig      `yaml:"forecast"`
+	Recurrence    RecurrenceConfig    `yaml:"recurrence"`
+	RateLimit     RateLimitConfig     `yaml:"rate_limit"`
+	Redaction     RedactionConfig     `yaml:"redaction"`
+	Urgency       UrgencyConfig       `yaml:"urgency"`
 }
 
 type ServerConfig struct {
 	Port string `yaml:"port"`
+	// ConfigWatchInterval is how often the config and secrets files are
+	// checked for changes. 0 disables hot reload.
+	ConfigWatchInterval time.Duration `yaml:"config_watch_interval"`
+	// TrustedProxies lists proxy addresses or CIDRs whose X-Forwarded-For
+	// header is believed when determining the client IP. Empty trusts none.
+	TrustedProxies []string `yaml:"trusted_proxies"`
+	// MaxBodyBytes rejects request bodies larger than this with 413.
+	MaxBodyBytes    int64                 `yaml:"max_body_bytes"`
+	CORS            CORSConfig            `yaml:"cors"`
+	TLS             TLSConfig             `yaml:"tls"`
+	SecurityHeaders SecurityHeadersConfig `yaml:"security_headers"`
+}
+
+// CORSConfig lists the browser origins allowed to call the API. An origin of
+// "*" allows any origin but cannot be combined with AllowCredentials. No
+// origins means no CORS headers are sent.
+type CORSConfig struct {
+	AllowedOrigins   []string      `yaml:"allowed_origins"`
+	AllowedMethods   []string      `yaml:"allowed_methods"`
+	AllowedHeaders   []string      `yaml:"allowed_headers"`
+	AllowCredentials bool          `yaml:"allow_credentials"`
+	MaxAge           time.Duration `yaml:"max_age"`
+}
+
+// TLSConfig enables HTTPS on server.port. With RedirectHTTP, plain HTTP
+// requests on HTTPPort are redirected to HTTPS.
+type TLSConfig struct {
+	Enabled      bool   `yaml:"enabled"`
+	CertFile     string `yaml:"cert_file"`
+	KeyFile      string `yaml:"key_file"`
+	RedirectHTTP bool   `yaml:"redirect_http"`
+	HTTPPort     string `yaml:"http_port"`
+}
+
+// SecurityHeadersConfig controls the headers added to every response. HSTS
+// is only sent over HTTPS.
+type SecurityHeadersConfig struct {
+	HSTSMaxAge            time.Duration `yaml:"hsts_max_age"`
+	ContentSecurityPolicy string        `yaml:"content_security_policy"`
+	FrameOptions          string        `yaml:"frame_options"`
 }
 
 type DatabaseConfig struct {
-	Type     string `yaml:"type"`
-	Host     string `yaml:"host"`
-	Port     string `yaml:"port"`
-	User     string `yaml:"user"`
-	Password string `yaml:"password"`
-	Name     string `yaml:"name"`
+	Type     string       `yaml:"type"`
+	Host     string       `yaml:"host"`
+	Port     string       `yaml:"port"`
+	User     string       `yaml:"user"`
+	Password string       `yaml:"password"`
+	Name     string       `yaml:"name"`
+	Backup   BackupConfig `yaml:"backup"`
+}
+
+// BackupConfig controls online SQLite backups. An Interval of 0 disables the
+// scheduled backup; backups can still be taken on demand.
+type BackupConfig struct {
+	Dir      string        `yaml:"dir"`
+	Interval time.Duration `yaml:"interval"`
+	Keep     int           `yaml:"keep"`
 }
 
 type OpenAIConfig struct {
@@ -32,23 +95,327 @@ type OpenAIConfig struct {
 	UseLLM  bool   `yaml:"use_llm"`
 }
 
-var GlobalConfig *Config
+// TrashConfig controls how long soft-deleted tasks are kept before they are
+// permanently purged. A RetentionDays of 0 keeps deleted tasks forever.
+type TrashConfig struct {
+	RetentionDays int           `yaml:"retention_days"`
+	PurgeInterval time.Duration `yaml:"purge_interval"`
+}
+
+// SecretsConfig points at the file holding secret values such as API keys.
+// EncryptionKey encrypts secrets stored in the database, such as workspace
+// LLM keys; it is normally kept in that file and generated on first start.
+type SecretsConfig struct {
+	File          string `yaml:"file"`
+	EncryptionKey string `yaml:"encryption_key"`
+}
+
+// SchedulingConfig holds the deadline thresholds used to derive a task's
+// priority: a task due within UrgentWithin is urgent, within HighWithin high,
+// within MediumWithin medium and low otherwise. Open tasks are re-prioritised
+// every RecalculateInterval as their deadlines approach; 0 disables this.
+type SchedulingConfig struct {
+	UrgentWithin        time.Duration `yaml:"urgent_within"`
+	HighWithin          time.Duration `yaml:"high_within"`
+	MediumWithin        time.Duration `yaml:"medium_within"`
+	RecalculateInterval time.Duration `yaml:"recalculate_interval"`
+}
+
+// UrgencyConfig lists the policies used to score task urgency. Workspaces use
+// DefaultPolicy unless they pick another one by name.
+type UrgencyConfig struct {
+	DefaultPolicy string                `yaml:"default_policy"`
+	Policies      []UrgencyPolicyConfig `yaml:"policies"`
+}
+
+// UrgencyPolicyConfig describes one urgency policy. The deadline score rises
+// along Curve as the deadline approaches: "exponential" reaches about 63 at
+// Horizon before the deadline, "linear" rises from 0 at Horizon to 100 at the
+// deadline. RemainingWorkWeight blends in how much of the time left the open
+// sub-tasks' estimates need, ManualPriorityBoost adds points per priority
+// level to manually prioritised tasks, and BlockedWeight lowers the score by
+// the share of open sub-tasks still waiting on dependencies. StatusWeights
+// multiply the final score per status name; statuses not listed count fully.
+// The Within thresholds override the scheduling ones when set.
+type UrgencyPolicyConfig struct {
+	Name                string             `yaml:"name"`
+	Curve               string             `yaml:"curve"`
+	Horizon             time.Duration      `yaml:"horizon"`
+	RemainingWorkWeight float64            `yaml:"remaining_work_weight"`
+	ManualPriorityBoost float64            `yaml:"manual_priority_boost"`
+	BlockedWeight       float64            `yaml:"blocked_weight"`
+	StatusWeights       map[string]float64 `yaml:"status_weights"`
+	UrgentWithin        time.Duration      `yaml:"urgent_within"`
+	HighWithin          time.Duration      `yaml:"high_within"`
+	MediumWithin        time.Duration      `yaml:"medium_within"`
+}
+
+// NotificationsConfig sets where task events are delivered. Events lists the
+// event names to send; an empty list sends all of them.
+type NotificationsConfig struct {
+	WebhookURL string   `yaml:"webhook_url"`
+	Events     []string `yaml:"events"`
+}
+
+// WorkloadConfig sets how many estimated hours a person can take on per
+// week before the workload view flags them as overallocated.
+type WorkloadConfig struct {
+	WeeklyCapacityHours int `yaml:"weekly_capacity_hours"`
+}
+
+// EstimationConfig controls how LLM estimates are calibrated against the
+// hours actually tracked on completed sub-tasks. A bias is only trusted once
+// MinSamples sub-tasks back it; the latest MaxSamples are used, and factors
+// are clamped to between 1/MaxFactor and MaxFactor.
+type EstimationConfig struct {
+	Calibrate  bool    `yaml:"calibrate"`
+	MinSamples int     `yaml:"min_samples"`
+	MaxSamples int     `yaml:"max_samples"`
+	MaxFactor  float64 `yaml:"max_factor"`
+}
+
+// ForecastConfig controls completion forecasts. Each forecast runs Trials
+// simulations drawing on the latest MaxSamples finished sub-tasks of the
+// workspace; with fewer than MinSamples a default spread is assumed instead.
+type ForecastConfig struct {
+	Trials     int `yaml:"trials"`
+	MinSamples int `yaml:"min_samples"`
+	MaxSamples int `yaml:"max_samples"`
+}
+
+// RecurrenceConfig controls recurring tasks. The next instance of a series
+// is created when the latest one is finished, or once its deadline is less
+// than LeadTime away; the check runs every CheckInterval, and 0 disables it.
+// Instances copy the previous instance's sub-tasks unless UseLLM asks for
+// fresh ones.
+type RecurrenceConfig struct {
+	UseLLM        bool          `yaml:"use_llm"`
+	LeadTime      time.Duration `yaml:"lead_time"`
+	CheckInterval time.Duration `yaml:"check_interval"`
+}
+
+// RateLimitConfig throttles clients with token buckets keyed by user,
+// personal access token or IP address. Default applies to every request;
+// LLM additionally applies to endpoints that call the LLM.
+type RateLimitConfig struct {
+	Enabled bool          `yaml:"enabled"`
+	Default RateLimitRule `yaml:"default"`
+	LLM     RateLimitRule `yaml:"llm"`
+}
+
+// RateLimitRule allows Requests per Period on average, with bursts of up to
+// Burst requests. Burst 0 means Requests.
+type RateLimitRule struct {
+	Requests int           `yaml:"requests"`
+	Period   time.Duration `yaml:"period"`
+	Burst    int           `yaml:"burst"`
+}
+
+// RedactionConfig controls what is stripped from task text before it is
+// sent to the LLM. Detectors names built-in detectors ("email", "phone",
+// "secret"); CustomPatterns adds regular expressions of its own. Matches are
+// replaced by placeholders such as [[EMAIL_1]] and restored in the response.
+type RedactionConfig struct {
+	Enabled        bool               `yaml:"enabled"`
+	Detectors      []string           `yaml:"detectors"`
+	CustomPatterns []RedactionPattern `yaml:"custom_patterns"`
+}
+
+// RedactionPattern is a named regular expression. The name, upper-cased,
+// becomes the placeholder label.
+type RedactionPattern struct {
+	Name    string `yaml:"name" json:"name"`
+	Pattern string `yaml:"pattern" json:"pattern"`
+}
+
+// AuthConfig controls user authentication. JWTSecret signs access and
+// refresh tokens; it is normally kept in the secrets file and generated on
+// first start. AllowRegistration lets anyone create an account; the first
+// account can always be created and becomes an admin. DefaultRole is given
+// to accounts created after that.
+type AuthConfig struct {
+	JWTSecret         string        `yaml:"jwt_secret"`
+	AccessTokenTTL    time.Duration `yaml:"access_token_ttl"`
+	RefreshTokenTTL   time.Duration `yaml:"refresh_token_ttl"`
+	AllowRegistration bool          `yaml:"allow_registration"`
+	DefaultRole       string        `yaml:"default_role"`
+}
+
+// applyDefaults gives a policy the original scoring: an exponential curve
+// with a 10 hour horizon that ignores finished tasks.
+func (p *UrgencyPolicyConfig) applyDefaults() {
+	if p.Curve == "" {
+		p.Curve = "exponential"
+	}
+	if p.Horizon == 0 {
+		p.Horizon = 10 * time.Hour
+	}
+	if p.StatusWeights == nil {
+		p.StatusWeights = map[string]float64{"completed": 0, "cancelled": 0}
+	}
+}
+
+// Policy returns the urgency policy called name, or the default policy when
+// there is no such policy.
+func (u UrgencyConfig) Policy(name string) UrgencyPolicyConfig {
+	for _, policy := range u.Policies {
+		if policy.Name == name {
+			return policy
+		}
+	}
+	for _, policy := range u.Policies {
+		if policy.Name == u.DefaultPolicy {
+			return policy
+		}
+	}
+	return UrgencyPolicyConfig{}
+}
+
+// HasPolicy reports whether a policy called name is configured.
+func (u UrgencyConfig) HasPolicy(name string) bool {
+	for _, policy := range u.Policies {
+		if policy.Name == name {
+			return true
+		}
+	}
+	return false
+}
 
+// LoadConfig reads the YAML file at configPath, expands ${VAR} references,
+// applies TASKMANAGER_* environment overrides and the secrets file, and
+// validates the result. It becomes the current configuration.
 func LoadConfig(configPath string) error {
+	cfg, err := load(configPath)
+	if err != nil {
+		return err
+	}
+
+	store.mu.Lock()
+	store.path = configPath
+	store.mu.Unlock()
+
+	store.current.Store(cfg)
+	return nil
+}
+
+// GetConfig returns the current configuration snapshot. Snapshots are never
+// modified after they are published, so callers may keep one for the duration
+// of a request but must not write to it; use Update instead.
+func GetConfig() *Config {
+	return store.current.Load()
+}
+
+// Path returns the file the current configuration was loaded from.
+func Path() string {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	return store.path
+}
+
+func load(configPath string) (*Config, error) {
 	data, err := os.ReadFile(configPath)
 	if err != nil {
-		return fmt.Errorf("failed to read config file: %w", err)
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	data, err = interpolateEnv(data)
+	if err != nil {
+		return nil, err
 	}
 
 	var config Config
 	if err := yaml.Unmarshal(data, &config); err != nil {
-		return fmt.Errorf("failed to unmarshal config: %w", err)
+		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
 
-	GlobalConfig = &config
-	return nil
+	if err := applyEnvOverrides(&config); err != nil {
+		return nil, err
+	}
+
+	if err := applySecrets(&config); err != nil {
+		return nil, err
+	}
+
+	applyDefaults(&config)
+
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return &config, nil
 }
 
-func GetConfig() *Config {
-	return GlobalConfig
+func applyDefaults(cfg *Config) {
+	if cfg.Server.MaxBodyBytes == 0 {
+		cfg.Server.MaxBodyBytes = 10 << 20
+	}
+	if len(cfg.Server.CORS.AllowedMethods) == 0 {
+		cfg.Server.CORS.AllowedMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
+	}
+	if len(cfg.Server.CORS.AllowedHeaders) == 0 {
+		cfg.Server.CORS.AllowedHeaders = []string{"Content-Type", "Authorization", "X-Workspace-ID"}
+	}
+	if cfg.Server.TLS.HTTPPort == "" {
+		cfg.Server.TLS.HTTPPort = "80"
+	}
+	if cfg.Server.SecurityHeaders.FrameOptions == "" {
+		cfg.Server.SecurityHeaders.FrameOptions = "DENY"
+	}
+	if cfg.Auth.AccessTokenTTL == 0 {
+		cfg.Auth.AccessTokenTTL = 15 * time.Minute
+	}
+	if cfg.Auth.RefreshTokenTTL == 0 {
+		cfg.Auth.RefreshTokenTTL = 7 * 24 * time.Hour
+	}
+	if cfg.RateLimit.Default.Period == 0 {
+		cfg.RateLimit.Default.Period = time.Minute
+	}
+	if cfg.RateLimit.LLM.Period == 0 {
+		cfg.RateLimit.LLM.Period = time.Hour
+	}
+	if cfg.Workload.WeeklyCapacityHours == 0 {
+		cfg.Workload.WeeklyCapacityHours = 40
+	}
+	if cfg.Estimation.MinSamples == 0 {
+		cfg.Estimation.MinSamples = 5
+	}
+	if cfg.Estimation.MaxSamples == 0 {
+		cfg.Estimation.MaxSamples = 50
+	}
+	if cfg.Estimation.MaxFactor == 0 {
+		cfg.Estimation.MaxFactor = 3
+	}
+	if cfg.Forecast.Trials == 0 {
+		cfg.Forecast.Trials = 2000
+	}
+	if cfg.Forecast.MinSamples == 0 {
+		cfg.Forecast.MinSamples = 10
+	}
+	if cfg.Forecast.MaxSamples == 0 {
+		cfg.Forecast.MaxSamples = 200
+	}
+	if len(cfg.Urgency.Policies) == 0 {
+		cfg.Urgency.Policies = []UrgencyPolicyConfig{{Name: "default"}}
+	}
+	for i := range cfg.Urgency.Policies {
+		cfg.Urgency.Policies[i].applyDefaults()
+	}
+	if cfg.Urgency.DefaultPolicy == "" {
+		cfg.Urgency.DefaultPolicy = cfg.Urgency.Policies[0].Name
+	}
+	if cfg.Redaction.Detectors == nil {
+		cfg.Redaction.Detectors = []string{"email", "phone", "secret"}
+	}
+	if cfg.Auth.DefaultRole == "" {
+		cfg.Auth.DefaultRole = "member"
+	}
+	if cfg.Scheduling.UrgentWithin == 0 {
+		cfg.Scheduling.UrgentWithin = 24 * time.Hour
+	}
+	if cfg.Scheduling.HighWithin == 0 {
+		cfg.Scheduling.HighWithin = 3 * 24 * time.Hour
+	}
+	if cfg.Scheduling.MediumWithin == 0 {
+		cfg.Scheduling.MediumWithin = 7 * 24 * time.Hour
+	}
 }
@@ -0,0 +1,191 @@
+package config
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+// Secrets holds values that must not be written to the main config file or
+// returned by the API. They live in a separate file readable only by the
+// owner.
+type Secrets struct {
+	OpenAIAPIKey  string `yaml:"openai_api_key"`
+	JWTSecret     string `yaml:"jwt_secret"`
+	EncryptionKey string `yaml:"encryption_key"`
+}
+
+// applySecrets fills secret fields from the secrets file. Explicit
+// environment overrides still win.
+func applySecrets(cfg *Config) error {
+	if cfg.Secrets.File == "" {
+		return nil
+	}
+
+	secrets, err := LoadSecrets(cfg.Secrets.File)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := os.LookupEnv(envPrefix + "_OPENAI_API_KEY"); !ok && secrets.OpenAIAPIKey != "" {
+		cfg.OpenAI.APIKey = secrets.OpenAIAPIKey
+	}
+	if _, ok := os.LookupEnv(envPrefix + "_AUTH_JWT_SECRET"); !ok && secrets.JWTSecret != "" {
+		cfg.Auth.JWTSecret = secrets.JWTSecret
+	}
+	if _, ok := os.LookupEnv(envPrefix + "_SECRETS_ENCRYPTION_KEY"); !ok && secrets.EncryptionKey != "" {
+		cfg.Secrets.EncryptionKey = secrets.EncryptionKey
+	}
+
+	return nil
+}
+
+// LoadSecrets reads the secrets file. A missing file yields empty secrets.
+func LoadSecrets(path string) (*Secrets, error) {
+	var secrets Secrets
+
+	stat, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return &secrets, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat secrets file: %w", err)
+	}
+	if stat.Mode().Perm()&0077 != 0 {
+		log.Printf("Warning: secrets file %s is accessible by other users (mode %s), expected 0600", path, stat.Mode().Perm())
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secrets file: %w", err)
+	}
+	if err := yaml.Unmarshal(data, &secrets); err != nil {
+		return nil, fmt.Errorf("failed to parse secrets file: %w", err)
+	}
+
+	return &secrets, nil
+}
+
+// SaveSecrets writes the secrets file with 0600 permissions, replacing it
+// atomically.
+func SaveSecrets(path string, secrets Secrets) error {
+	data, err := yaml.Marshal(secrets)
+	if err != nil {
+		return fmt.Errorf("failed to encode secrets: %w", err)
+	}
+
+	return writeFileAtomic(path, data, 0600)
+}
+
+// SetOpenAIAPIKey stores key in the secrets file, removes a plaintext key
+// from the config file (${VAR} references are left alone) and publishes it.
+func SetOpenAIAPIKey(key string) error {
+	return commit(setOpenAIAPIKey(key, func(*Config) error { return nil }))
+}
+
+// setOpenAIAPIKey is the change that stores key, after applying fn.
+func setOpenAIAPIKey(key string, fn func(*Config) error) change {
+	return change{
+		apply: func(cfg *Config) error {
+			if err := fn(cfg); err != nil {
+				return err
+			}
+			cfg.OpenAI.APIKey = key
+			return nil
+		},
+		secrets: func(secrets *Secrets) {
+			secrets.OpenAIAPIKey = key
+		},
+		file: func(doc *yaml3.Node) {
+			openai := mappingValue(doc.Content[0], "openai")
+			if openai == nil {
+				return
+			}
+			if apiKey := mappingValue(openai, "api_key"); apiKey != nil && !strings.Contains(apiKey.Value, "${") {
+				apiKey.Value = ""
+			}
+		},
+	}
+}
+
+// EnsureJWTSecret generates a random signing secret and stores it in the
+// secrets file when none is configured, so tokens survive restarts.
+func EnsureJWTSecret() error {
+	return ensureSecret("auth.jwt_secret",
+		func(cfg *Config) *string { return &cfg.Auth.JWTSecret },
+		func(secrets *Secrets) *string { return &secrets.JWTSecret })
+}
+
+// EnsureEncryptionKey generates the key that encrypts secrets stored in the
+// database when none is configured. Losing it makes those secrets unreadable.
+func EnsureEncryptionKey() error {
+	return ensureSecret("secrets.encryption_key",
+		func(cfg *Config) *string { return &cfg.Secrets.EncryptionKey },
+		func(secrets *Secrets) *string { return &secrets.EncryptionKey })
+}
+
+// ensureSecret stores a random value in the secrets file and the running
+// configuration when the field is empty.
+func ensureSecret(name string, field func(*Config) *string, stored func(*Secrets) *string) error {
+	cfg := GetConfig()
+	if *field(cfg) != "" {
+		return nil
+	}
+	if cfg.Secrets.File == "" {
+		return fmt.Errorf("%s is not set and no secrets file is configured", name)
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Errorf("failed to generate %s: %w", name, err)
+	}
+	secret := hex.EncodeToString(buf)
+
+	secrets, err := LoadSecrets(cfg.Secrets.File)
+	if err != nil {
+		return err
+	}
+	*stored(secrets) = secret
+	if err := SaveSecrets(cfg.Secrets.File, *secrets); err != nil {
+		return err
+	}
+
+	return Update(func(cfg *Config) error {
+		*field(cfg) = secret
+		return nil
+	})
+}
+
+// MaskSecret keeps just enough of a secret to tell keys apart.
+func MaskSecret(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	if len(secret) < 12 {
+		return "****"
+	}
+	return secret[:3] + "****" + secret[len(secret)-4:]
+}
+
+// MaskURL keeps a URL's scheme and host but hides its path and query, which
+// for chat webhooks is the secret part.
+func MaskURL(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return MaskSecret(raw)
+	}
+	if u.Path == "" && u.RawQuery == "" {
+		return u.Scheme + "://" + u.Host
+	}
+	return u.Scheme + "://" + u.Host + "/****"
+}
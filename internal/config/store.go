@@ -0,0 +1,444 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Listener is called after a new configuration has been published.
+type Listener func(old, new *Config)
+
+// store holds the current configuration as an immutable snapshot. Readers
+// load it without locking; writers build a modified copy and swap it in.
+var store struct {
+	current atomic.Pointer[Config]
+
+	mu        sync.Mutex // serialises writers and guards the fields below
+	path      string
+	listeners []Listener
+}
+
+// Subscribe registers fn to be called whenever the configuration changes,
+// either through Update or a reload from disk.
+func Subscribe(fn Listener) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.listeners = append(store.listeners, fn)
+}
+
+// Update applies fn to a copy of the current configuration, validates it and
+// publishes it. The running configuration is untouched if fn or validation
+// fails.
+func Update(fn func(*Config) error) error {
+	store.mu.Lock()
+	old := store.current.Load()
+	next := old.clone()
+	if err := fn(next); err != nil {
+		store.mu.Unlock()
+		return err
+	}
+	if err := next.Validate(); err != nil {
+		store.mu.Unlock()
+		return fmt.Errorf("invalid config: %w", err)
+	}
+	store.current.Store(next)
+	listeners := store.listeners
+	store.mu.Unlock()
+
+	notify(listeners, old, next)
+	return nil
+}
+
+// clone returns a deep copy of c. Published snapshots are read without
+// locks, so a writer must not share their slices and maps.
+func (c *Config) clone() *Config {
+	next := new(Config)
+	copyValue(reflect.ValueOf(next).Elem(), reflect.ValueOf(c).Elem())
+	return next
+}
+
+func copyValue(dst, src reflect.Value) {
+	switch src.Kind() {
+	case reflect.Struct:
+		for i := 0; i < src.NumField(); i++ {
+			copyValue(dst.Field(i), src.Field(i))
+		}
+	case reflect.Slice:
+		if src.IsNil() {
+			return
+		}
+		dst.Set(reflect.MakeSlice(src.Type(), src.Len(), src.Len()))
+		for i := 0; i < src.Len(); i++ {
+			copyValue(dst.Index(i), src.Index(i))
+		}
+	case reflect.Map:
+		if src.IsNil() {
+			return
+		}
+		dst.Set(reflect.MakeMapWithSize(src.Type(), src.Len()))
+		for iter := src.MapRange(); iter.Next(); {
+			value := reflect.New(src.Type().Elem()).Elem()
+			copyValue(value, iter.Value())
+			dst.SetMapIndex(iter.Key(), value)
+		}
+	case reflect.Pointer:
+		if src.IsNil() {
+			return
+		}
+		dst.Set(reflect.New(src.Type().Elem()))
+		copyValue(dst.Elem(), src.Elem())
+	default:
+		dst.Set(src)
+	}
+}
+
+// secretPaths are fields never written to the config file; they live in
+// the secrets file or come from the environment.
+var secretPaths = map[string]bool{
+	"openai.api_key":         true,
+	"auth.jwt_secret":        true,
+	"secrets.encryption_key": true,
+}
+
+// change is an update committed to the running configuration and the files
+// behind it.
+type change struct {
+	// apply changes the running configuration. The fields it changes are
+	// written to the config file too, except secrets.
+	apply func(*Config) error
+	// secrets, when set, changes the secrets file.
+	secrets func(*Secrets)
+	// file, when set, edits the config file's YAML document directly.
+	file func(doc *yaml.Node)
+}
+
+// commit applies c to a copy of the running configuration, validates it,
+// writes the secrets and config files and publishes it. Either every step
+// takes effect or none does: files already written are put back when a
+// later step fails.
+func commit(c change) error {
+	store.mu.Lock()
+	old := store.current.Load()
+	next := old.clone()
+	if err := c.apply(next); err != nil {
+		store.mu.Unlock()
+		return err
+	}
+	if err := next.Validate(); err != nil {
+		store.mu.Unlock()
+		return fmt.Errorf("invalid config: %w", err)
+	}
+
+	var saved []savedFile
+	restore := func() {
+		for _, file := range saved {
+			if err := file.restore(); err != nil {
+				log.Printf("Failed to restore %s after a failed config update: %v", file.path, err)
+			}
+		}
+	}
+
+	if c.secrets != nil {
+		path := next.Secrets.File
+		if path == "" {
+			store.mu.Unlock()
+			return fmt.Errorf("no secrets file configured")
+		}
+		file, err := saveFile(path)
+		if err == nil {
+			saved = append(saved, file)
+			err = writeSecrets(path, c.secrets)
+		}
+		if err != nil {
+			restore()
+			store.mu.Unlock()
+			return err
+		}
+	}
+
+	file, err := saveFile(store.path)
+	if err == nil {
+		saved = append(saved, file)
+		err = writeConfigFile(store.path, file.perm, old, next, c.file)
+	}
+	if err != nil {
+		restore()
+		store.mu.Unlock()
+		return err
+	}
+
+	store.current.Store(next)
+	listeners := store.listeners
+	store.mu.Unlock()
+
+	notify(listeners, old, next)
+	return nil
+}
+
+func writeSecrets(path string, fn func(*Secrets)) error {
+	secrets, err := LoadSecrets(path)
+	if err != nil {
+		return err
+	}
+	fn(secrets)
+	return SaveSecrets(path, *secrets)
+}
+
+// writeConfigFile writes the fields that differ between old and next into
+// the config file, and then applies edit. The rest of the file, including
+// comments and ${VAR} references, is kept as written.
+func writeConfigFile(path string, perm os.FileMode, old, next *Config, edit func(*yaml.Node)) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to parse config file: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+
+	var before, after yaml.Node
+	if err := before.Encode(old); err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+	if err := after.Encode(next); err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+
+	changed := false
+	diffNodes(&before, &after, nil, func(keyPath []string, value *yaml.Node) {
+		if secretPaths[strings.Join(keyPath, ".")] {
+			return
+		}
+		setNode(doc.Content[0], keyPath, value)
+		changed = true
+	})
+	if edit != nil {
+		edit(&doc)
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+
+	// Match the two-space indent of the shipped config so untouched lines
+	// stay as they are
+	var updated bytes.Buffer
+	enc := yaml.NewEncoder(&updated)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+	return writeFileAtomic(path, updated.Bytes(), perm)
+}
+
+// diffNodes calls fn with the key path and new value of every field that
+// differs between two encoded configs. Lists are compared as a whole.
+func diffNodes(before, after *yaml.Node, keyPath []string, fn func([]string, *yaml.Node)) {
+	if before != nil && before.Kind == yaml.MappingNode && after.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(after.Content); i += 2 {
+			key := after.Content[i].Value
+			path := append(append([]string{}, keyPath...), key)
+			diffNodes(mappingValue(before, key), after.Content[i+1], path, fn)
+		}
+		return
+	}
+	if before == nil || !sameNode(before, after) {
+		fn(keyPath, after)
+	}
+}
+
+func sameNode(a, b *yaml.Node) bool {
+	x, errX := yaml.Marshal(a)
+	y, errY := yaml.Marshal(b)
+	return errX == nil && errY == nil && string(x) == string(y)
+}
+
+// mappingValue returns the value under key in a mapping node, or nil.
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	if mapping.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// setNode puts value at keyPath under mapping, adding mappings on the way
+// as needed. A replaced value keeps its comments.
+func setNode(mapping *yaml.Node, keyPath []string, value *yaml.Node) {
+	for i, key := range keyPath {
+		current := mappingValue(mapping, key)
+		last := i == len(keyPath)-1
+
+		switch {
+		case current == nil && last:
+			mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+			return
+		case current == nil:
+			current = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, current)
+		case last:
+			value.HeadComment, value.LineComment, value.FootComment = current.HeadComment, current.LineComment, current.FootComment
+			if value.Kind == yaml.ScalarNode && value.Tag == "!!str" && current.Kind == yaml.ScalarNode {
+				value.Style = current.Style
+			}
+			*current = *value
+			return
+		case current.Kind != yaml.MappingNode:
+			*current = yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		}
+		mapping = current
+	}
+}
+
+// savedFile is a file's content before an update, to put back if the update
+// fails.
+type savedFile struct {
+	path   string
+	data   []byte
+	perm   os.FileMode
+	exists bool
+}
+
+func saveFile(path string) (savedFile, error) {
+	file := savedFile{path: path, perm: 0600}
+	stat, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return file, nil
+	}
+	if err != nil {
+		return file, fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+	if file.data, err = os.ReadFile(path); err != nil {
+		return file, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	file.perm, file.exists = stat.Mode().Perm(), true
+	return file, nil
+}
+
+func (f savedFile) restore() error {
+	if !f.exists {
+		if err := os.Remove(f.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	return writeFileAtomic(f.path, f.data, f.perm)
+}
+
+// Reload loads the config file again and publishes it. On error the running
+// configuration is kept.
+func Reload() error {
+	next, err := load(Path())
+	if err != nil {
+		return err
+	}
+
+	store.mu.Lock()
+	old := store.current.Load()
+	store.current.Store(next)
+	listeners := store.listeners
+	store.mu.Unlock()
+
+	notify(listeners, old, next)
+	return nil
+}
+
+// Watch polls the config file and the secrets file for changes and reloads
+// when either is modified.
+func Watch(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		last := watchedModTimes()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			current := watchedModTimes()
+			if current == last {
+				continue
+			}
+			last = current
+
+			if err := Reload(); err != nil {
+				log.Printf("Config reload failed, keeping current config: %v", err)
+				continue
+			}
+			log.Printf("Config reloaded from %s", Path())
+		}
+	}()
+}
+
+func watchedModTimes() [2]time.Time {
+	var times [2]time.Time
+	if stat, err := os.Stat(Path()); err == nil {
+		times[0] = stat.ModTime()
+	}
+	if cfg := GetConfig(); cfg != nil && cfg.Secrets.File != "" {
+		if stat, err := os.Stat(cfg.Secrets.File); err == nil {
+			times[1] = stat.ModTime()
+		}
+	}
+	return times
+}
+
+func notify(listeners []Listener, old, new *Config) {
+	for _, fn := range listeners {
+		fn(old, new)
+	}
+}
+
+// writeFileAtomic writes data to a temporary file in the same directory and
+// renames it over path, so readers never see a partial file.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,326 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// RedactionDetectors are the built-in redaction detectors that
+// redaction.detectors may name.
+var RedactionDetectors = []string{"email", "phone", "secret"}
+
+// UrgencyCurves are the curves an urgency policy may use.
+var UrgencyCurves = []string{"exponential", "linear"}
+
+var urgencyStatuses = map[string]bool{
+	"pending":     true,
+	"in_progress": true,
+	"completed":   true,
+	"cancelled":   true,
+}
+
+var redactionPatternName = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+
+var supportedDatabaseTypes = map[string]bool{
+	"sqlite3": true,
+}
+
+// assignableDefaultRoles excludes admin so open registration cannot hand
+// out admin accounts.
+var assignableDefaultRoles = map[string]bool{
+	"member": true,
+	"viewer": true,
+}
+
+// FieldError describes a problem with one config field, identified by its
+// YAML path such as "openai.base_url".
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError collects every FieldError found in a configuration.
+type ValidationError []FieldError
+
+func (e ValidationError) Error() string {
+	lines := make([]string, 0, len(e))
+	for _, fe := range e {
+		lines = append(lines, fe.Field+" "+fe.Message)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (e *ValidationError) add(field, format string, args ...interface{}) {
+	*e = append(*e, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// Validate reports every problem with the configuration at once so a broken
+// deployment can be fixed in one pass. The error is a ValidationError.
+func (c *Config) Validate() error {
+	var errs ValidationError
+
+	if c.Server.Port == "" {
+		errs.add("server.port", "is required")
+	} else if port, err := strconv.Atoi(c.Server.Port); err != nil || port < 1 || port > 65535 {
+		errs.add("server.port", "must be a number between 1 and 65535, got %q", c.Server.Port)
+	}
+
+	if c.Server.ConfigWatchInterval < 0 {
+		errs.add("server.config_watch_interval", "must not be negative")
+	}
+	for _, proxy := range c.Server.TrustedProxies {
+		if net.ParseIP(proxy) == nil {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				errs.add("server.trusted_proxies", "%q is not an IP address or CIDR", proxy)
+			}
+		}
+	}
+	if c.Server.MaxBodyBytes < 0 {
+		errs.add("server.max_body_bytes", "must not be negative")
+	}
+
+	cors := c.Server.CORS
+	for _, origin := range cors.AllowedOrigins {
+		if origin == "*" {
+			if cors.AllowCredentials {
+				errs.add("server.cors.allowed_origins", "cannot contain \"*\" when server.cors.allow_credentials is true")
+			}
+		} else if !isHTTPURL(origin) || strings.TrimSuffix(origin, "/") != origin {
+			errs.add("server.cors.allowed_origins", "%q must be \"*\" or an origin such as \"https://tasks.example.com\"", origin)
+		}
+	}
+	if cors.MaxAge < 0 {
+		errs.add("server.cors.max_age", "must not be negative")
+	}
+
+	if tls := c.Server.TLS; tls.Enabled {
+		if tls.CertFile == "" {
+			errs.add("server.tls.cert_file", "is required when server.tls.enabled is true")
+		} else if _, err := os.Stat(tls.CertFile); err != nil {
+			errs.add("server.tls.cert_file", "cannot be read: %v", err)
+		}
+		if tls.KeyFile == "" {
+			errs.add("server.tls.key_file", "is required when server.tls.enabled is true")
+		} else if _, err := os.Stat(tls.KeyFile); err != nil {
+			errs.add("server.tls.key_file", "cannot be read: %v", err)
+		}
+		if tls.RedirectHTTP {
+			if port, err := strconv.Atoi(tls.HTTPPort); err != nil || port < 1 || port > 65535 {
+				errs.add("server.tls.http_port", "must be a number between 1 and 65535, got %q", tls.HTTPPort)
+			} else if tls.HTTPPort == c.Server.Port {
+				errs.add("server.tls.http_port", "must differ from server.port")
+			}
+		}
+	}
+
+	if c.Server.SecurityHeaders.HSTSMaxAge < 0 {
+		errs.add("server.security_headers.hsts_max_age", "must not be negative")
+	}
+
+	if !supportedDatabaseTypes[c.Database.Type] {
+		errs.add("database.type", "%q is not supported (supported: sqlite3)", c.Database.Type)
+	}
+	if c.Database.Name == "" {
+		errs.add("database.name", "is required")
+	}
+	if c.Database.Backup.Interval < 0 {
+		errs.add("database.backup.interval", "must not be negative")
+	}
+	if c.Database.Backup.Keep < 0 {
+		errs.add("database.backup.keep", "must not be negative")
+	}
+
+	if c.OpenAI.UseLLM {
+		if c.OpenAI.APIKey == "" {
+			errs.add("openai.api_key", "is required when openai.use_llm is true")
+		}
+		if c.OpenAI.Model == "" {
+			errs.add("openai.model", "is required when openai.use_llm is true")
+		}
+	}
+	if c.OpenAI.BaseURL != "" {
+		if !isHTTPURL(c.OpenAI.BaseURL) {
+			errs.add("openai.base_url", "must be an http(s) URL, got %q", c.OpenAI.BaseURL)
+		}
+	} else if c.OpenAI.UseLLM {
+		errs.add("openai.base_url", "is required when openai.use_llm is true")
+	}
+
+	if c.Trash.RetentionDays < 0 {
+		errs.add("trash.retention_days", "must not be negative")
+	}
+	if c.Trash.PurgeInterval < 0 {
+		errs.add("trash.purge_interval", "must not be negative")
+	}
+
+	s := c.Scheduling
+	if s.UrgentWithin <= 0 {
+		errs.add("scheduling.urgent_within", "must be positive")
+	}
+	if s.HighWithin < s.UrgentWithin {
+		errs.add("scheduling.high_within", "must not be shorter than scheduling.urgent_within")
+	}
+	if s.MediumWithin < s.HighWithin {
+		errs.add("scheduling.medium_within", "must not be shorter than scheduling.high_within")
+	}
+	if s.RecalculateInterval < 0 {
+		errs.add("scheduling.recalculate_interval", "must not be negative")
+	}
+
+	if c.Notifications.WebhookURL != "" && !isHTTPURL(c.Notifications.WebhookURL) {
+		errs.add("notifications.webhook_url", "must be an http(s) URL, got %q", c.Notifications.WebhookURL)
+	}
+
+	if c.Auth.AccessTokenTTL <= 0 {
+		errs.add("auth.access_token_ttl", "must be positive")
+	}
+	if c.Auth.RefreshTokenTTL < c.Auth.AccessTokenTTL {
+		errs.add("auth.refresh_token_ttl", "must not be shorter than auth.access_token_ttl")
+	}
+	if c.RateLimit.Enabled {
+		validateRateLimitRule(&errs, "rate_limit.default", c.RateLimit.Default)
+		validateRateLimitRule(&errs, "rate_limit.llm", c.RateLimit.LLM)
+	}
+
+	if c.Workload.WeeklyCapacityHours <= 0 {
+		errs.add("workload.weekly_capacity_hours", "must be positive")
+	}
+	if c.Estimation.MinSamples <= 0 {
+		errs.add("estimation.min_samples", "must be positive")
+	}
+	if c.Estimation.MaxSamples < c.Estimation.MinSamples {
+		errs.add("estimation.max_samples", "must not be less than estimation.min_samples")
+	}
+	if c.Estimation.MaxFactor < 1 {
+		errs.add("estimation.max_factor", "must be at least 1")
+	}
+	if c.Forecast.Trials < 100 || c.Forecast.Trials > 100000 {
+		errs.add("forecast.trials", "must be between 100 and 100000")
+	}
+	if c.Forecast.MinSamples <= 0 {
+		errs.add("forecast.min_samples", "must be positive")
+	}
+	if c.Forecast.MaxSamples < c.Forecast.MinSamples {
+		errs.add("forecast.max_samples", "must not be less than forecast.min_samples")
+	}
+	if c.Recurrence.LeadTime < 0 {
+		errs.add("recurrence.lead_time", "must not be negative")
+	}
+	if c.Recurrence.CheckInterval < 0 {
+		errs.add("recurrence.check_interval", "must not be negative")
+	}
+
+	if !assignableDefaultRoles[c.Auth.DefaultRole] {
+		errs.add("auth.default_role", "must be member or viewer, got %q", c.Auth.DefaultRole)
+	}
+
+	validateUrgency(&errs, c.Urgency)
+
+	for _, detector := range c.Redaction.Detectors {
+		if !isRedactionDetector(detector) {
+			errs.add("redaction.detectors", "%q is not a detector (available: %s)", detector, strings.Join(RedactionDetectors, ", "))
+		}
+	}
+	for i, pattern := range c.Redaction.CustomPatterns {
+		field := fmt.Sprintf("redaction.custom_patterns[%d]", i)
+		if !redactionPatternName.MatchString(pattern.Name) {
+			errs.add(field+".name", "must start with a letter and contain only letters, digits and underscores, got %q", pattern.Name)
+		}
+		if pattern.Pattern == "" {
+			errs.add(field+".pattern", "is required")
+		} else if re, err := regexp.Compile(pattern.Pattern); err != nil {
+			errs.add(field+".pattern", "is not a valid regular expression: %v", err)
+		} else if re.MatchString("") {
+			// It would put a placeholder between every character
+			errs.add(field+".pattern", "must not match empty text, got %q", pattern.Pattern)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func validateRateLimitRule(errs *ValidationError, field string, rule RateLimitRule) {
+	if rule.Requests <= 0 {
+		errs.add(field+".requests", "must be positive when rate_limit.enabled is true")
+	}
+	if rule.Period <= 0 {
+		errs.add(field+".period", "must be positive")
+	}
+	if rule.Burst < 0 {
+		errs.add(field+".burst", "must not be negative")
+	}
+}
+
+func validateUrgency(errs *ValidationError, u UrgencyConfig) {
+	names := make(map[string]bool, len(u.Policies))
+	for i, policy := range u.Policies {
+		field := fmt.Sprintf("urgency.policies[%d]", i)
+		if policy.Name == "" {
+			errs.add(field+".name", "is required")
+		} else if names[policy.Name] {
+			errs.add(field+".name", "%q is used by another policy", policy.Name)
+		}
+		names[policy.Name] = true
+
+		if !isUrgencyCurve(policy.Curve) {
+			errs.add(field+".curve", "%q is not a curve (available: %s)", policy.Curve, strings.Join(UrgencyCurves, ", "))
+		}
+		if policy.Horizon <= 0 {
+			errs.add(field+".horizon", "must be positive")
+		}
+		if policy.RemainingWorkWeight < 0 || policy.RemainingWorkWeight > 1 {
+			errs.add(field+".remaining_work_weight", "must be between 0 and 1")
+		}
+		if policy.BlockedWeight < 0 || policy.BlockedWeight > 1 {
+			errs.add(field+".blocked_weight", "must be between 0 and 1")
+		}
+		if policy.ManualPriorityBoost < 0 {
+			errs.add(field+".manual_priority_boost", "must not be negative")
+		}
+		for status, weight := range policy.StatusWeights {
+			if !urgencyStatuses[status] {
+				errs.add(field+".status_weights", "%q is not a task status", status)
+			} else if weight < 0 {
+				errs.add(field+".status_weights", "weight for %q must not be negative", status)
+			}
+		}
+		if policy.UrgentWithin < 0 || policy.HighWithin < 0 || policy.MediumWithin < 0 {
+			errs.add(field, "priority thresholds must not be negative")
+		}
+	}
+
+	if !names[u.DefaultPolicy] {
+		errs.add("urgency.default_policy", "%q is not one of urgency.policies", u.DefaultPolicy)
+	}
+}
+
+func isUrgencyCurve(name string) bool {
+	for _, curve := range UrgencyCurves {
+		if curve == name {
+			return true
+		}
+	}
+	return false
+}
+
+func isRedactionDetector(name string) bool {
+	for _, detector := range RedactionDetectors {
+		if detector == name {
+			return true
+		}
+	}
+	return false
+}
+
+func isHTTPURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
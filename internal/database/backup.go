@@ -0,0 +1,261 @@
+package database
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"task-manager/internal/config"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+const backupPrefix = "backup-"
+
+type BackupInfo struct {
+	Name      string    `json:"name"`
+	Path      string    `json:"path"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Backup writes a consistent copy of the running SQLite database into the
+// configured backup directory using VACUUM INTO, then applies rotation.
+func Backup() (*BackupInfo, error) {
+	cfg := config.GetConfig()
+	if cfg.Database.Type != "sqlite3" {
+		return nil, fmt.Errorf("backups are only supported for sqlite3, not %s", cfg.Database.Type)
+	}
+
+	// Backups hold password and token hashes and workspace LLM keys, so
+	// only the server's user may read them
+	dir := backupDir()
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	now := time.Now()
+	name := backupPrefix + now.Format("20060102-150405.000") + ".db"
+	path := filepath.Join(dir, name)
+
+	// VACUUM INTO fills an empty file as it finds it, keeping its mode
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create backup: %w", err)
+	}
+	file.Close()
+
+	if err := DB.Exec("VACUUM INTO ?", path).Error; err != nil {
+		os.Remove(path)
+		return nil, fmt.Errorf("failed to back up database: %w", err)
+	}
+
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat backup: %w", err)
+	}
+
+	if err := rotateBackups(dir, cfg.Database.Backup.Keep); err != nil {
+		return nil, err
+	}
+
+	return &BackupInfo{
+		Name:      name,
+		Path:      path,
+		Size:      stat.Size(),
+		CreatedAt: now,
+	}, nil
+}
+
+// ListBackups returns the backups in the configured directory, newest first.
+func ListBackups() ([]BackupInfo, error) {
+	dir := backupDir()
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return []BackupInfo{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup directory: %w", err)
+	}
+
+	backups := make([]BackupInfo, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || !isBackupFile(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat backup: %w", err)
+		}
+		backups = append(backups, BackupInfo{
+			Name:      entry.Name(),
+			Path:      filepath.Join(dir, entry.Name()),
+			Size:      info.Size(),
+			CreatedAt: info.ModTime(),
+		})
+	}
+
+	// Names embed the timestamp, so they sort chronologically.
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].Name > backups[j].Name
+	})
+
+	return backups, nil
+}
+
+// Restore replaces the configured database file with a backup. It must run
+// while the server is stopped; the current file is kept next to it with a
+// .pre-restore suffix.
+func Restore(backupPath string) error {
+	cfg := config.GetConfig()
+	if cfg.Database.Type != "sqlite3" {
+		return fmt.Errorf("restore is only supported for sqlite3, not %s", cfg.Database.Type)
+	}
+	if isMemoryDatabase(cfg.Database.Name) {
+		return fmt.Errorf("cannot restore into an in-memory database, configure a database file first")
+	}
+
+	if err := checkBackup(backupPath); err != nil {
+		return err
+	}
+
+	target := cfg.Database.Name
+	tmp := target + ".restore"
+	if err := copyFile(backupPath, tmp); err != nil {
+		return fmt.Errorf("failed to copy backup: %w", err)
+	}
+
+	if _, err := os.Stat(target); err == nil {
+		if err := os.Rename(target, target+".pre-restore"); err != nil {
+			os.Remove(tmp)
+			return fmt.Errorf("failed to move current database aside: %w", err)
+		}
+	}
+	for _, suffix := range []string{"-wal", "-shm"} {
+		os.Remove(target + suffix)
+	}
+
+	if err := os.Rename(tmp, target); err != nil {
+		return fmt.Errorf("failed to restore database: %w", err)
+	}
+
+	return nil
+}
+
+// StartBackupSchedule takes a backup on the configured interval. It does
+// nothing when scheduled backups are disabled.
+func StartBackupSchedule() {
+	interval := config.GetConfig().Database.Backup.Interval
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			backup, err := Backup()
+			if err != nil {
+				log.Printf("Scheduled backup failed: %v", err)
+				continue
+			}
+			log.Printf("Scheduled backup written to %s", backup.Path)
+		}
+	}()
+}
+
+func backupDir() string {
+	dir := config.GetConfig().Database.Backup.Dir
+	if dir == "" {
+		return "backups"
+	}
+	return dir
+}
+
+func isBackupFile(name string) bool {
+	return strings.HasPrefix(name, backupPrefix) && strings.HasSuffix(name, ".db")
+}
+
+// IsMemoryDatabase reports whether the configured database lives in memory,
+// where only the server process can see it.
+func IsMemoryDatabase() bool {
+	return isMemoryDatabase(config.GetConfig().Database.Name)
+}
+
+func isMemoryDatabase(name string) bool {
+	return name == ":memory:" || strings.Contains(name, "mode=memory")
+}
+
+func rotateBackups(dir string, keep int) error {
+	if keep <= 0 {
+		return nil
+	}
+
+	backups, err := ListBackups()
+	if err != nil {
+		return err
+	}
+
+	if len(backups) <= keep {
+		return nil
+	}
+
+	for _, backup := range backups[keep:] {
+		if err := os.Remove(filepath.Join(dir, backup.Name)); err != nil {
+			return fmt.Errorf("failed to remove old backup %s: %w", backup.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func checkBackup(path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("backup not found: %w", err)
+	}
+
+	db, err := gorm.Open(sqlite.Open("file:"+path+"?mode=ro"), &gorm.Config{})
+	if err != nil {
+		return fmt.Errorf("failed to open backup: %w", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return fmt.Errorf("failed to open backup: %w", err)
+	}
+	defer sqlDB.Close()
+
+	var result string
+	if err := db.Raw("PRAGMA integrity_check").Scan(&result).Error; err != nil {
+		return fmt.Errorf("failed to check backup: %w", err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("backup failed integrity check: %s", result)
+	}
+
+	return nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
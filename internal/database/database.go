@@ -26,14 +26,111 @@ func InitDatabase() error {
 		return fmt.Errorf("failed to connect to database: %w", err)
 	}
 
+	// Every connection to ":memory:" opens its own empty database, so keep
+	// the pool to a single connection to share one.
+	if isMemoryDatabase(cfg.Database.Name) {
+		sqlDB, err := DB.DB()
+		if err != nil {
+			return fmt.Errorf("failed to configure database: %w", err)
+		}
+		sqlDB.SetMaxOpenConns(1)
+	}
+
 	// Auto migrate the schema
-	if err := DB.AutoMigrate(&models.Task{}, &models.SubTask{}); err != nil {
+	if err := DB.AutoMigrate(
+		&models.Task{}, &models.SubTask{}, &models.User{}, &models.APIToken{},
+		&models.Workspace{}, &models.WorkspaceMembership{}, &models.WorkspaceInvitation{}, &models.Team{},
+		&models.TaskHistory{}, &models.Calendar{}, &models.Holiday{}, &models.TimeEntry{},
+	); err != nil {
 		return fmt.Errorf("failed to migrate database: %w", err)
 	}
 
+	if err := ensureRunningTimerIndex(); err != nil {
+		return err
+	}
+
+	if err := ensureAdmin(); err != nil {
+		return err
+	}
+
+	if err := ensurePersonalWorkspaces(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ensureRunningTimerIndex allows each user one running timer. It is not
+// declared on the model because GORM reads a single-column unique index as a
+// unique column and, on the next migration, rebuilds time_entries with
+// user_id unique outright, which fails once anyone has two entries.
+func ensureRunningTimerIndex() error {
+	if err := DB.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_time_entries_running ON time_entries(user_id) WHERE ended_at IS NULL").Error; err != nil {
+		return fmt.Errorf("failed to create running timer index: %w", err)
+	}
+	return nil
+}
+
+// ensureAdmin promotes the oldest account when no admin exists, so databases
+// created before roles were introduced are not locked out of admin routes.
+func ensureAdmin() error {
+	var admins int64
+	if err := DB.Model(&models.User{}).Where("role = ?", models.RoleAdmin).Count(&admins).Error; err != nil {
+		return fmt.Errorf("failed to count admins: %w", err)
+	}
+	if admins > 0 {
+		return nil
+	}
+
+	var first models.User
+	err := DB.Order("id").Limit(1).Find(&first).Error
+	if err != nil {
+		return fmt.Errorf("failed to find first user: %w", err)
+	}
+	if first.ID == 0 {
+		return nil
+	}
+
+	if err := DB.Model(&first).Update("role", models.RoleAdmin).Error; err != nil {
+		return fmt.Errorf("failed to promote first user: %w", err)
+	}
 	return nil
 }
 
 func GetDB() *gorm.DB {
 	return DB
 }
+
+// ensurePersonalWorkspaces gives users created before workspaces existed a
+// personal workspace and moves their tasks, including trashed ones, into it.
+func ensurePersonalWorkspaces() error {
+	var users []models.User
+	if err := DB.Where("id NOT IN (?)", DB.Model(&models.WorkspaceMembership{}).Select("user_id")).
+		Find(&users).Error; err != nil {
+		return fmt.Errorf("failed to find users without a workspace: %w", err)
+	}
+
+	for _, user := range users {
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			workspace := models.PersonalWorkspace(user)
+			if err := tx.Create(&workspace).Error; err != nil {
+				return err
+			}
+			if err := tx.Create(&models.WorkspaceMembership{
+				WorkspaceID: workspace.ID,
+				UserID:      user.ID,
+				Role:        models.WorkspaceOwner,
+			}).Error; err != nil {
+				return err
+			}
+			return tx.Unscoped().Model(&models.Task{}).
+				Where("owner_id = ? AND (workspace_id = 0 OR workspace_id IS NULL)", user.ID).
+				Update("workspace_id", workspace.ID).Error
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create workspace for user %d: %w", user.ID, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"net/http"
+	"task-manager/internal/database"
+	"task-manager/internal/middleware"
+	"task-manager/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+type BackupHandler struct{}
+
+func NewBackupHandler() *BackupHandler {
+	return &BackupHandler{}
+}
+
+func (h *BackupHandler) CreateBackup(c *gin.Context) {
+	backup, err := database.Backup()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, backup)
+}
+
+func (h *BackupHandler) ListBackups(c *gin.Context) {
+	backups, err := database.ListBackups()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"backups": backups})
+}
+
+func (h *BackupHandler) RegisterRoutes(router *gin.Engine) {
+	api := router.Group("/api/v1", middleware.RequireAuth(), middleware.RequirePermission(models.PermConfigManage))
+	{
+		admin := api.Group("/admin")
+		{
+			admin.GET("/backups", h.ListBackups)
+			admin.POST("/backups", h.CreateBackup)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"task-manager/internal/middleware"
+	"task-manager/internal/models"
+	"task-manager/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type CalendarHandler struct {
+	calendarService *services.CalendarService
+}
+
+func NewCalendarHandler() *CalendarHandler {
+	return &CalendarHandler{
+		calendarService: services.NewCalendarService(),
+	}
+}
+
+func (h *CalendarHandler) ListCalendars(c *gin.Context) {
+	calendars, err := h.calendarService.ListCalendars(middleware.CurrentWorkspaceID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"calendars": calendars})
+}
+
+func (h *CalendarHandler) CreateCalendar(c *gin.Context) {
+	var req models.CalendarRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	calendar, err := h.calendarService.CreateCalendar(middleware.CurrentWorkspaceID(c), req)
+	if err != nil {
+		respondCalendarError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, calendar)
+}
+
+func (h *CalendarHandler) GetCalendar(c *gin.Context) {
+	id, ok := parseID(c, "id", "Invalid calendar ID")
+	if !ok {
+		return
+	}
+
+	calendar, err := h.calendarService.GetCalendar(id, middleware.CurrentWorkspaceID(c))
+	if err != nil {
+		respondCalendarError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, calendar)
+}
+
+func (h *CalendarHandler) UpdateCalendar(c *gin.Context) {
+	id, ok := parseID(c, "id", "Invalid calendar ID")
+	if !ok {
+		return
+	}
+
+	var req models.CalendarRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	calendar, err := h.calendarService.UpdateCalendar(id, middleware.CurrentWorkspaceID(c), req)
+	if err != nil {
+		respondCalendarError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, calendar)
+}
+
+func (h *CalendarHandler) DeleteCalendar(c *gin.Context) {
+	id, ok := parseID(c, "id", "Invalid calendar ID")
+	if !ok {
+		return
+	}
+
+	if err := h.calendarService.DeleteCalendar(id, middleware.CurrentWorkspaceID(c)); err != nil {
+		respondCalendarError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Calendar deleted"})
+}
+
+func (h *CalendarHandler) AddHoliday(c *gin.Context) {
+	id, ok := parseID(c, "id", "Invalid calendar ID")
+	if !ok {
+		return
+	}
+
+	var req models.HolidayRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	calendar, err := h.calendarService.AddHoliday(id, middleware.CurrentWorkspaceID(c), req)
+	if err != nil {
+		respondCalendarError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, calendar)
+}
+
+func (h *CalendarHandler) RemoveHoliday(c *gin.Context) {
+	id, ok := parseID(c, "id", "Invalid calendar ID")
+	if !ok {
+		return
+	}
+	holidayID, ok := parseID(c, "holidayId", "Invalid holiday ID")
+	if !ok {
+		return
+	}
+
+	calendar, err := h.calendarService.RemoveHoliday(id, holidayID, middleware.CurrentWorkspaceID(c))
+	if err != nil {
+		respondCalendarError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, calendar)
+}
+
+// ImportHolidays accepts an ICS file as the request body or as the "file"
+// field of a multipart form.
+func (h *CalendarHandler) ImportHolidays(c *gin.Context) {
+	id, ok := parseID(c, "id", "Invalid calendar ID")
+	if !ok {
+		return
+	}
+
+	var body io.Reader = c.Request.Body
+	if c.ContentType() == "multipart/form-data" {
+		file, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Missing file field"})
+			return
+		}
+		f, err := file.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to open uploaded file"})
+			return
+		}
+		defer f.Close()
+		body = f
+	}
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read ICS data"})
+		return
+	}
+
+	result, err := h.calendarService.ImportHolidays(id, middleware.CurrentWorkspaceID(c), data)
+	if err != nil {
+		respondCalendarError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+func (h *CalendarHandler) RegisterRoutes(router *gin.Engine) {
+	read := middleware.RequirePermission(models.PermTasksRead)
+	write := middleware.RequirePermission(models.PermTasksWrite)
+
+	api := router.Group("/api/v1", middleware.RequireAuth(), middleware.RequireWorkspace())
+	{
+		calendars := api.Group("/calendars")
+		{
+			calendars.GET("", read, h.ListCalendars)
+			calendars.POST("", write, h.CreateCalendar)
+			calendars.GET("/:id", read, h.GetCalendar)
+			calendars.PUT("/:id", write, h.UpdateCalendar)
+			calendars.DELETE("/:id", write, h.DeleteCalendar)
+
+			calendars.POST("/:id/holidays", write, h.AddHoliday)
+			calendars.POST("/:id/holidays/import", write, h.ImportHolidays)
+			calendars.DELETE("/:id/holidays/:holidayId", write, h.RemoveHoliday)
+		}
+	}
+}
+
+func respondCalendarError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Calendar not found"})
+	case errors.Is(err, services.ErrHolidayExists):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidTimezone), errors.Is(err, services.ErrInvalidWorkingDays),
+		errors.Is(err, services.ErrInvalidWorkingHours), errors.Is(err, services.ErrInvalidHolidayDate),
+		errors.Is(err, services.ErrInvalidICS):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
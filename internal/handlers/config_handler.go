@@ -1,11 +1,13 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"task-manager/internal/config"
+	"task-manager/internal/llm"
+	"task-manager/internal/middleware"
+	"task-manager/internal/models"
 	"github.com/gin-gonic/gin"
-	"gopkg.in/yaml.v2"
-	"os"
 )
 
 type ConfigHandler struct{}
@@ -19,11 +21,45 @@ func NewConfigHandler() *ConfigHandler {
 	return &ConfigHandler{}
 }
 
+func (h *ConfigHandler) GetConfig(c *gin.Context) {
+	c.JSON(http.StatusOK, config.GetConfig().View())
+}
+
+func (h *ConfigHandler) UpdateConfig(c *gin.Context) {
+	var patch config.Patch
+	if err := c.ShouldBindJSON(&patch); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	dryRun := c.Query("dry_run") == "true"
+
+	cfg, err := config.ApplyPatch(&patch, dryRun)
+	if err != nil {
+		var validationErr config.ValidationError
+		if errors.As(err, &validationErr) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error": "Invalid configuration",
+				"fields": validationErr,
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"dry_run": dryRun,
+		"config": cfg.View(),
+	})
+}
+
 func (h *ConfigHandler) GetOpenAIConfig(c *gin.Context) {
 	cfg := config.GetConfig()
 	
 	c.JSON(http.StatusOK, gin.H{
-		"api_key": cfg.OpenAI.APIKey,
+		"api_key": config.MaskSecret(cfg.OpenAI.APIKey),
+		"api_key_set": cfg.OpenAI.APIKey != "",
 		"model": cfg.OpenAI.Model,
 		"use_llm": cfg.OpenAI.UseLLM,
 	})
@@ -36,59 +72,86 @@ func (h *ConfigHandler) UpdateOpenAIConfig(c *gin.Context) {
 		return
 	}
 
-	// Update config in memory
-	cfg := config.GetConfig()
-	cfg.OpenAI.APIKey = req.APIKey
-	
-	// Update model if provided
+	patch := config.Patch{OpenAI: &config.OpenAIPatch{}}
+	if req.APIKey != "" {
+		patch.OpenAI.APIKey = &req.APIKey
+	}
 	if req.Model != "" {
-		cfg.OpenAI.Model = req.Model
+		patch.OpenAI.Model = &req.Model
 	}
-	
-	// Save to config file
-	configPath := "configs/config.yaml"
-	data, err := os.ReadFile(configPath)
+
+	cfg, err := config.ApplyPatch(&patch, false)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read config file"})
+		var validationErr config.ValidationError
+		if errors.As(err, &validationErr) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save OpenAI configuration"})
 		return
 	}
 	
-	var fileConfig config.Config
-	if err := yaml.Unmarshal(data, &fileConfig); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse config file"})
-		return
+	c.JSON(http.StatusOK, gin.H{
+		"message": "OpenAI configuration updated successfully",
+		"api_key": config.MaskSecret(cfg.OpenAI.APIKey),
+		"model": cfg.OpenAI.Model,
+	})
+}
+
+func (h *ConfigHandler) VerifyOpenAIKey(c *gin.Context) {
+	var req struct {
+		APIKey string `json:"api_key"`
 	}
-	
-	fileConfig.OpenAI.APIKey = req.APIKey
-	if req.Model != "" {
-		fileConfig.OpenAI.Model = req.Model
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 	}
-	
-	updatedData, err := yaml.Marshal(fileConfig)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate updated config"})
+
+	if err := llm.NewLLMClient().VerifyAPIKey(req.APIKey); err != nil {
+		c.JSON(http.StatusOK, gin.H{"valid": false, "error": err.Error()})
 		return
 	}
-	
-	if err := os.WriteFile(configPath, updatedData, 0644); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to write config file"})
+
+	c.JSON(http.StatusOK, gin.H{"valid": true})
+}
+
+// TestOpenAIConnection probes a base URL before it is saved. An omitted
+// base_url tests the configured one; the configured key is only sent there,
+// so another base_url needs its own api_key.
+func (h *ConfigHandler) TestOpenAIConnection(c *gin.Context) {
+	var req struct {
+		BaseURL string `json:"base_url"`
+		APIKey  string `json:"api_key"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	result, err := llm.NewLLMClient().TestConnection(req.BaseURL, req.APIKey)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	
-	c.JSON(http.StatusOK, gin.H{
-		"message": "OpenAI configuration updated successfully",
-		"api_key": req.APIKey,
-		"model": cfg.OpenAI.Model,
-	})
+
+	c.JSON(http.StatusOK, result)
 }
 
 func (h *ConfigHandler) RegisterRoutes(router *gin.Engine) {
-	api := router.Group("/api/v1")
+	api := router.Group("/api/v1", middleware.RequireAuth(), middleware.RequirePermission(models.PermConfigManage))
 	{
 		config := api.Group("/config")
 		{
+			config.GET("", h.GetConfig)
+			config.PUT("", h.UpdateConfig)
 			config.GET("/openai", h.GetOpenAIConfig)
 			config.PUT("/openai", h.UpdateOpenAIConfig)
+			config.POST("/openai/verify", h.VerifyOpenAIKey)
+			config.POST("/openai/test", h.TestOpenAIConnection)
 		}
 	}
-} 
\ No newline at end of file
+}
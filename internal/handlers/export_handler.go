@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"task-manager/internal/middleware"
+	"task-manager/internal/models"
+	"task-manager/internal/services"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ExportHandler struct {
+	exportService *services.ExportService
+}
+
+var exportContentTypes = map[models.ExportFormat]string{
+	models.ExportFormatJSON:     "application/json",
+	models.ExportFormatCSV:      "text/csv",
+	models.ExportFormatMarkdown: "text/markdown",
+}
+
+func NewExportHandler() *ExportHandler {
+	return &ExportHandler{
+		exportService: services.NewExportService(),
+	}
+}
+
+func (h *ExportHandler) Export(c *gin.Context) {
+	format := models.ExportFormat(c.DefaultQuery("format", string(models.ExportFormatJSON)))
+	contentType, ok := exportContentTypes[format]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid export format, expected json, csv or md"})
+		return
+	}
+
+	data, err := h.exportService.Export(format, middleware.CurrentWorkspaceID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	filename := fmt.Sprintf("tasks-%s.%s", time.Now().Format("20060102-150405"), format)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Data(http.StatusOK, contentType, data)
+}
+
+func (h *ExportHandler) Import(c *gin.Context) {
+	format := models.ExportFormat(c.DefaultQuery("format", string(models.ExportFormatJSON)))
+	if format != models.ExportFormatJSON && format != models.ExportFormatCSV {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid import format, expected json or csv"})
+		return
+	}
+
+	mode := models.ConflictMode(c.DefaultQuery("conflict", string(models.ConflictSkip)))
+	switch mode {
+	case models.ConflictSkip, models.ConflictOverwrite, models.ConflictDuplicate:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid conflict mode, expected skip, overwrite or duplicate"})
+		return
+	}
+
+	var body io.Reader = c.Request.Body
+	if c.ContentType() == "multipart/form-data" {
+		file, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Missing file field"})
+			return
+		}
+		f, err := file.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to open uploaded file"})
+			return
+		}
+		defer f.Close()
+		body = f
+	}
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read import data"})
+		return
+	}
+
+	result, err := h.exportService.Import(format, data, mode, middleware.CurrentUserID(c), middleware.CurrentWorkspaceID(c))
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidImport) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+func (h *ExportHandler) RegisterRoutes(router *gin.Engine) {
+	api := router.Group("/api/v1", middleware.RequireAuth(), middleware.RequireWorkspace())
+	{
+		api.GET("/export", middleware.RequirePermission(models.PermTasksRead), h.Export)
+		api.POST("/import", middleware.RequirePermission(models.PermTasksWrite), h.Import)
+	}
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"net/http"
+	"task-manager/internal/middleware"
+	"task-manager/internal/models"
+	"task-manager/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type InsightsHandler struct {
+	estimationService *services.EstimationService
+}
+
+func NewInsightsHandler() *InsightsHandler {
+	return &InsightsHandler{
+		estimationService: services.NewEstimationService(),
+	}
+}
+
+// GetEstimation reports how far tracked hours have strayed from estimates
+// in the workspace, per member and per team.
+func (h *InsightsHandler) GetEstimation(c *gin.Context) {
+	insights, err := h.estimationService.GetInsights(middleware.CurrentWorkspaceID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, insights)
+}
+
+func (h *InsightsHandler) RegisterRoutes(router *gin.Engine) {
+	api := router.Group("/api/v1", middleware.RequireAuth(), middleware.RequireWorkspace())
+	{
+		insights := api.Group("/insights")
+		{
+			insights.GET("/estimation", middleware.RequirePermission(models.PermTasksRead), h.GetEstimation)
+		}
+	}
+}
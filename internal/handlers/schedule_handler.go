@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"task-manager/internal/middleware"
+	"task-manager/internal/models"
+	"task-manager/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const icsContentType = "text/calendar; charset=utf-8"
+
+type ScheduleHandler struct {
+	scheduleService *services.ScheduleService
+}
+
+func NewScheduleHandler() *ScheduleHandler {
+	return &ScheduleHandler{
+		scheduleService: services.NewScheduleService(),
+	}
+}
+
+func (h *ScheduleHandler) GetPlan(c *gin.Context) {
+	id, ok := parseID(c, "id", "Invalid task ID")
+	if !ok {
+		return
+	}
+
+	plan, err := h.scheduleService.GetPlan(id, middleware.CurrentWorkspaceID(c))
+	if err != nil {
+		respondScheduleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, plan)
+}
+
+// Replan schedules a task's open sub-tasks again from now on.
+func (h *ScheduleHandler) Replan(c *gin.Context) {
+	id, ok := parseID(c, "id", "Invalid task ID")
+	if !ok {
+		return
+	}
+
+	plan, err := h.scheduleService.Replan(id, middleware.CurrentWorkspaceID(c))
+	if err != nil {
+		respondScheduleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, plan)
+}
+
+// GetForecast simulates the task's remaining work to give likely completion
+// dates and the chance of meeting the deadline.
+func (h *ScheduleHandler) GetForecast(c *gin.Context) {
+	id, ok := parseID(c, "id", "Invalid task ID")
+	if !ok {
+		return
+	}
+
+	forecast, err := h.scheduleService.Forecast(id, middleware.CurrentWorkspaceID(c))
+	if err != nil {
+		respondScheduleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, forecast)
+}
+
+func (h *ScheduleHandler) GetPlanICS(c *gin.Context) {
+	id, ok := parseID(c, "id", "Invalid task ID")
+	if !ok {
+		return
+	}
+
+	data, err := h.scheduleService.PlanICS(id, middleware.CurrentWorkspaceID(c))
+	if err != nil {
+		respondScheduleError(c, err)
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("task-%d-plan.ics", id)))
+	c.Data(http.StatusOK, icsContentType, data)
+}
+
+// GetScheduleICS serves the planned work of the whole workspace, optionally
+// for ?user_id= only.
+func (h *ScheduleHandler) GetScheduleICS(c *gin.Context) {
+	var userID *uint
+	if value := c.Query("user_id"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+			return
+		}
+		uid := uint(id)
+		userID = &uid
+	}
+
+	data, err := h.scheduleService.ScheduleICS(middleware.CurrentWorkspaceID(c), userID)
+	if err != nil {
+		respondScheduleError(c, err)
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="schedule.ics"`)
+	c.Data(http.StatusOK, icsContentType, data)
+}
+
+func (h *ScheduleHandler) RegisterRoutes(router *gin.Engine) {
+	api := router.Group("/api/v1", middleware.RequireAuth(), middleware.RequireWorkspace())
+	{
+		tasks := api.Group("/tasks")
+		{
+			tasks.GET("/:id/plan", middleware.RequirePermission(models.PermTasksRead), h.GetPlan)
+			tasks.POST("/:id/plan", middleware.RequirePermission(models.PermTasksWrite), h.Replan)
+			tasks.GET("/:id/forecast", middleware.RequirePermission(models.PermTasksRead), h.GetForecast)
+		}
+	}
+
+	// Calendar apps subscribe to feeds by URL, so these also accept a feed
+	// token in the query string
+	feeds := router.Group("/api/v1", middleware.RequireFeedAuth(), middleware.RequireWorkspace())
+	{
+		feeds.GET("/tasks/:id/plan.ics", middleware.RequirePermission(models.PermFeedRead), h.GetPlanICS)
+		feeds.GET("/schedule.ics", middleware.RequirePermission(models.PermFeedRead), h.GetScheduleICS)
+	}
+}
+
+func respondScheduleError(c *gin.Context, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
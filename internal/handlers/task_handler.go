@@ -1,12 +1,16 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
+	"task-manager/internal/middleware"
 	"task-manager/internal/models"
 	"task-manager/internal/services"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type TaskHandler struct {
@@ -26,22 +30,30 @@ func (h *TaskHandler) CreateTask(c *gin.Context) {
 		return
 	}
 
-	task, err := h.taskService.CreateTask(req)
+	task, err := h.taskService.CreateTask(req, middleware.CurrentUserID(c), middleware.CurrentWorkspaceID(c))
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidDeadline) || errors.Is(err, services.ErrInvalidTimezone) || errors.Is(err, services.ErrInvalidRecurrence) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	localize(c, task)
 	c.JSON(http.StatusCreated, task)
 }
 
 func (h *TaskHandler) GetAllTasks(c *gin.Context) {
-	tasks, err := h.taskService.GetAllTasks()
+	tasks, err := h.taskService.GetAllTasks(middleware.CurrentWorkspaceID(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	for i := range tasks {
+		localize(c, &tasks[i])
+	}
 	c.JSON(http.StatusOK, gin.H{"tasks": tasks})
 }
 
@@ -53,12 +65,13 @@ func (h *TaskHandler) GetTaskByID(c *gin.Context) {
 		return
 	}
 
-	task, err := h.taskService.GetTaskByID(uint(id))
+	task, err := h.taskService.GetTaskByID(uint(id), middleware.CurrentWorkspaceID(c))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
 		return
 	}
 
+	localize(c, task)
 	c.JSON(http.StatusOK, task)
 }
 
@@ -78,7 +91,11 @@ func (h *TaskHandler) UpdateTaskStatus(c *gin.Context) {
 		return
 	}
 
-	if err := h.taskService.UpdateTaskStatus(uint(id), req.Status); err != nil {
+	if err := h.taskService.UpdateTaskStatus(uint(id), req.Status, middleware.CurrentWorkspaceID(c)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -94,24 +111,487 @@ func (h *TaskHandler) DeleteTask(c *gin.Context) {
 		return
 	}
 
-	if err := h.taskService.DeleteTask(uint(id)); err != nil {
+	if err := h.taskService.DeleteTask(uint(id), middleware.CurrentWorkspaceID(c)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Task moved to trash"})
+}
+
+func (h *TaskHandler) SetPriority(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+		return
+	}
+
+	var req models.PriorityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	task, err := h.taskService.SetPriority(uint(id), req.Priority, middleware.CurrentUserID(c), middleware.CurrentWorkspaceID(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		case errors.Is(err, services.ErrInvalidPriority):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	localize(c, task)
+	c.JSON(http.StatusOK, task)
+}
+
+func (h *TaskHandler) UpdateDeadline(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+		return
+	}
+
+	var req models.DeadlineRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	task, err := h.taskService.UpdateDeadline(uint(id), req, middleware.CurrentUserID(c), middleware.CurrentWorkspaceID(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		case errors.Is(err, services.ErrInvalidDeadline), errors.Is(err, services.ErrInvalidTimezone):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	localize(c, task)
+	c.JSON(http.StatusOK, task)
+}
+
+// SetRecurrence makes a task repeat on an RRULE; an empty rrule ends the
+// series.
+func (h *TaskHandler) SetRecurrence(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+		return
+	}
+
+	var req models.RecurrenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	task, err := h.taskService.SetRecurrence(uint(id), req.RRULE, middleware.CurrentWorkspaceID(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		case errors.Is(err, services.ErrInvalidRecurrence):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	localize(c, task)
+	c.JSON(http.StatusOK, task)
+}
+
+// PreviewRecurrence lists the next ?count= (default 5) deadlines of a
+// task's series.
+func (h *TaskHandler) PreviewRecurrence(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+		return
+	}
+
+	count, err := strconv.Atoi(c.DefaultQuery("count", "5"))
+	if err != nil || count < 1 || count > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "count must be a number from 1 to 100"})
+		return
+	}
+
+	preview, err := h.taskService.PreviewRecurrence(uint(id), count, middleware.CurrentWorkspaceID(c))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Task deleted successfully"})
+	c.JSON(http.StatusOK, preview)
+}
+
+// GetFeasibility checks whether a task's remaining work fits before its
+// deadline.
+func (h *TaskHandler) GetFeasibility(c *gin.Context) {
+	h.checkFeasibility(c, false)
+}
+
+// SuggestScopeCuts checks feasibility and, when the work does not fit, asks
+// the LLM what to cut.
+func (h *TaskHandler) SuggestScopeCuts(c *gin.Context) {
+	h.checkFeasibility(c, true)
+}
+
+func (h *TaskHandler) checkFeasibility(c *gin.Context, suggest bool) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+		return
+	}
+
+	feasibility, err := h.taskService.CheckFeasibility(uint(id), middleware.CurrentWorkspaceID(c), suggest)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, feasibility)
+}
+
+func (h *TaskHandler) GetTaskHistory(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+		return
+	}
+
+	history, err := h.taskService.GetTaskHistory(uint(id), middleware.CurrentWorkspaceID(c))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"history": history})
+}
+
+func (h *TaskHandler) PreviewUrgency(c *gin.Context) {
+	var names []string
+	for _, name := range strings.Split(c.Query("policies"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+
+	preview, err := h.taskService.PreviewUrgency(middleware.CurrentWorkspaceID(c), names)
+	if err != nil {
+		if errors.Is(err, services.ErrUnknownUrgencyPolicy) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, preview)
+}
+
+func (h *TaskHandler) AssignTask(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+		return
+	}
+
+	var req models.AssignRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	task, err := h.taskService.AssignTask(uint(id), req.AssigneeID, middleware.CurrentWorkspaceID(c))
+	if err != nil {
+		respondAssignmentError(c, err)
+		return
+	}
+
+	localize(c, task)
+	c.JSON(http.StatusOK, task)
+}
+
+func (h *TaskHandler) AssignSubTask(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+		return
+	}
+
+	subTaskID, err := strconv.ParseUint(c.Param("subTaskId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sub-task ID"})
+		return
+	}
+
+	var req models.AssignRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	task, err := h.taskService.AssignSubTask(uint(id), uint(subTaskID), req.AssigneeID, middleware.CurrentWorkspaceID(c))
+	if err != nil {
+		respondAssignmentError(c, err)
+		return
+	}
+
+	localize(c, task)
+	c.JSON(http.StatusOK, task)
+}
+
+// UpdateSubTask changes a sub-task's status or estimate; the task's work is
+// replanned.
+func (h *TaskHandler) UpdateSubTask(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+		return
+	}
+
+	subTaskID, err := strconv.ParseUint(c.Param("subTaskId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sub-task ID"})
+		return
+	}
+
+	var req models.SubTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	task, err := h.taskService.UpdateSubTask(uint(id), uint(subTaskID), req, middleware.CurrentWorkspaceID(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Task or sub-task not found"})
+		case errors.Is(err, services.ErrInvalidStatus), errors.Is(err, services.ErrInvalidEstimate):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	localize(c, task)
+	c.JSON(http.StatusOK, task)
+}
+
+func (h *TaskHandler) AddWatcher(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+		return
+	}
+
+	var req models.WatchRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if req.UserID == 0 {
+		req.UserID = middleware.CurrentUserID(c)
+	}
+	if !canManageWatcher(c, req.UserID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Your role only allows watching tasks yourself"})
+		return
+	}
+
+	task, err := h.taskService.AddWatcher(uint(id), req.UserID, middleware.CurrentWorkspaceID(c))
+	if err != nil {
+		respondAssignmentError(c, err)
+		return
+	}
+
+	localize(c, task)
+	c.JSON(http.StatusOK, task)
+}
+
+func (h *TaskHandler) RemoveWatcher(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+		return
+	}
+
+	userID, err := strconv.ParseUint(c.Param("userId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	if !canManageWatcher(c, uint(userID)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Your role only allows watching tasks yourself"})
+		return
+	}
+
+	task, err := h.taskService.RemoveWatcher(uint(id), uint(userID), middleware.CurrentWorkspaceID(c))
+	if err != nil {
+		respondAssignmentError(c, err)
+		return
+	}
+
+	localize(c, task)
+	c.JSON(http.StatusOK, task)
+}
+
+func (h *TaskHandler) GetTrash(c *gin.Context) {
+	trash, err := h.taskService.GetTrash(middleware.CurrentWorkspaceID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tasks": trash})
+}
+
+func (h *TaskHandler) RestoreTask(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+		return
+	}
+
+	task, err := h.taskService.RestoreTask(uint(id), middleware.CurrentWorkspaceID(c))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Task not found in trash"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	localize(c, task)
+	c.JSON(http.StatusOK, task)
+}
+
+func (h *TaskHandler) PurgeTask(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+		return
+	}
+
+	if err := h.taskService.PurgeTask(uint(id), middleware.CurrentWorkspaceID(c)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Task not found in trash"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Task permanently deleted"})
+}
+
+func (h *TaskHandler) EmptyTrash(c *gin.Context) {
+	purged, err := h.taskService.EmptyTrash(middleware.CurrentWorkspaceID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Trash emptied", "purged": purged})
+}
+
+// localize renders a task's deadline in the caller's timezone.
+func localize(c *gin.Context, task *models.TaskResponse) {
+	task.Localize(middleware.CurrentLocation(c))
 }
 
 func (h *TaskHandler) RegisterRoutes(router *gin.Engine) {
-	api := router.Group("/api/v1")
+	api := router.Group("/api/v1", middleware.RequireAuth(), middleware.RequireWorkspace())
 	{
 		tasks := api.Group("/tasks")
 		{
-			tasks.POST("", h.CreateTask)
-			tasks.GET("", h.GetAllTasks)
-			tasks.GET("/:id", h.GetTaskByID)
-			tasks.PUT("/:id/status", h.UpdateTaskStatus)
-			tasks.DELETE("/:id", h.DeleteTask)
+			tasks.POST("", middleware.RequirePermission(models.PermTasksWrite), middleware.LLMRateLimit(), h.CreateTask)
+			tasks.GET("", middleware.RequirePermission(models.PermTasksRead), h.GetAllTasks)
+			tasks.GET("/urgency-preview", middleware.RequirePermission(models.PermTasksRead), h.PreviewUrgency)
+			tasks.GET("/:id", middleware.RequirePermission(models.PermTasksRead), h.GetTaskByID)
+			tasks.PUT("/:id/status", middleware.RequirePermission(models.PermTasksWrite), middleware.RecurrenceLLMRateLimit(), h.UpdateTaskStatus)
+			tasks.PUT("/:id/priority", middleware.RequirePermission(models.PermTasksWrite), h.SetPriority)
+			tasks.PUT("/:id/deadline", middleware.RequirePermission(models.PermTasksWrite), h.UpdateDeadline)
+			tasks.PUT("/:id/recurrence", middleware.RequirePermission(models.PermTasksWrite), middleware.RecurrenceLLMRateLimit(), h.SetRecurrence)
+			tasks.GET("/:id/recurrence", middleware.RequirePermission(models.PermTasksRead), h.PreviewRecurrence)
+			tasks.GET("/:id/feasibility", middleware.RequirePermission(models.PermTasksRead), h.GetFeasibility)
+			tasks.POST("/:id/scope-cuts", middleware.RequirePermission(models.PermTasksWrite), middleware.LLMRateLimit(), h.SuggestScopeCuts)
+			tasks.GET("/:id/history", middleware.RequirePermission(models.PermTasksRead), h.GetTaskHistory)
+			tasks.DELETE("/:id", middleware.RequirePermission(models.PermTasksWrite), h.DeleteTask)
+			tasks.POST("/:id/restore", middleware.RequirePermission(models.PermTasksWrite), h.RestoreTask)
+			tasks.PUT("/:id/assignee", middleware.RequirePermission(models.PermTasksWrite), h.AssignTask)
+			tasks.PUT("/:id/subtasks/:subTaskId", middleware.RequirePermission(models.PermTasksWrite), h.UpdateSubTask)
+			tasks.PUT("/:id/subtasks/:subTaskId/assignee", middleware.RequirePermission(models.PermTasksWrite), h.AssignSubTask)
+			tasks.POST("/:id/watchers", middleware.RequirePermission(models.PermTasksRead), h.AddWatcher)
+			tasks.DELETE("/:id/watchers/:userId", middleware.RequirePermission(models.PermTasksRead), h.RemoveWatcher)
+		}
+
+		trash := api.Group("/trash")
+		{
+			trash.GET("", middleware.RequirePermission(models.PermTasksRead), h.GetTrash)
+			trash.DELETE("", middleware.RequireOwnerOrPermission(models.PermTasksPurge), h.EmptyTrash)
+			trash.DELETE("/:id", middleware.RequireOwnerOrPermission(models.PermTasksPurge), h.PurgeTask)
 		}
 	}
 }
+
+// canManageWatcher lets anyone who can read a task watch it themselves, but
+// only users who can edit tasks add or remove other watchers.
+func canManageWatcher(c *gin.Context, userID uint) bool {
+	return userID == middleware.CurrentUserID(c) || middleware.CurrentRole(c).Can(models.PermTasksWrite)
+}
+
+func respondAssignmentError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+	case errors.Is(err, services.ErrUserNotInWorkspace):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
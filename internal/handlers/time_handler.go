@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"task-manager/internal/middleware"
+	"task-manager/internal/models"
+	"task-manager/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type TimeHandler struct {
+	timeService *services.TimeService
+}
+
+func NewTimeHandler() *TimeHandler {
+	return &TimeHandler{
+		timeService: services.NewTimeService(),
+	}
+}
+
+func (h *TimeHandler) StartTimer(c *gin.Context) {
+	taskID, subTaskID, ok := parseSubTaskIDs(c)
+	if !ok {
+		return
+	}
+
+	// The note is optional, so an empty body is fine
+	var req models.TimerRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	userID := middleware.CurrentUserID(c)
+	entry, err := h.timeService.StartTimer(taskID, subTaskID, userID, middleware.CurrentWorkspaceID(c), req)
+	if err != nil {
+		if errors.Is(err, services.ErrTimerRunning) {
+			running, _ := h.timeService.RunningTimer(userID)
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "timer": running})
+			return
+		}
+		respondTimeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, entry)
+}
+
+func (h *TimeHandler) StopTimer(c *gin.Context) {
+	taskID, subTaskID, ok := parseSubTaskIDs(c)
+	if !ok {
+		return
+	}
+
+	entry, err := h.timeService.StopTimer(taskID, subTaskID, middleware.CurrentUserID(c), middleware.CurrentWorkspaceID(c))
+	if err != nil {
+		respondTimeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, entry)
+}
+
+// StopRunningTimer stops the caller's running timer, whichever task it is on.
+func (h *TimeHandler) StopRunningTimer(c *gin.Context) {
+	entry, err := h.timeService.StopRunningTimer(middleware.CurrentUserID(c))
+	if err != nil {
+		respondTimeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, entry)
+}
+
+// GetTimer returns the caller's running timer, or null.
+func (h *TimeHandler) GetTimer(c *gin.Context) {
+	running, err := h.timeService.RunningTimer(middleware.CurrentUserID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"timer": running})
+}
+
+func (h *TimeHandler) AddEntry(c *gin.Context) {
+	taskID, subTaskID, ok := parseSubTaskIDs(c)
+	if !ok {
+		return
+	}
+
+	var req models.TimeEntryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	entry, err := h.timeService.AddEntry(taskID, subTaskID, middleware.CurrentUserID(c), middleware.CurrentWorkspaceID(c), req)
+	if err != nil {
+		respondTimeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, entry)
+}
+
+func (h *TimeHandler) ListEntries(c *gin.Context) {
+	taskID, ok := parseID(c, "id", "Invalid task ID")
+	if !ok {
+		return
+	}
+
+	entries, err := h.timeService.ListEntries(taskID, middleware.CurrentWorkspaceID(c))
+	if err != nil {
+		respondTimeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"entries": entries})
+}
+
+func (h *TimeHandler) DeleteEntry(c *gin.Context) {
+	taskID, ok := parseID(c, "id", "Invalid task ID")
+	if !ok {
+		return
+	}
+	entryID, ok := parseID(c, "entryId", "Invalid time entry ID")
+	if !ok {
+		return
+	}
+
+	if err := h.timeService.DeleteEntry(taskID, entryID, middleware.CurrentUserID(c), middleware.CurrentWorkspaceID(c)); err != nil {
+		respondTimeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Time entry deleted"})
+}
+
+func (h *TimeHandler) GetTaskTime(c *gin.Context) {
+	taskID, ok := parseID(c, "id", "Invalid task ID")
+	if !ok {
+		return
+	}
+
+	taskTime, err := h.timeService.GetTaskTime(taskID, middleware.CurrentWorkspaceID(c))
+	if err != nil {
+		respondTimeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, taskTime)
+}
+
+// GetReport compares actual with estimated hours across the workspace,
+// optionally for ?user_id= only.
+func (h *TimeHandler) GetReport(c *gin.Context) {
+	var userID *uint
+	if value := c.Query("user_id"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+			return
+		}
+		uid := uint(id)
+		userID = &uid
+	}
+
+	report, err := h.timeService.GetReport(middleware.CurrentWorkspaceID(c), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+func (h *TimeHandler) RegisterRoutes(router *gin.Engine) {
+	api := router.Group("/api/v1", middleware.RequireAuth(), middleware.RequireWorkspace())
+	{
+		tasks := api.Group("/tasks")
+		{
+			tasks.POST("/:id/subtasks/:subTaskId/timer/start", middleware.RequirePermission(models.PermTasksWrite), h.StartTimer)
+			tasks.POST("/:id/subtasks/:subTaskId/timer/stop", middleware.RequirePermission(models.PermTasksWrite), h.StopTimer)
+			tasks.POST("/:id/subtasks/:subTaskId/time-entries", middleware.RequirePermission(models.PermTasksWrite), h.AddEntry)
+			tasks.GET("/:id/time-entries", middleware.RequirePermission(models.PermTasksRead), h.ListEntries)
+			tasks.DELETE("/:id/time-entries/:entryId", middleware.RequirePermission(models.PermTasksWrite), h.DeleteEntry)
+			tasks.GET("/:id/time", middleware.RequirePermission(models.PermTasksRead), h.GetTaskTime)
+		}
+
+		api.GET("/timer", middleware.RequirePermission(models.PermTasksRead), h.GetTimer)
+		api.POST("/timer/stop", middleware.RequirePermission(models.PermTasksWrite), h.StopRunningTimer)
+		api.GET("/time-report", middleware.RequirePermission(models.PermTasksRead), h.GetReport)
+	}
+}
+
+func parseSubTaskIDs(c *gin.Context) (taskID, subTaskID uint, ok bool) {
+	if taskID, ok = parseID(c, "id", "Invalid task ID"); !ok {
+		return 0, 0, false
+	}
+	if subTaskID, ok = parseID(c, "subTaskId", "Invalid sub-task ID"); !ok {
+		return 0, 0, false
+	}
+	return taskID, subTaskID, true
+}
+
+func respondTimeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task, sub-task or time entry not found"})
+	case errors.Is(err, services.ErrTimerRunning), errors.Is(err, services.ErrNoRunningTimer), errors.Is(err, services.ErrNoTimer):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidTimeEntry):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNotEntryOwner):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"task-manager/internal/middleware"
+	"task-manager/internal/models"
+	"task-manager/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type TokenHandler struct {
+	tokenService *services.TokenService
+}
+
+func NewTokenHandler() *TokenHandler {
+	return &TokenHandler{
+		tokenService: services.NewTokenService(),
+	}
+}
+
+func (h *TokenHandler) CreateToken(c *gin.Context) {
+	var req models.CreateAPITokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	token, err := h.tokenService.CreateToken(middleware.CurrentUserID(c), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidScope), errors.Is(err, services.ErrInvalidExpiry), errors.Is(err, services.ErrTokenNameEmpty):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, token)
+}
+
+func (h *TokenHandler) ListTokens(c *gin.Context) {
+	tokens, err := h.tokenService.ListTokens(middleware.CurrentUserID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tokens": tokens})
+}
+
+func (h *TokenHandler) RevokeToken(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid token ID"})
+		return
+	}
+
+	if err := h.tokenService.RevokeToken(uint(id), middleware.CurrentUserID(c)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Token not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Token revoked"})
+}
+
+func (h *TokenHandler) RegisterRoutes(router *gin.Engine) {
+	api := router.Group("/api/v1", middleware.RequireAuth(), middleware.RequireSession())
+	{
+		tokens := api.Group("/auth/tokens")
+		{
+			tokens.GET("", h.ListTokens)
+			tokens.POST("", h.CreateToken)
+			tokens.DELETE("/:id", h.RevokeToken)
+		}
+	}
+}
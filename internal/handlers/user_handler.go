@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"task-manager/internal/middleware"
+	"task-manager/internal/models"
+	"task-manager/internal/services"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type UserHandler struct {
+	authService     *services.AuthService
+	workloadService *services.WorkloadService
+}
+
+func NewUserHandler() *UserHandler {
+	return &UserHandler{
+		authService:     services.NewAuthService(),
+		workloadService: services.NewWorkloadService(),
+	}
+}
+
+func (h *UserHandler) ListUsers(c *gin.Context) {
+	users, err := h.authService.ListUsers()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"users": users})
+}
+
+func (h *UserHandler) UpdateUserRole(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req models.UpdateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.authService.UpdateUserRole(uint(id), req.Role)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		case errors.Is(err, services.ErrInvalidRole):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrLastAdmin):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// GetWorkload reports a user's planned hours per week in the current
+// workspace. Query parameters: weeks (default 4, max 52) and from
+// (YYYY-MM-DD, default today).
+func (h *UserHandler) GetWorkload(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	weeks := 4
+	if raw := c.Query("weeks"); raw != "" {
+		weeks, err = strconv.Atoi(raw)
+		if err != nil || weeks < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "weeks must be a positive number"})
+			return
+		}
+	}
+
+	from := time.Now()
+	if raw := c.Query("from"); raw != "" {
+		from, err = time.ParseInLocation("2006-01-02", raw, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from must be a date such as 2024-01-31"})
+			return
+		}
+	}
+
+	workload, err := h.workloadService.GetWorkload(uint(id), middleware.CurrentWorkspaceID(c), from, weeks)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found in this workspace"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, workload)
+}
+
+func (h *UserHandler) RegisterRoutes(router *gin.Engine) {
+	api := router.Group("/api/v1", middleware.RequireAuth())
+	{
+		users := api.Group("/users", middleware.RequireWorkspace(), middleware.RequirePermission(models.PermTasksRead))
+		{
+			users.GET("/:id/workload", h.GetWorkload)
+		}
+
+		admin := api.Group("/admin", middleware.RequirePermission(models.PermUsersManage))
+		{
+			admin.GET("/users", h.ListUsers)
+			admin.PUT("/users/:id/role", h.UpdateUserRole)
+		}
+	}
+}
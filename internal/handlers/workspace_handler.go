@@ -0,0 +1,507 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"task-manager/internal/middleware"
+	"task-manager/internal/models"
+	"task-manager/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type WorkspaceHandler struct {
+	workspaceService *services.WorkspaceService
+}
+
+func NewWorkspaceHandler() *WorkspaceHandler {
+	return &WorkspaceHandler{
+		workspaceService: services.NewWorkspaceService(),
+	}
+}
+
+func (h *WorkspaceHandler) ListWorkspaces(c *gin.Context) {
+	workspaces, err := h.workspaceService.ListWorkspaces(middleware.CurrentUserID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"workspaces": workspaces})
+}
+
+func (h *WorkspaceHandler) CreateWorkspace(c *gin.Context) {
+	var req models.WorkspaceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	workspace, err := h.workspaceService.CreateWorkspace(middleware.CurrentUserID(c), req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, workspace)
+}
+
+func (h *WorkspaceHandler) GetWorkspace(c *gin.Context) {
+	id, ok := parseID(c, "id", "Invalid workspace ID")
+	if !ok {
+		return
+	}
+
+	workspace, err := h.workspaceService.GetWorkspace(id, middleware.CurrentUserID(c))
+	if err != nil {
+		respondWorkspaceError(c, err, "Workspace not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, workspace)
+}
+
+func (h *WorkspaceHandler) RenameWorkspace(c *gin.Context) {
+	id, ok := parseID(c, "id", "Invalid workspace ID")
+	if !ok {
+		return
+	}
+
+	var req models.WorkspaceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	workspace, err := h.workspaceService.RenameWorkspace(id, middleware.CurrentUserID(c), req)
+	if err != nil {
+		respondWorkspaceError(c, err, "Workspace not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, workspace)
+}
+
+func (h *WorkspaceHandler) ListMembers(c *gin.Context) {
+	id, ok := parseID(c, "id", "Invalid workspace ID")
+	if !ok {
+		return
+	}
+
+	members, err := h.workspaceService.ListMembers(id, middleware.CurrentUserID(c))
+	if err != nil {
+		respondWorkspaceError(c, err, "Workspace not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"members": members})
+}
+
+func (h *WorkspaceHandler) UpdateMemberRole(c *gin.Context) {
+	id, ok := parseID(c, "id", "Invalid workspace ID")
+	if !ok {
+		return
+	}
+	userID, ok := parseID(c, "userId", "Invalid user ID")
+	if !ok {
+		return
+	}
+
+	var req models.MemberRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	member, err := h.workspaceService.UpdateMemberRole(id, middleware.CurrentUserID(c), userID, req.Role)
+	if err != nil {
+		respondWorkspaceError(c, err, "Member not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, member)
+}
+
+func (h *WorkspaceHandler) RemoveMember(c *gin.Context) {
+	id, ok := parseID(c, "id", "Invalid workspace ID")
+	if !ok {
+		return
+	}
+	userID, ok := parseID(c, "userId", "Invalid user ID")
+	if !ok {
+		return
+	}
+
+	if err := h.workspaceService.RemoveMember(id, middleware.CurrentUserID(c), userID); err != nil {
+		respondWorkspaceError(c, err, "Member not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Member removed"})
+}
+
+func (h *WorkspaceHandler) Invite(c *gin.Context) {
+	id, ok := parseID(c, "id", "Invalid workspace ID")
+	if !ok {
+		return
+	}
+
+	var req models.InvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	invitation, err := h.workspaceService.Invite(id, middleware.CurrentUserID(c), req)
+	if err != nil {
+		respondWorkspaceError(c, err, "User not found")
+		return
+	}
+
+	c.JSON(http.StatusCreated, invitation)
+}
+
+func (h *WorkspaceHandler) ListWorkspaceInvitations(c *gin.Context) {
+	id, ok := parseID(c, "id", "Invalid workspace ID")
+	if !ok {
+		return
+	}
+
+	invitations, err := h.workspaceService.ListWorkspaceInvitations(id, middleware.CurrentUserID(c))
+	if err != nil {
+		respondWorkspaceError(c, err, "Workspace not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"invitations": invitations})
+}
+
+func (h *WorkspaceHandler) RevokeInvitation(c *gin.Context) {
+	id, ok := parseID(c, "id", "Invalid workspace ID")
+	if !ok {
+		return
+	}
+	invitationID, ok := parseID(c, "invitationId", "Invalid invitation ID")
+	if !ok {
+		return
+	}
+
+	if err := h.workspaceService.RevokeInvitation(id, middleware.CurrentUserID(c), invitationID); err != nil {
+		respondWorkspaceError(c, err, "Invitation not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation revoked"})
+}
+
+func (h *WorkspaceHandler) ListMyInvitations(c *gin.Context) {
+	invitations, err := h.workspaceService.ListUserInvitations(middleware.CurrentUserID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"invitations": invitations})
+}
+
+func (h *WorkspaceHandler) AcceptInvitation(c *gin.Context) {
+	id, ok := parseID(c, "id", "Invalid invitation ID")
+	if !ok {
+		return
+	}
+
+	workspace, err := h.workspaceService.AcceptInvitation(id, middleware.CurrentUserID(c))
+	if err != nil {
+		respondWorkspaceError(c, err, "Invitation not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, workspace)
+}
+
+func (h *WorkspaceHandler) DeclineInvitation(c *gin.Context) {
+	id, ok := parseID(c, "id", "Invalid invitation ID")
+	if !ok {
+		return
+	}
+
+	if err := h.workspaceService.DeclineInvitation(id, middleware.CurrentUserID(c)); err != nil {
+		respondWorkspaceError(c, err, "Invitation not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation declined"})
+}
+
+func (h *WorkspaceHandler) ListTeams(c *gin.Context) {
+	id, ok := parseID(c, "id", "Invalid workspace ID")
+	if !ok {
+		return
+	}
+
+	teams, err := h.workspaceService.ListTeams(id, middleware.CurrentUserID(c))
+	if err != nil {
+		respondWorkspaceError(c, err, "Workspace not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"teams": teams})
+}
+
+func (h *WorkspaceHandler) CreateTeam(c *gin.Context) {
+	id, ok := parseID(c, "id", "Invalid workspace ID")
+	if !ok {
+		return
+	}
+
+	var req models.TeamRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	team, err := h.workspaceService.CreateTeam(id, middleware.CurrentUserID(c), req)
+	if err != nil {
+		respondWorkspaceError(c, err, "Workspace not found")
+		return
+	}
+
+	c.JSON(http.StatusCreated, team)
+}
+
+func (h *WorkspaceHandler) UpdateTeam(c *gin.Context) {
+	id, ok := parseID(c, "id", "Invalid workspace ID")
+	if !ok {
+		return
+	}
+	teamID, ok := parseID(c, "teamId", "Invalid team ID")
+	if !ok {
+		return
+	}
+
+	var req models.TeamRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	team, err := h.workspaceService.UpdateTeam(id, middleware.CurrentUserID(c), teamID, req)
+	if err != nil {
+		respondWorkspaceError(c, err, "Team not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, team)
+}
+
+func (h *WorkspaceHandler) DeleteTeam(c *gin.Context) {
+	id, ok := parseID(c, "id", "Invalid workspace ID")
+	if !ok {
+		return
+	}
+	teamID, ok := parseID(c, "teamId", "Invalid team ID")
+	if !ok {
+		return
+	}
+
+	if err := h.workspaceService.DeleteTeam(id, middleware.CurrentUserID(c), teamID); err != nil {
+		respondWorkspaceError(c, err, "Team not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Team deleted"})
+}
+
+func (h *WorkspaceHandler) GetLLMSettings(c *gin.Context) {
+	id, ok := parseID(c, "id", "Invalid workspace ID")
+	if !ok {
+		return
+	}
+
+	settings, err := h.workspaceService.GetLLMSettings(id, middleware.CurrentUserID(c))
+	if err != nil {
+		respondWorkspaceError(c, err, "Workspace not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+func (h *WorkspaceHandler) UpdateLLMSettings(c *gin.Context) {
+	id, ok := parseID(c, "id", "Invalid workspace ID")
+	if !ok {
+		return
+	}
+
+	var patch models.WorkspaceLLMPatch
+	if err := c.ShouldBindJSON(&patch); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	settings, err := h.workspaceService.UpdateLLMSettings(id, middleware.CurrentUserID(c), patch)
+	if err != nil {
+		respondWorkspaceError(c, err, "Workspace not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+func (h *WorkspaceHandler) GetUrgencyPolicy(c *gin.Context) {
+	id, ok := parseID(c, "id", "Invalid workspace ID")
+	if !ok {
+		return
+	}
+
+	policy, err := h.workspaceService.GetUrgencyPolicy(id, middleware.CurrentUserID(c))
+	if err != nil {
+		respondWorkspaceError(c, err, "Workspace not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+func (h *WorkspaceHandler) SetUrgencyPolicy(c *gin.Context) {
+	id, ok := parseID(c, "id", "Invalid workspace ID")
+	if !ok {
+		return
+	}
+
+	var req models.UrgencyPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	policy, err := h.workspaceService.SetUrgencyPolicy(id, middleware.CurrentUserID(c), req)
+	if err != nil {
+		respondWorkspaceError(c, err, "Workspace not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+func (h *WorkspaceHandler) SetCalendar(c *gin.Context) {
+	id, ok := parseID(c, "id", "Invalid workspace ID")
+	if !ok {
+		return
+	}
+
+	var req models.WorkspaceCalendarRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	workspace, err := h.workspaceService.SetCalendar(id, middleware.CurrentUserID(c), req.CalendarID)
+	if err != nil {
+		respondWorkspaceError(c, err, "Workspace not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, workspace)
+}
+
+// SetMemberCalendar picks the working calendar a member is scheduled by.
+func (h *WorkspaceHandler) SetMemberCalendar(c *gin.Context) {
+	id, ok := parseID(c, "id", "Invalid workspace ID")
+	if !ok {
+		return
+	}
+	userID, ok := parseID(c, "userId", "Invalid user ID")
+	if !ok {
+		return
+	}
+
+	var req models.WorkspaceCalendarRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	member, err := h.workspaceService.SetMemberCalendar(id, middleware.CurrentUserID(c), userID, req.CalendarID)
+	if err != nil {
+		respondWorkspaceError(c, err, "Member not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, member)
+}
+
+func (h *WorkspaceHandler) RegisterRoutes(router *gin.Engine) {
+	read := middleware.RequirePermission(models.PermTasksRead)
+	write := middleware.RequirePermission(models.PermTasksWrite)
+
+	api := router.Group("/api/v1", middleware.RequireAuth())
+	{
+		workspaces := api.Group("/workspaces")
+		{
+			workspaces.GET("", read, h.ListWorkspaces)
+			workspaces.POST("", write, h.CreateWorkspace)
+			workspaces.GET("/:id", read, h.GetWorkspace)
+			workspaces.PUT("/:id", write, h.RenameWorkspace)
+
+			workspaces.GET("/:id/members", read, h.ListMembers)
+			workspaces.PUT("/:id/members/:userId", write, h.UpdateMemberRole)
+			workspaces.DELETE("/:id/members/:userId", read, h.RemoveMember)
+			workspaces.PUT("/:id/members/:userId/calendar", read, h.SetMemberCalendar)
+
+			workspaces.GET("/:id/invitations", write, h.ListWorkspaceInvitations)
+			workspaces.POST("/:id/invitations", write, h.Invite)
+			workspaces.DELETE("/:id/invitations/:invitationId", write, h.RevokeInvitation)
+
+			workspaces.GET("/:id/teams", read, h.ListTeams)
+			workspaces.POST("/:id/teams", write, h.CreateTeam)
+			workspaces.PUT("/:id/teams/:teamId", write, h.UpdateTeam)
+			workspaces.DELETE("/:id/teams/:teamId", write, h.DeleteTeam)
+
+			workspaces.GET("/:id/llm", write, h.GetLLMSettings)
+			workspaces.PUT("/:id/llm", write, h.UpdateLLMSettings)
+
+			workspaces.GET("/:id/urgency-policy", read, h.GetUrgencyPolicy)
+			workspaces.PUT("/:id/urgency-policy", write, h.SetUrgencyPolicy)
+			workspaces.PUT("/:id/calendar", write, h.SetCalendar)
+		}
+
+		invitations := api.Group("/invitations", read)
+		{
+			invitations.GET("", h.ListMyInvitations)
+			invitations.POST("/:id/accept", h.AcceptInvitation)
+			invitations.POST("/:id/decline", h.DeclineInvitation)
+		}
+	}
+}
+
+// parseID reads a numeric path parameter, responding with 400 and message
+// when it is malformed.
+func parseID(c *gin.Context, param, message string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(param), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": message})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func respondWorkspaceError(c *gin.Context, err error, notFound string) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": notFound})
+	case errors.Is(err, services.ErrNotWorkspaceMember), errors.Is(err, services.ErrNotWorkspaceOwner):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrLastWorkspaceOwner), errors.Is(err, services.ErrAlreadyMember),
+		errors.Is(err, services.ErrInvitationPending):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidWorkspaceRole), errors.Is(err, services.ErrTeamMemberNotInSpace),
+		errors.Is(err, services.ErrInvalidLLMBaseURL), errors.Is(err, services.ErrLLMKeyRequired),
+		errors.Is(err, services.ErrUnknownUrgencyPolicy), errors.Is(err, services.ErrCalendarNotInSpace):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -3,9 +3,12 @@ package llm
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"sort"
+	"strings"
 	"task-manager/internal/config"
 	"time"
 )
@@ -29,30 +32,51 @@ type Choice struct {
 }
 
 type LLMClient struct {
-	config *config.OpenAIConfig
-	client *http.Client
+	client   *http.Client
+	override func(*config.OpenAIConfig)
 }
 
 func NewLLMClient() *LLMClient {
-	cfg := config.GetConfig()
 	return &LLMClient{
-		config: &cfg.OpenAI,
 		client: &http.Client{
 			Timeout: 30 * time.Second,
 		},
 	}
 }
 
+// WithOverride returns a client whose settings are the global ones adjusted
+// by override, such as a workspace's own model or API key.
+func (c *LLMClient) WithOverride(override func(*config.OpenAIConfig)) *LLMClient {
+	return &LLMClient{
+		client:   c.client,
+		override: override,
+	}
+}
+
+// settings returns the current OpenAI settings. They are read per call so
+// config updates and reloads apply without restarting.
+func (c *LLMClient) settings() config.OpenAIConfig {
+	settings := config.GetConfig().OpenAI
+	if c.override != nil {
+		c.override(&settings)
+	}
+	return settings
+}
+
 func (c *LLMClient) GenerateTechnicalPlan(taskTitle, taskDescription string, deadline time.Time) (string, error) {
-	if !c.config.UseLLM {
+	if !c.settings().UseLLM {
 		return c.generateMockTechnicalPlan(taskTitle), nil
 	}
 
+	redactor, err := c.redactor()
+	if err != nil {
+		return "", err
+	}
+
 	prompt := fmt.Sprintf(`
 Please generate a detailed technical plan for the following development task:
 
-Task Title: %s
-Task Description: %s
+%s
 Deadline: %s
 
 Please provide:
@@ -64,21 +88,35 @@ Please provide:
 6. Resource requirements
 
 Format the response in markdown.
-`, taskTitle, taskDescription, deadline.Format("2006-01-02 15:04:05"))
+`, redactor.taskInput(taskTitle, taskDescription), formatDeadline(deadline))
 
-	return c.callOpenAI(prompt)
+	response, err := c.callOpenAI(prompt)
+	if err != nil {
+		return "", err
+	}
+	return redactor.Restore(response), nil
+}
+
+// formatDeadline spells out the deadline's offset and zone so the model does
+// not have to guess which timezone it is in.
+func formatDeadline(deadline time.Time) string {
+	return fmt.Sprintf("%s (%s)", deadline.Format("Monday, 2006-01-02 15:04 -07:00"), deadline.Location())
 }
 
 func (c *LLMClient) GenerateWorkflow(taskTitle, taskDescription string) (string, error) {
-	if !c.config.UseLLM {
+	if !c.settings().UseLLM {
 		return c.generateMockWorkflow(taskTitle), nil
 	}
 
+	redactor, err := c.redactor()
+	if err != nil {
+		return "", err
+	}
+
 	prompt := fmt.Sprintf(`
 Generate a detailed workflow for the following development task:
 
-Task Title: %s
-Task Description: %s
+%s
 
 Please provide:
 1. Step-by-step development process
@@ -88,21 +126,29 @@ Please provide:
 5. Testing strategy
 
 Format the response as a structured workflow in markdown.
-`, taskTitle, taskDescription)
+`, redactor.taskInput(taskTitle, taskDescription))
 
-	return c.callOpenAI(prompt)
+	response, err := c.callOpenAI(prompt)
+	if err != nil {
+		return "", err
+	}
+	return redactor.Restore(response), nil
 }
 
 func (c *LLMClient) GenerateSubTasks(taskTitle, taskDescription string) ([]SubTaskSuggestion, error) {
-	if !c.config.UseLLM {
+	if !c.settings().UseLLM {
 		return c.generateMockSubTasks(taskTitle), nil
 	}
 
+	redactor, err := c.redactor()
+	if err != nil {
+		return nil, err
+	}
+
 	prompt := fmt.Sprintf(`
 Break down the following development task into smaller sub-tasks:
 
-Task Title: %s
-Task Description: %s
+%s
 
 Please provide a JSON array of sub-tasks with the following structure:
 [
@@ -117,25 +163,98 @@ Please provide a JSON array of sub-tasks with the following structure:
 ]
 
 Only return the JSON array, no additional text.
-`, taskTitle, taskDescription)
+`, redactor.taskInput(taskTitle, taskDescription))
 
 	response, err := c.callOpenAI(prompt)
 	if err != nil {
 		return nil, err
 	}
 
+	// Restore after decoding so original values containing quotes or
+	// backslashes cannot break the JSON
 	var subTasks []SubTaskSuggestion
 	if err := json.Unmarshal([]byte(response), &subTasks); err != nil {
 		return c.generateMockSubTasks(taskTitle), nil
 	}
+	for i := range subTasks {
+		subTasks[i].Title = redactor.Restore(subTasks[i].Title)
+		subTasks[i].Description = redactor.Restore(subTasks[i].Description)
+		subTasks[i].Priority = redactor.Restore(subTasks[i].Priority)
+	}
 
 	return subTasks, nil
 }
 
+// WorkItem is an open sub-task considered for scope cuts.
+type WorkItem struct {
+	Title          string
+	EstimatedHours int
+}
+
+// SuggestScopeCuts asks for ways to cut or defer the open work of a task
+// that cannot be done by its deadline. hoursLeft is the capacity available
+// before the deadline and problems are the reasons the plan does not fit.
+func (c *LLMClient) SuggestScopeCuts(taskTitle, taskDescription string, deadline time.Time, items []WorkItem, hoursLeft float64, problems []string) (string, error) {
+	if !c.settings().UseLLM {
+		return c.generateMockScopeCuts(items, hoursLeft), nil
+	}
+
+	redactor, err := c.redactor()
+	if err != nil {
+		return "", err
+	}
+
+	work := []string{"Open sub-tasks:"}
+	for _, item := range items {
+		work = append(work, fmt.Sprintf("- %s (%d hours)", item.Title, item.EstimatedHours))
+	}
+	var issues strings.Builder
+	for _, problem := range problems {
+		fmt.Fprintf(&issues, "- %s\n", redactor.Redact(problem))
+	}
+
+	prompt := fmt.Sprintf(`
+The following development task cannot be finished by its deadline as planned:
+
+%s
+Deadline: %s
+Hours available before the deadline: %.0f
+
+Problems found:
+%s
+Suggest how to cut or defer scope so the essential outcome still ships by the deadline:
+1. Sub-tasks to drop or defer, and why
+2. Sub-tasks to simplify, with a reduced estimate
+3. What the reduced scope still delivers
+
+Format the response in markdown.
+`, redactor.taskInput(taskTitle, taskDescription, work...), formatDeadline(deadline), hoursLeft, issues.String())
+
+	response, err := c.callOpenAI(prompt)
+	if err != nil {
+		return "", err
+	}
+	return redactor.Restore(response), nil
+}
+
+// redactor returns a Redactor for one prompt using the current redaction
+// settings.
+func (c *LLMClient) redactor() (*Redactor, error) {
+	return NewRedactor(config.GetConfig().Redaction)
+}
+
+// callOpenAI sends prompt, whose user content has already been redacted and
+// fenced, and returns the reply with placeholders still in it. Callers
+// restore the values once they have decoded the reply.
 func (c *LLMClient) callOpenAI(prompt string) (string, error) {
+	settings := c.settings()
 	request := OpenAIRequest{
-		Model: c.config.Model,
+		Model: settings.Model,
 		Messages: []Message{
+			{
+				Role:    "system",
+				Content: systemPrompt,
+			},
 			{
 				Role:    "user",
 				Content: prompt,
@@ -148,13 +267,13 @@ func (c *LLMClient) callOpenAI(prompt string) (string, error) {
 		return "", fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", c.config.BaseURL+"/chat/completions", bytes.NewBuffer(jsonData))
+	req, err := http.NewRequest("POST", settings.BaseURL+"/chat/completions", bytes.NewBuffer(jsonData))
 	if err != nil {
 		return "", fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+c.config.APIKey)
+	req.Header.Set("Authorization", "Bearer "+settings.APIKey)
 
 	resp, err := c.client.Do(req)
 	if err != nil {
@@ -190,6 +309,91 @@ func (c *LLMClient) callOpenAI(prompt string) (string, error) {
 	return response.Choices[0].Message.Content, nil
 }
 
+// ErrAPIKeyRequired is returned when a connection test names a base URL
+// other than the configured one without a key of its own.
+var ErrAPIKeyRequired = errors.New("an api_key is required to test a base_url other than the configured one")
+
+// ConnectionTest is the outcome of probing an OpenAI-compatible endpoint.
+type ConnectionTest struct {
+	BaseURL       string `json:"base_url"`
+	Reachable     bool   `json:"reachable"`
+	Authenticated bool   `json:"authenticated"`
+	StatusCode    int    `json:"status_code,omitempty"`
+	LatencyMS     int64  `json:"latency_ms"`
+	Error         string `json:"error,omitempty"`
+}
+
+// TestConnection lists models at baseURL with apiKey, which costs no tokens.
+// An empty baseURL tests the configured one. The configured key is only used
+// for the configured base URL; any other needs apiKey. The result never
+// includes the key.
+func (c *LLMClient) TestConnection(baseURL, apiKey string) (ConnectionTest, error) {
+	settings := c.settings()
+	if baseURL == "" {
+		baseURL = settings.BaseURL
+	}
+	if apiKey == "" {
+		if strings.TrimRight(baseURL, "/") != strings.TrimRight(settings.BaseURL, "/") {
+			return ConnectionTest{}, ErrAPIKeyRequired
+		}
+		apiKey = settings.APIKey
+	}
+
+	result := ConnectionTest{BaseURL: baseURL}
+
+	req, err := http.NewRequest("GET", strings.TrimRight(baseURL, "/")+"/models", nil)
+	if err != nil {
+		result.Error = fmt.Sprintf("invalid base URL: %v", err)
+		return result, nil
+	}
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	start := time.Now()
+	resp, err := c.client.Do(req)
+	result.LatencyMS = time.Since(start).Milliseconds()
+	if err != nil {
+		result.Error = fmt.Sprintf("failed to reach %s: %v", baseURL, err)
+		return result, nil
+	}
+	resp.Body.Close()
+
+	result.Reachable = true
+	result.StatusCode = resp.StatusCode
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		result.Authenticated = true
+	case http.StatusUnauthorized, http.StatusForbidden:
+		result.Error = fmt.Sprintf("API key was rejected by %s", baseURL)
+	case http.StatusTooManyRequests:
+		result.Authenticated = true
+		result.Error = "API key is valid but rate limited or out of quota"
+	default:
+		result.Error = fmt.Sprintf("unexpected status %d from %s", resp.StatusCode, baseURL)
+	}
+
+	return result, nil
+}
+
+// VerifyAPIKey checks that apiKey is accepted by the configured endpoint. An
+// empty apiKey checks the configured one. Errors never include the key.
+func (c *LLMClient) VerifyAPIKey(apiKey string) error {
+	if apiKey == "" && c.settings().APIKey == "" {
+		return fmt.Errorf("no API key configured")
+	}
+
+	result, err := c.TestConnection("", apiKey)
+	if err != nil {
+		return err
+	}
+	if !result.Authenticated {
+		return fmt.Errorf("%s", result.Error)
+	}
+	return nil
+}
+
 type SubTaskSuggestion struct {
 	Title          string `json:"title"`
 	Description    string `json:"description"`
@@ -237,6 +441,33 @@ func (c *LLMClient) generateMockTechnicalPlan(taskTitle string) string {
 - Standard development environment`, taskTitle)
 }
 
+// generateMockScopeCuts defers the largest sub-tasks until the rest fits in
+// hoursLeft.
+func (c *LLMClient) generateMockScopeCuts(items []WorkItem, hoursLeft float64) string {
+	total := 0
+	for _, item := range items {
+		total += item.EstimatedHours
+	}
+
+	largest := make([]WorkItem, len(items))
+	copy(largest, items)
+	sort.SliceStable(largest, func(a, b int) bool {
+		return largest[a].EstimatedHours > largest[b].EstimatedHours
+	})
+
+	var b strings.Builder
+	b.WriteString("# Scope Cut Suggestions\n\n## Defer\n")
+	for _, item := range largest {
+		if float64(total) <= hoursLeft {
+			break
+		}
+		total -= item.EstimatedHours
+		fmt.Fprintf(&b, "- %s (%d hours)\n", item.Title, item.EstimatedHours)
+	}
+	fmt.Fprintf(&b, "\n## Remaining Scope\n- %d hours of work against %.0f hours available\n", total, hoursLeft)
+	return b.String()
+}
+
 func (c *LLMClient) generateMockWorkflow(taskTitle string) string {
 	return fmt.Sprintf(`# Workflow for %s
 
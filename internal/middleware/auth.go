@@ -0,0 +1,174 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+	"task-manager/internal/models"
+	"task-manager/internal/services"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	userIDKey   = "userID"
+	usernameKey = "username"
+	roleKey     = "role"
+	timezoneKey = "timezone"
+	scopesKey   = "tokenScopes"
+)
+
+// RequireAuth rejects requests without a valid access token or personal
+// access token in the Authorization header and stores the caller's identity
+// in the context.
+func RequireAuth() gin.HandlerFunc {
+	authService := services.NewAuthService()
+	tokenService := services.NewTokenService()
+
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		token, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok || token == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+			return
+		}
+
+		if strings.HasPrefix(token, services.APITokenPrefix) {
+			apiToken, user, err := tokenService.Authenticate(token)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid, expired or revoked token"})
+				return
+			}
+
+			setIdentity(c, user)
+			c.Set(scopesKey, apiToken.Scopes)
+			c.Next()
+			return
+		}
+
+		claims, err := authService.ParseAccessToken(token)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+			return
+		}
+
+		// Load the user rather than trusting the token so role changes and
+		// deleted accounts take effect before the token expires.
+		user, err := authService.GetUser(claims.UserID())
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+			return
+		}
+
+		setIdentity(c, user)
+		c.Next()
+	}
+}
+
+// RequireFeedAuth is RequireAuth for calendar feeds, which calendar apps
+// fetch without custom headers: a personal access token with the feed scope
+// may be given as the token query parameter instead.
+func RequireFeedAuth() gin.HandlerFunc {
+	requireAuth := RequireAuth()
+	tokenService := services.NewTokenService()
+
+	return func(c *gin.Context) {
+		raw := c.Query("token")
+		if raw == "" || c.GetHeader("Authorization") != "" {
+			requireAuth(c)
+			return
+		}
+
+		apiToken, user, err := tokenService.Authenticate(raw)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid, expired or revoked token"})
+			return
+		}
+		// Tokens in URLs end up in logs and calendar apps, so only feed
+		// tokens are accepted there
+		if !apiToken.Scopes.Has(models.ScopeFeed) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Only tokens with the " + models.ScopeFeed + " scope can be passed in the URL"})
+			return
+		}
+
+		setIdentity(c, user)
+		c.Set(scopesKey, models.TokenScopes{models.ScopeFeed})
+		c.Next()
+	}
+}
+
+func setIdentity(c *gin.Context, user *models.User) {
+	c.Set(userIDKey, user.ID)
+	c.Set(usernameKey, user.Username)
+	c.Set(roleKey, user.Role)
+	c.Set(timezoneKey, user.PreferredTimezone())
+}
+
+// RequirePermission rejects callers whose role does not grant perm, and
+// personal access tokens that lack the matching scope. Interactive sessions
+// are not limited by scopes. It must run after RequireAuth.
+func RequirePermission(perm models.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !CurrentRole(c).Can(perm) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Your role does not allow " + string(perm)})
+			return
+		}
+
+		scope := perm.Scope()
+		if scopes, isToken := tokenScopes(c); isToken && !scopes.Allows(scope) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Token is missing the " + scope + " scope"})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequireSession rejects personal access tokens, for endpoints such as token
+// management that need an interactive login.
+func RequireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, isToken := tokenScopes(c); isToken {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "This endpoint requires an interactive login"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// CurrentUserID returns the authenticated user's ID, or 0 outside
+// RequireAuth.
+func CurrentUserID(c *gin.Context) uint {
+	return c.GetUint(userIDKey)
+}
+
+// CurrentRole returns the authenticated user's role, or "" outside
+// RequireAuth.
+func CurrentRole(c *gin.Context) models.Role {
+	role, _ := c.Get(roleKey)
+	r, _ := role.(models.Role)
+	return r
+}
+
+// CurrentLocation returns the authenticated user's preferred timezone, or
+// nil when they have none or outside RequireAuth.
+func CurrentLocation(c *gin.Context) *time.Location {
+	name := c.GetString(timezoneKey)
+	if name == "" {
+		return nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil
+	}
+	return loc
+}
+
+func tokenScopes(c *gin.Context) (models.TokenScopes, bool) {
+	value, ok := c.Get(scopesKey)
+	if !ok {
+		return nil, false
+	}
+	scopes, _ := value.(models.TokenScopes)
+	return scopes, true
+}
@@ -0,0 +1,186 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"task-manager/internal/config"
+	"task-manager/internal/services"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// bucket is a token bucket. Tokens refill continuously at the rule's rate up
+// to its burst size.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+type rateLimiter struct {
+	name string
+	rule func(*config.Config) config.RateLimitRule
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+// llmLimiter is shared by every route that calls the LLM, so together they
+// draw on one rate_limit.llm budget per client.
+var (
+	llmLimiter     *rateLimiter
+	llmLimiterOnce sync.Once
+)
+
+// RateLimit throttles requests using the default rule in rate_limit. Apply
+// it to the whole router.
+func RateLimit() gin.HandlerFunc {
+	return newRateLimiter("default", func(cfg *config.Config) config.RateLimitRule {
+		return cfg.RateLimit.Default
+	}).handle
+}
+
+// LLMRateLimit applies the stricter rate_limit.llm rule. Apply it to routes
+// that call the LLM, in addition to RateLimit.
+func LLMRateLimit() gin.HandlerFunc {
+	return sharedLLMLimiter().handle
+}
+
+// RecurrenceLLMRateLimit is LLMRateLimit for routes that only call the LLM
+// when recurrence.use_llm is on, such as completing a recurring task.
+func RecurrenceLLMRateLimit() gin.HandlerFunc {
+	l := sharedLLMLimiter()
+	return func(c *gin.Context) {
+		if !config.GetConfig().Recurrence.UseLLM {
+			c.Next()
+			return
+		}
+		l.handle(c)
+	}
+}
+
+func sharedLLMLimiter() *rateLimiter {
+	llmLimiterOnce.Do(func() {
+		llmLimiter = newRateLimiter("llm", func(cfg *config.Config) config.RateLimitRule {
+			return cfg.RateLimit.LLM
+		})
+	})
+	return llmLimiter
+}
+
+func newRateLimiter(name string, rule func(*config.Config) config.RateLimitRule) *rateLimiter {
+	l := &rateLimiter{
+		name:    name,
+		rule:    rule,
+		buckets: make(map[string]*bucket),
+	}
+	go l.sweep()
+	return l
+}
+
+func (l *rateLimiter) handle(c *gin.Context) {
+	cfg := config.GetConfig()
+	if !cfg.RateLimit.Enabled {
+		c.Next()
+		return
+	}
+
+	rule := l.rule(cfg)
+	capacity := float64(rule.Burst)
+	if capacity <= 0 {
+		capacity = float64(rule.Requests)
+	}
+	rate := float64(rule.Requests) / rule.Period.Seconds()
+
+	allowed, remaining, wait := l.take(clientKey(c), capacity, rate)
+
+	// Seconds until the bucket is full again
+	reset := math.Ceil((capacity - remaining) / rate)
+
+	c.Header("RateLimit-Policy", fmt.Sprintf("%d;w=%d;burst=%d;policy=%q", rule.Requests, int(rule.Period.Seconds()), int(capacity), l.name))
+	c.Header("RateLimit-Limit", strconv.Itoa(int(capacity)))
+	c.Header("RateLimit-Remaining", strconv.Itoa(int(remaining)))
+	c.Header("RateLimit-Reset", strconv.Itoa(int(reset)))
+
+	if !allowed {
+		retryAfter := int(math.Ceil(wait.Seconds()))
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+			"error":       fmt.Sprintf("Rate limit exceeded, retry in %d seconds", retryAfter),
+			"retry_after": retryAfter,
+		})
+		return
+	}
+
+	c.Next()
+}
+
+// take removes one token from key's bucket. It returns whether the request
+// is allowed, the tokens left and, when refused, how long until a token is
+// available.
+func (l *rateLimiter) take(key string, capacity, rate float64) (bool, float64, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, last: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = math.Min(capacity, b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / rate * float64(time.Second))
+		return false, b.tokens, wait
+	}
+
+	b.tokens--
+	return true, b.tokens, 0
+}
+
+// sweep drops buckets that have been idle long enough to be full again, so
+// memory does not grow with every client ever seen.
+func (l *rateLimiter) sweep() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		rule := l.rule(config.GetConfig())
+		idle := rule.Period
+		if idle < time.Minute {
+			idle = time.Minute
+		}
+
+		l.mu.Lock()
+		for key, b := range l.buckets {
+			if time.Since(b.last) > idle {
+				delete(l.buckets, key)
+			}
+		}
+		l.mu.Unlock()
+	}
+}
+
+// clientKey identifies who a request counts against: the user for access
+// tokens, the token for valid personal access tokens and otherwise the
+// client IP, so made-up credentials do not get a bucket of their own.
+func clientKey(c *gin.Context) string {
+	token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if ok && token != "" {
+		if strings.HasPrefix(token, services.APITokenPrefix) {
+			if apiToken, _, err := services.NewTokenService().Authenticate(token); err == nil {
+				return "token:" + strconv.FormatUint(uint64(apiToken.ID), 10)
+			}
+		} else if claims, err := services.NewAuthService().ParseAccessToken(token); err == nil {
+			return "user:" + strconv.FormatUint(uint64(claims.UserID()), 10)
+		}
+	}
+	return "ip:" + c.ClientIP()
+}
@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"task-manager/internal/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CORS answers cross-origin requests from the origins in server.cors. The
+// settings are read per request so config changes apply without a restart.
+func CORS() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cors := config.GetConfig().Server.CORS
+		origin := c.GetHeader("Origin")
+
+		if origin != "" {
+			c.Writer.Header().Add("Vary", "Origin")
+		}
+
+		if allowed, wildcard := originAllowed(cors.AllowedOrigins, origin); allowed {
+			if wildcard {
+				c.Header("Access-Control-Allow-Origin", "*")
+			} else {
+				c.Header("Access-Control-Allow-Origin", origin)
+			}
+			if cors.AllowCredentials {
+				c.Header("Access-Control-Allow-Credentials", "true")
+			}
+			c.Header("Access-Control-Expose-Headers", "RateLimit-Policy, RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, Retry-After")
+
+			if c.Request.Method == http.MethodOptions {
+				c.Header("Access-Control-Allow-Methods", strings.Join(cors.AllowedMethods, ", "))
+				c.Header("Access-Control-Allow-Headers", strings.Join(cors.AllowedHeaders, ", "))
+				if cors.MaxAge > 0 {
+					c.Header("Access-Control-Max-Age", strconv.Itoa(int(cors.MaxAge.Seconds())))
+				}
+			}
+		}
+
+		if c.Request.Method == http.MethodOptions {
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func originAllowed(allowed []string, origin string) (ok bool, wildcard bool) {
+	if origin == "" {
+		return false, false
+	}
+	for _, candidate := range allowed {
+		if candidate == "*" {
+			return true, true
+		}
+		if strings.EqualFold(candidate, origin) {
+			return true, false
+		}
+	}
+	return false, false
+}
+
+// SecurityHeaders sets standard hardening headers on every response. HSTS
+// is only sent on HTTPS requests so plain-HTTP development setups are not
+// pinned to HTTPS.
+func SecurityHeaders() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		headers := config.GetConfig().Server.SecurityHeaders
+
+		c.Header("X-Content-Type-Options", "nosniff")
+		c.Header("Referrer-Policy", "no-referrer")
+		if headers.FrameOptions != "" {
+			c.Header("X-Frame-Options", headers.FrameOptions)
+		}
+		if headers.ContentSecurityPolicy != "" {
+			c.Header("Content-Security-Policy", headers.ContentSecurityPolicy)
+		}
+		if headers.HSTSMaxAge > 0 && c.Request.TLS != nil {
+			c.Header("Strict-Transport-Security", fmt.Sprintf("max-age=%d; includeSubDomains", int(headers.HSTSMaxAge.Seconds())))
+		}
+
+		c.Next()
+	}
+}
+
+// BodyLimit rejects request bodies larger than server.max_body_bytes. Bodies
+// without a Content-Length are cut off at the limit while being read.
+func BodyLimit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit := config.GetConfig().Server.MaxBodyBytes
+		if limit <= 0 || c.Request.Body == nil {
+			c.Next()
+			return
+		}
+
+		if c.Request.ContentLength > limit {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{
+				"error": fmt.Sprintf("Request body exceeds the %d byte limit", limit),
+			})
+			return
+		}
+
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+		c.Next()
+	}
+}
+
+// RedirectToHTTPS returns a handler that sends plain HTTP requests to the
+// same path on the HTTPS port.
+func RedirectToHTTPS(httpsPort string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, ok := strings.Cut(host, ":"); ok {
+			host = h
+		}
+		if httpsPort != "443" {
+			host += ":" + httpsPort
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+	})
+}
@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"task-manager/internal/models"
+	"task-manager/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WorkspaceHeader selects the workspace a request acts on. Without it the
+// caller's personal workspace is used.
+const WorkspaceHeader = "X-Workspace-ID"
+
+const workspaceIDKey = "workspaceID"
+
+// RequireWorkspace resolves the workspace from the X-Workspace-ID header
+// (or workspace_id query parameter) and rejects callers who are not members.
+// It must run after RequireAuth.
+func RequireWorkspace() gin.HandlerFunc {
+	workspaceService := services.NewWorkspaceService()
+
+	return func(c *gin.Context) {
+		raw := c.GetHeader(WorkspaceHeader)
+		if raw == "" {
+			raw = c.Query("workspace_id")
+		}
+
+		var requested uint64
+		if raw != "" {
+			var err error
+			requested, err = strconv.ParseUint(raw, 10, 32)
+			if err != nil || requested == 0 {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid workspace ID"})
+				return
+			}
+		}
+
+		workspaceID, err := workspaceService.ResolveWorkspace(CurrentUserID(c), uint(requested))
+		if err != nil {
+			if errors.Is(err, services.ErrNotWorkspaceMember) {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error()})
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.Set(workspaceIDKey, workspaceID)
+		c.Next()
+	}
+}
+
+// CurrentWorkspaceID returns the workspace selected by RequireWorkspace.
+func CurrentWorkspaceID(c *gin.Context) uint {
+	return c.GetUint(workspaceIDKey)
+}
+
+// RequireOwnerOrPermission is RequirePermission that also lets owners of the
+// current workspace through. It must run after RequireWorkspace.
+func RequireOwnerOrPermission(perm models.Permission) gin.HandlerFunc {
+	workspaceService := services.NewWorkspaceService()
+
+	return func(c *gin.Context) {
+		if !CurrentRole(c).Can(perm) {
+			owner, err := workspaceService.IsOwner(CurrentWorkspaceID(c), CurrentUserID(c))
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			if !owner {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Only admins and workspace owners can " + string(perm)})
+				return
+			}
+		}
+
+		scope := perm.Scope()
+		if scopes, isToken := tokenScopes(c); isToken && !scopes.Allows(scope) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Token is missing the " + scope + " scope"})
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,69 @@
+package models
+
+import "time"
+
+// Token scopes. A token may carry several; see TokenScopes.Allows for how
+// they imply each other.
+const (
+	ScopeRead        = "read"
+	ScopeTasksWrite  = "tasks:write"
+	ScopeAdminConfig = "admin:config"
+	// ScopeFeed only reads calendar feeds. Calendar apps cannot send
+	// headers, so feed tokens may be given in the URL instead.
+	ScopeFeed = "feed"
+)
+
+var ValidScopes = []string{ScopeRead, ScopeTasksWrite, ScopeAdminConfig, ScopeFeed}
+
+type TokenScopes []string
+
+// Allows reports whether the scopes grant required. Any scope grants feed
+// access, any scope but feed grants read access, and admin:config grants
+// everything.
+func (s TokenScopes) Allows(required string) bool {
+	for _, scope := range s {
+		if scope == required || scope == ScopeAdminConfig || required == ScopeFeed ||
+			(required == ScopeRead && scope != ScopeFeed) {
+			return true
+		}
+	}
+	return false
+}
+
+// Has reports whether scope is among the scopes itself.
+func (s TokenScopes) Has(scope string) bool {
+	for _, own := range s {
+		if own == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// APIToken is a long-lived personal access token. Only a hash of the token
+// is stored; the plaintext is shown once when it is created.
+type APIToken struct {
+	ID         uint        `json:"id" gorm:"primaryKey"`
+	UserID     uint        `json:"user_id" gorm:"index;not null"`
+	Name       string      `json:"name" gorm:"not null"`
+	Prefix     string      `json:"prefix"`
+	TokenHash  string      `json:"-" gorm:"uniqueIndex;not null"`
+	Scopes     TokenScopes `json:"scopes" gorm:"type:json;serializer:json"`
+	ExpiresAt  *time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time  `json:"last_used_at"`
+	RevokedAt  *time.Time  `json:"revoked_at"`
+	CreatedAt  time.Time   `json:"created_at"`
+}
+
+type CreateAPITokenRequest struct {
+	Name      string     `json:"name" binding:"required"`
+	Scopes    []string   `json:"scopes" binding:"required"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// CreateAPITokenResponse carries the plaintext token, which cannot be
+// retrieved again.
+type CreateAPITokenResponse struct {
+	APIToken
+	Token string `json:"token"`
+}
@@ -0,0 +1,48 @@
+package models
+
+import "testing"
+
+func TestTokenScopesAllows(t *testing.T) {
+	tests := []struct {
+		scopes TokenScopes
+		// allowed lists the required scopes the token covers; the others
+		// of ValidScopes must be refused
+		allowed []string
+	}{
+		{scopes: nil},
+		{scopes: TokenScopes{}},
+		{scopes: TokenScopes{ScopeRead}, allowed: []string{ScopeRead, ScopeFeed}},
+		{scopes: TokenScopes{ScopeTasksWrite}, allowed: []string{ScopeRead, ScopeTasksWrite, ScopeFeed}},
+		{scopes: TokenScopes{ScopeAdminConfig}, allowed: []string{ScopeRead, ScopeTasksWrite, ScopeAdminConfig, ScopeFeed}},
+		{scopes: TokenScopes{ScopeFeed}, allowed: []string{ScopeFeed}},
+		{scopes: TokenScopes{ScopeFeed, ScopeRead}, allowed: []string{ScopeRead, ScopeFeed}},
+		{scopes: TokenScopes{ScopeFeed, ScopeTasksWrite}, allowed: []string{ScopeRead, ScopeTasksWrite, ScopeFeed}},
+	}
+
+	for _, tt := range tests {
+		for _, required := range ValidScopes {
+			want := false
+			for _, allowed := range tt.allowed {
+				want = want || allowed == required
+			}
+			if got := tt.scopes.Allows(required); got != want {
+				t.Errorf("%v.Allows(%q) = %v, want %v", tt.scopes, required, got, want)
+			}
+		}
+	}
+}
+
+func TestTokenScopesHas(t *testing.T) {
+	scopes := TokenScopes{ScopeAdminConfig, ScopeFeed}
+
+	for scope, want := range map[string]bool{
+		ScopeAdminConfig: true,
+		ScopeFeed:        true,
+		ScopeRead:        false,
+		ScopeTasksWrite:  false,
+	} {
+		if got := scopes.Has(scope); got != want {
+			t.Errorf("Has(%q) = %v, want %v", scope, got, want)
+		}
+	}
+}
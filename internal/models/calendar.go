@@ -0,0 +1,70 @@
+package models
+
+import "time"
+
+// Calendar defines a workspace's working time: the weekdays worked, the
+// working hours of each day in Timezone and the holidays that are not
+// worked. Times are "HH:MM" on a 24-hour clock.
+type Calendar struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	WorkspaceID uint      `json:"workspace_id" gorm:"index;not null"`
+	Name        string    `json:"name" gorm:"not null"`
+	Timezone    string    `json:"timezone" gorm:"not null"`
+	WorkingDays []string  `json:"working_days" gorm:"type:json;serializer:json"`
+	DayStart    string    `json:"day_start" gorm:"not null"`
+	DayEnd      string    `json:"day_end" gorm:"not null"`
+	Holidays    []Holiday `json:"holidays" gorm:"foreignKey:CalendarID"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Holiday is a whole day off, given as a date in the calendar's timezone.
+type Holiday struct {
+	ID         uint   `json:"id" gorm:"primaryKey"`
+	CalendarID uint   `json:"calendar_id" gorm:"uniqueIndex:idx_calendar_date;not null"`
+	Date       string `json:"date" gorm:"uniqueIndex:idx_calendar_date;not null"`
+	Name       string `json:"name"`
+}
+
+// CalendarRequest creates or replaces a calendar. Empty fields default to
+// UTC, Monday to Friday and 09:00 to 17:00.
+type CalendarRequest struct {
+	Name        string   `json:"name" binding:"required"`
+	Timezone    string   `json:"timezone"`
+	WorkingDays []string `json:"working_days"`
+	DayStart    string   `json:"day_start"`
+	DayEnd      string   `json:"day_end"`
+}
+
+type HolidayRequest struct {
+	Date string `json:"date" binding:"required"`
+	Name string `json:"name"`
+}
+
+// HolidayImportResult counts the days taken from an ICS file. Skipped days
+// were already holidays or came from recurring events, which are not
+// expanded.
+type HolidayImportResult struct {
+	Imported int `json:"imported"`
+	Skipped  int `json:"skipped"`
+}
+
+// WorkspaceCalendarRequest picks the calendar used for a workspace's or a
+// member's working time. A null calendar_id counts wall-clock time for a
+// workspace and falls back to the workspace's calendar for a member.
+type WorkspaceCalendarRequest struct {
+	CalendarID *uint `json:"calendar_id"`
+}
+
+// RemainingTime is the time left until a deadline. With a working calendar
+// it counts working time only and Days are working days of HoursPerDay
+// hours; otherwise Days are 24 hours.
+type RemainingTime struct {
+	Overdue     bool    `json:"overdue"`
+	Seconds     int64   `json:"seconds"`
+	Days        int     `json:"days"`
+	Hours       int     `json:"hours"`
+	Minutes     int     `json:"minutes"`
+	HoursPerDay float64 `json:"hours_per_day"`
+	WorkingTime bool    `json:"working_time"`
+}
@@ -0,0 +1,30 @@
+package models
+
+// EstimationBias compares the hours tracked on completed sub-tasks with what
+// was estimated for them. Factor is actual over estimated hours: 1.5 means
+// work took half as long again as estimated, and BiasPercent is the same as
+// a percentage (50). A bias is Calibrated once enough samples back it.
+type EstimationBias struct {
+	UserID         *uint   `json:"user_id,omitempty"`
+	TeamID         *uint   `json:"team_id,omitempty"`
+	Name           string  `json:"name"`
+	Samples        int     `json:"samples"`
+	EstimatedHours float64 `json:"estimated_hours"`
+	ActualHours    float64 `json:"actual_hours"`
+	Factor         float64 `json:"factor"`
+	BiasPercent    float64 `json:"bias_percent"`
+	Calibrated     bool    `json:"calibrated"`
+}
+
+// EstimationInsights is the estimation bias of a workspace and of each of its
+// members and teams. New sub-task estimates are scaled by the creator's own
+// bias, else their team's, else the workspace's, whichever is calibrated
+// first.
+type EstimationInsights struct {
+	WorkspaceID uint             `json:"workspace_id"`
+	Calibrate   bool             `json:"calibrate"`
+	MinSamples  int              `json:"min_samples"`
+	Workspace   EstimationBias   `json:"workspace"`
+	Users       []EstimationBias `json:"users"`
+	Teams       []EstimationBias `json:"teams"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// Event names delivered to notifications.webhook_url.
+const (
+	EventTaskEscalated = "task.escalated"
+)
+
+// WebhookEvent is the JSON body posted to the notifications webhook.
+type WebhookEvent struct {
+	Event      string      `json:"event"`
+	OccurredAt time.Time   `json:"occurred_at"`
+	Data       interface{} `json:"data"`
+}
+
+// TaskEscalation is the data of a task.escalated event.
+type TaskEscalation struct {
+	TaskID      uint      `json:"task_id"`
+	WorkspaceID uint      `json:"workspace_id"`
+	Title       string    `json:"title"`
+	Deadline    time.Time `json:"deadline"`
+	OldPriority string    `json:"old_priority"`
+	NewPriority string    `json:"new_priority"`
+	AssigneeID  *uint     `json:"assignee_id"`
+}
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+type ExportFormat string
+
+const (
+	ExportFormatJSON     ExportFormat = "json"
+	ExportFormatCSV      ExportFormat = "csv"
+	ExportFormatMarkdown ExportFormat = "md"
+)
+
+// ConflictMode decides what an import does with a task whose ID already
+// exists in the database.
+type ConflictMode string
+
+const (
+	ConflictSkip      ConflictMode = "skip"
+	ConflictOverwrite ConflictMode = "overwrite"
+	ConflictDuplicate ConflictMode = "duplicate"
+)
+
+const ExportVersion = 1
+
+type WorkspaceExport struct {
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exported_at"`
+	Tasks      []Task    `json:"tasks"`
+}
+
+type ImportResult struct {
+	Created     int           `json:"created"`
+	Overwritten int           `json:"overwritten"`
+	Duplicated  int           `json:"duplicated"`
+	Skipped     int           `json:"skipped"`
+	IDMap       map[uint]uint `json:"id_map,omitempty"`
+}
@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+type FeasibilityVerdict string
+
+const (
+	FeasibilityFeasible   FeasibilityVerdict = "feasible"
+	FeasibilityAtRisk     FeasibilityVerdict = "at_risk"
+	FeasibilityInfeasible FeasibilityVerdict = "infeasible"
+)
+
+// Feasibility compares the work left on a task with the time and capacity
+// left before its deadline. Reasons explain any verdict other than feasible.
+type Feasibility struct {
+	Verdict           FeasibilityVerdict `json:"verdict"`
+	Reasons           []string           `json:"reasons"`
+	CheckedAt         time.Time          `json:"checked_at"`
+	Deadline          time.Time          `json:"deadline"`
+	HoursLeft         float64            `json:"hours_left"`
+	WorkingTime       bool               `json:"working_time"`
+	RemainingHours    int                `json:"remaining_hours"`
+	CriticalPathHours int                `json:"critical_path_hours"`
+	CriticalPath      []uint             `json:"critical_path"`
+	CapacityHours     float64            `json:"capacity_hours"`
+	Assignees         []AssigneeCapacity `json:"assignees"`
+	ScopeCuts         string             `json:"scope_cuts,omitempty"`
+}
+
+// AssigneeCapacity is one person's share of a task against the hours they
+// have before its deadline. OtherHours is their open work on other tasks due
+// no later than this one.
+type AssigneeCapacity struct {
+	UserID        uint    `json:"user_id"`
+	Username      string  `json:"username"`
+	TaskHours     int     `json:"task_hours"`
+	OtherHours    int     `json:"other_hours"`
+	CapacityHours float64 `json:"capacity_hours"`
+}
+
+// DeadlineRequest moves a task's deadline. Deadline and Timezone are read as
+// in TaskRequest.
+type DeadlineRequest struct {
+	Deadline string `json:"deadline" binding:"required"`
+	Timezone string `json:"timezone"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+type ForecastBasis string
+
+const (
+	// ForecastHistory draws on the workspace's finished sub-tasks.
+	ForecastHistory ForecastBasis = "history"
+	// ForecastDefault assumes a default spread when there are too few.
+	ForecastDefault ForecastBasis = "default"
+)
+
+// Forecast is the spread of completion dates over simulated runs of a
+// task's open sub-tasks. P50 is the date half of the runs finish by, and
+// P85 and P95 likewise. OnTimeProbability is the share of runs that finish
+// by the deadline. EstimatedEnd is when the work would end if every
+// estimate were right.
+type Forecast struct {
+	TaskID            uint          `json:"task_id"`
+	Title             string        `json:"title"`
+	Deadline          time.Time     `json:"deadline"`
+	ForecastAt        time.Time     `json:"forecast_at"`
+	Basis             ForecastBasis `json:"basis"`
+	Samples           int           `json:"samples"`
+	Trials            int           `json:"trials"`
+	RemainingHours    int           `json:"remaining_hours"`
+	EstimatedEnd      time.Time     `json:"estimated_end"`
+	P50               time.Time     `json:"p50"`
+	P85               time.Time     `json:"p85"`
+	P95               time.Time     `json:"p95"`
+	OnTimeProbability float64       `json:"on_time_probability"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// HistorySource says who made a recorded change.
+type HistorySource string
+
+const (
+	HistorySourceUser      HistorySource = "user"
+	HistorySourceScheduler HistorySource = "scheduler"
+)
+
+// TaskHistory records one change to a task field. UserID is set for changes
+// made by a user and nil for automatic ones.
+type TaskHistory struct {
+	ID        uint          `json:"id" gorm:"primaryKey"`
+	TaskID    uint          `json:"task_id" gorm:"index;not null"`
+	Field     string        `json:"field" gorm:"not null"`
+	OldValue  string        `json:"old_value"`
+	NewValue  string        `json:"new_value"`
+	Source    HistorySource `json:"source" gorm:"not null"`
+	UserID    *uint         `json:"user_id"`
+	CreatedAt time.Time     `json:"created_at"`
+}
+
+// PriorityRequest sets a manual priority override. A null priority clears
+// the override so the priority follows the deadline again.
+type PriorityRequest struct {
+	Priority *string `json:"priority"`
+}
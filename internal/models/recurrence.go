@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// RecurrenceRequest sets a task's RRULE. An empty RRULE ends the series.
+type RecurrenceRequest struct {
+	RRULE string `json:"rrule"`
+}
+
+// RecurrencePreview lists the next deadlines of a task's series after the
+// latest instance. Ended is set when the rule has no more occurrences.
+type RecurrencePreview struct {
+	TaskID      uint        `json:"task_id"`
+	SeriesID    *uint       `json:"series_id"`
+	Recurrence  string      `json:"recurrence"`
+	Timezone    string      `json:"timezone"`
+	Occurrences []time.Time `json:"occurrences"`
+	Ended       bool        `json:"ended"`
+}
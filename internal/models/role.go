@@ -0,0 +1,68 @@
+package models
+
+// Role decides what a user may do. Roles are stored on the user and checked
+// on every request, so changes take effect immediately.
+type Role string
+
+const (
+	RoleAdmin  Role = "admin"
+	RoleMember Role = "member"
+	RoleViewer Role = "viewer"
+)
+
+// Permission names an action guarded by a route.
+type Permission string
+
+const (
+	PermTasksRead    Permission = "tasks:read"
+	PermTasksWrite   Permission = "tasks:write"
+	PermConfigManage Permission = "config:manage"
+	PermUsersManage  Permission = "users:manage"
+	// PermTasksPurge deletes trashed tasks for good. Workspace owners hold
+	// it in their own workspaces whatever their role.
+	PermTasksPurge Permission = "tasks:purge"
+	// PermFeedRead reads calendar feeds of planned work.
+	PermFeedRead Permission = "feed:read"
+)
+
+var rolePermissions = map[Role][]Permission{
+	RoleAdmin:  {PermTasksRead, PermFeedRead, PermTasksWrite, PermTasksPurge, PermConfigManage, PermUsersManage},
+	RoleMember: {PermTasksRead, PermFeedRead, PermTasksWrite},
+	RoleViewer: {PermTasksRead, PermFeedRead},
+}
+
+// Can reports whether the role grants perm.
+func (r Role) Can(perm Permission) bool {
+	for _, p := range rolePermissions[r] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// Scope returns the personal access token scope that must also be present
+// when perm is exercised with a token.
+func (p Permission) Scope() string {
+	switch p {
+	case PermTasksRead:
+		return ScopeRead
+	case PermFeedRead:
+		return ScopeFeed
+	case PermTasksWrite, PermTasksPurge:
+		return ScopeTasksWrite
+	default:
+		return ScopeAdminConfig
+	}
+}
+
+// ParseRole accepts the role names used in the API.
+func ParseRole(s string) (Role, bool) {
+	role := Role(s)
+	_, ok := rolePermissions[role]
+	return role, ok
+}
+
+type UpdateRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
@@ -0,0 +1,66 @@
+package models
+
+import "testing"
+
+var allPermissions = []Permission{
+	PermTasksRead, PermFeedRead, PermTasksWrite, PermTasksPurge, PermConfigManage, PermUsersManage,
+}
+
+func TestRoleCan(t *testing.T) {
+	tests := []struct {
+		role    Role
+		allowed []Permission
+	}{
+		{RoleAdmin, allPermissions},
+		{RoleMember, []Permission{PermTasksRead, PermFeedRead, PermTasksWrite}},
+		{RoleViewer, []Permission{PermTasksRead, PermFeedRead}},
+		{Role("guest"), nil},
+	}
+
+	for _, tt := range tests {
+		for _, perm := range allPermissions {
+			want := false
+			for _, allowed := range tt.allowed {
+				want = want || allowed == perm
+			}
+			if got := tt.role.Can(perm); got != want {
+				t.Errorf("%s.Can(%s) = %v, want %v", tt.role, perm, got, want)
+			}
+		}
+	}
+}
+
+func TestPermissionScope(t *testing.T) {
+	tests := []struct {
+		perm  Permission
+		scope string
+	}{
+		{PermTasksRead, ScopeRead},
+		{PermFeedRead, ScopeFeed},
+		{PermTasksWrite, ScopeTasksWrite},
+		{PermTasksPurge, ScopeTasksWrite},
+		{PermConfigManage, ScopeAdminConfig},
+		{PermUsersManage, ScopeAdminConfig},
+	}
+
+	for _, tt := range tests {
+		if got := tt.perm.Scope(); got != tt.scope {
+			t.Errorf("%s.Scope() = %q, want %q", tt.perm, got, tt.scope)
+		}
+	}
+}
+
+// A feed token reaches the calendar feeds and nothing else, while any other
+// token reaches them too.
+func TestFeedTokenPermissions(t *testing.T) {
+	for _, perm := range allPermissions {
+		if got, want := (TokenScopes{ScopeFeed}).Allows(perm.Scope()), perm == PermFeedRead; got != want {
+			t.Errorf("feed token allows %s = %v, want %v", perm, got, want)
+		}
+	}
+	for _, scope := range []string{ScopeRead, ScopeTasksWrite, ScopeAdminConfig} {
+		if !(TokenScopes{scope}).Allows(PermFeedRead.Scope()) {
+			t.Errorf("%s token does not allow %s", scope, PermFeedRead)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// PlannedSubTask is when an open sub-task is planned to be worked on by
+// UserID, the person responsible for it. Late is set when it is planned to
+// end after the task's deadline.
+type PlannedSubTask struct {
+	SubTaskID      uint       `json:"sub_task_id"`
+	Title          string     `json:"title"`
+	Status         TaskStatus `json:"status"`
+	Priority       Priority   `json:"priority"`
+	EstimatedHours int        `json:"estimated_hours"`
+	Dependencies   []uint     `json:"dependencies"`
+	UserID         uint       `json:"user_id"`
+	Start          time.Time  `json:"start"`
+	End            time.Time  `json:"end"`
+	Late           bool       `json:"late"`
+}
+
+// TaskPlan schedules a task's open sub-tasks, earliest first. End is when
+// the last of them is planned to finish, nil when none is open.
+type TaskPlan struct {
+	TaskID    uint             `json:"task_id"`
+	Title     string           `json:"title"`
+	Deadline  time.Time        `json:"deadline"`
+	PlannedAt time.Time        `json:"planned_at"`
+	End       *time.Time       `json:"end"`
+	Late      bool             `json:"late"`
+	SubTasks  []PlannedSubTask `json:"sub_tasks"`
+}
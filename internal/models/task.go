@@ -28,11 +28,38 @@ type Task struct {
 	Title       string         `json:"title" gorm:"not null"`
 	Description string         `json:"description" gorm:"type:text"`
 	Deadline    time.Time      `json:"deadline"`
+	// DeadlineTimezone is the IANA zone the deadline was given in; Deadline
+	// itself is stored in UTC.
+	DeadlineTimezone string `json:"deadline_timezone" gorm:"not null;default:UTC"`
 	Priority    Priority       `json:"priority"`
 	Status      TaskStatus     `json:"status" gorm:"default:0"`
 	CreatedAt   time.Time      `json:"created_at"`
 	UpdatedAt   time.Time      `json:"updated_at"`
 	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// PriorityOverride is set when a user picked the priority by hand; the
+	// scheduler then leaves it alone.
+	PriorityOverride bool `json:"priority_override"`
+
+	// PlannedAt is when the sub-tasks were last scheduled; nil means they
+	// have not been yet.
+	PlannedAt *time.Time `json:"planned_at"`
+
+	// Recurrence is an RFC 5545 RRULE such as "FREQ=WEEKLY;BYDAY=MO". Its
+	// occurrences, counted from RecurrenceStart, are the deadlines of the
+	// series' instances. SeriesID is the ID of the first instance and is
+	// shared by all of them.
+	Recurrence      string     `json:"recurrence"`
+	RecurrenceStart *time.Time `json:"recurrence_start"`
+	SeriesID        *uint      `json:"series_id" gorm:"index"`
+
+	// Ownership
+	WorkspaceID uint `json:"workspace_id" gorm:"index"`
+	CreatedBy   uint `json:"created_by" gorm:"index"`
+	OwnerID     uint `json:"owner_id" gorm:"index"`
+
+	// Assignment
+	AssigneeID *uint `json:"assignee_id" gorm:"index"`
 	
 	// LLM generated content
 	TechnicalPlan string `json:"technical_plan" gorm:"type:text"`
@@ -41,6 +68,7 @@ type Task struct {
 	
 	// Relationships
 	SubTasks []SubTask `json:"sub_tasks" gorm:"foreignKey:TaskID"`
+	Watchers []User    `json:"watchers" gorm:"many2many:task_watchers"`
 }
 
 type SubTask struct {
@@ -49,25 +77,95 @@ type SubTask struct {
 	Title       string         `json:"title" gorm:"not null"`
 	Description string         `json:"description" gorm:"type:text"`
 	EstimatedHours int         `json:"estimated_hours"`
+	// RawEstimatedHours is the LLM's estimate before EstimateFactor, the
+	// learned estimation bias, was applied to get EstimatedHours.
+	RawEstimatedHours int     `json:"raw_estimated_hours"`
+	EstimateFactor    float64 `json:"estimate_factor" gorm:"not null;default:1"`
 	Priority    Priority       `json:"priority"`
 	Status      TaskStatus     `json:"status" gorm:"default:0"`
 	Order       int            `json:"order"`
-	Dependencies []uint        `json:"dependencies" gorm:"type:json"`
+	Dependencies []uint        `json:"dependencies" gorm:"type:json;serializer:json"`
+	AssigneeID  *uint          `json:"assignee_id" gorm:"index"`
+	// PlannedStart and PlannedEnd are when the scheduler plans the work;
+	// both are nil for finished sub-tasks.
+	PlannedStart *time.Time    `json:"planned_start"`
+	PlannedEnd   *time.Time    `json:"planned_end"`
 	CreatedAt   time.Time      `json:"created_at"`
 	UpdatedAt   time.Time      `json:"updated_at"`
 	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
+// RawEstimate is the uncalibrated estimate. Sub-tasks from before
+// calibration only have EstimatedHours.
+func (s SubTask) RawEstimate() int {
+	if s.RawEstimatedHours > 0 {
+		return s.RawEstimatedHours
+	}
+	return s.EstimatedHours
+}
+
+// TaskRequest creates a task. Deadline is RFC 3339, a local date and time
+// such as "2024-05-10T17:00", or a date alone, which means the end of that
+// day. Local values are read in Timezone, falling back to the creator's
+// timezone preference.
 type TaskRequest struct {
-	Title       string    `json:"title" binding:"required"`
-	Description string    `json:"description" binding:"required"`
-	Deadline    time.Time `json:"deadline" binding:"required"`
+	Title       string `json:"title" binding:"required"`
+	Description string `json:"description" binding:"required"`
+	Deadline    string `json:"deadline" binding:"required"`
+	Timezone    string `json:"timezone"`
+	// Recurrence optionally makes the task the first of a series repeating
+	// on an RRULE, starting from its deadline.
+	Recurrence string `json:"recurrence"`
 }
 
 type TaskResponse struct {
-	Task          Task      `json:"task"`
-	UrgencyScore  float64   `json:"urgency_score"`
-	TimeRemaining string    `json:"time_remaining"`
+	Task          Task          `json:"task"`
+	UrgencyScore  float64       `json:"urgency_score"`
+	TimeRemaining string        `json:"time_remaining"`
+	Remaining     RemainingTime `json:"remaining"`
+
+	// DeadlineLocal is the deadline rendered in Timezone, the viewer's
+	// preference or else the zone the deadline was given in.
+	DeadlineLocal string `json:"deadline_local"`
+	Timezone      string `json:"timezone"`
+
+	// Feasibility is set when a task is created or its deadline moves.
+	Feasibility *Feasibility `json:"feasibility,omitempty"`
+}
+
+// Localize renders the deadline in loc, or in the zone the deadline was
+// given in when loc is nil.
+func (r *TaskResponse) Localize(loc *time.Location) {
+	if loc == nil {
+		var err error
+		if loc, err = time.LoadLocation(r.Task.DeadlineTimezone); err != nil {
+			loc = time.UTC
+		}
+	}
+	r.DeadlineLocal = r.Task.Deadline.In(loc).Format(time.RFC3339)
+	r.Timezone = loc.String()
+}
+
+// SubTaskRequest updates a sub-task; omitted fields are left unchanged.
+type SubTaskRequest struct {
+	Status         *TaskStatus `json:"status"`
+	EstimatedHours *int        `json:"estimated_hours"`
+}
+
+// AssignRequest sets or, with a null assignee_id, clears an assignee.
+type AssignRequest struct {
+	AssigneeID *uint `json:"assignee_id"`
+}
+
+// WatchRequest adds a watcher; without user_id the caller watches the task.
+type WatchRequest struct {
+	UserID uint `json:"user_id"`
+}
+
+type TrashedTask struct {
+	Task      Task       `json:"task"`
+	DeletedAt time.Time  `json:"deleted_at"`
+	PurgeAt   *time.Time `json:"purge_at,omitempty"`
 }
 
 func (p Priority) String() string {
@@ -99,3 +197,23 @@ func (s TaskStatus) String() string {
 		return "unknown"
 	}
 }
+
+// ParsePriority is the inverse of Priority.String.
+func ParsePriority(s string) (Priority, bool) {
+	for p := PriorityLow; p <= PriorityUrgent; p++ {
+		if p.String() == s {
+			return p, true
+		}
+	}
+	return PriorityLow, false
+}
+
+// ParseTaskStatus is the inverse of TaskStatus.String.
+func ParseTaskStatus(s string) (TaskStatus, bool) {
+	for st := StatusPending; st <= StatusCancelled; st++ {
+		if st.String() == s {
+			return st, true
+		}
+	}
+	return StatusPending, false
+}
@@ -0,0 +1,78 @@
+package models
+
+import "time"
+
+// TimeEntry is effort a user spent on a sub-task. Entries without an end are
+// running timers; a user has at most one, which the database enforces with
+// the idx_time_entries_running partial index.
+type TimeEntry struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	TaskID    uint       `json:"task_id" gorm:"index;not null"`
+	SubTaskID uint       `json:"sub_task_id" gorm:"index;not null"`
+	UserID    uint       `json:"user_id" gorm:"index;not null"`
+	StartedAt time.Time  `json:"started_at" gorm:"not null"`
+	EndedAt   *time.Time `json:"ended_at"`
+	Note      string     `json:"note"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// Running reports whether the entry is a timer that has not been stopped.
+func (e TimeEntry) Running() bool {
+	return e.EndedAt == nil
+}
+
+// Duration is the time the entry covers, counting a running timer up to now.
+func (e TimeEntry) Duration(now time.Time) time.Duration {
+	if e.EndedAt != nil {
+		return e.EndedAt.Sub(e.StartedAt)
+	}
+	return now.Sub(e.StartedAt)
+}
+
+// TimerRequest starts a timer, optionally with a note.
+type TimerRequest struct {
+	Note string `json:"note"`
+}
+
+// TimeEntryRequest records time spent without a timer.
+type TimeEntryRequest struct {
+	StartedAt time.Time `json:"started_at" binding:"required"`
+	EndedAt   time.Time `json:"ended_at" binding:"required"`
+	Note      string    `json:"note"`
+}
+
+// SubTaskTime compares the time tracked on a sub-task with its estimate.
+// VarianceHours is positive when more time was spent than estimated.
+type SubTaskTime struct {
+	SubTaskID      uint       `json:"sub_task_id"`
+	Title          string     `json:"title"`
+	Status         TaskStatus `json:"status"`
+	EstimatedHours int        `json:"estimated_hours"`
+	ActualHours    float64    `json:"actual_hours"`
+	VarianceHours  float64    `json:"variance_hours"`
+	Entries        int        `json:"entries"`
+	Running        bool       `json:"running"`
+}
+
+// TaskTime totals the time tracked on a task's sub-tasks.
+type TaskTime struct {
+	TaskID         uint          `json:"task_id"`
+	Title          string        `json:"title"`
+	Status         TaskStatus    `json:"status"`
+	EstimatedHours int           `json:"estimated_hours"`
+	ActualHours    float64       `json:"actual_hours"`
+	VarianceHours  float64       `json:"variance_hours"`
+	SubTasks       []SubTaskTime `json:"sub_tasks"`
+}
+
+// TimeReport compares actual with estimated hours across a workspace,
+// optionally for one user's entries only.
+type TimeReport struct {
+	WorkspaceID    uint       `json:"workspace_id"`
+	UserID         *uint      `json:"user_id,omitempty"`
+	EstimatedHours int        `json:"estimated_hours"`
+	ActualHours    float64    `json:"actual_hours"`
+	VarianceHours  float64    `json:"variance_hours"`
+	Tasks          []TaskTime `json:"tasks"`
+}
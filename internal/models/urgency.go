@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// UrgencyPolicyRequest picks a workspace's urgency policy by name. An empty
+// policy follows urgency.default_policy.
+type UrgencyPolicyRequest struct {
+	Policy string `json:"policy"`
+}
+
+// WorkspaceUrgencyPolicy reports the policy a workspace chose, the policy in
+// effect and the policies it can choose from.
+type WorkspaceUrgencyPolicy struct {
+	Policy    string   `json:"policy"`
+	Effective string   `json:"effective"`
+	Available []string `json:"available"`
+}
+
+// UrgencyPreview scores a workspace's tasks under several policies so they
+// can be compared before switching.
+type UrgencyPreview struct {
+	Current  string               `json:"current"`
+	Policies []string             `json:"policies"`
+	Tasks    []UrgencyPreviewTask `json:"tasks"`
+}
+
+type UrgencyPreviewTask struct {
+	TaskID   uint                           `json:"task_id"`
+	Title    string                         `json:"title"`
+	Status   TaskStatus                     `json:"status"`
+	Deadline time.Time                      `json:"deadline"`
+	Priority Priority                       `json:"priority"`
+	Scores   map[string]UrgencyPreviewScore `json:"scores"`
+}
+
+// UrgencyPreviewScore is a task's score, derived priority and rank among the
+// workspace's tasks under one policy. Rank 1 is the most urgent.
+type UrgencyPreviewScore struct {
+	UrgencyScore float64  `json:"urgency_score"`
+	Priority     Priority `json:"priority"`
+	Rank         int      `json:"rank"`
+}
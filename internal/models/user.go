@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// User is an account. Timezone is the preferred IANA zone for showing
+// deadlines; without one they are shown in the zone they were given in.
+type User struct {
+	ID           uint           `json:"id" gorm:"primaryKey"`
+	Username     string         `json:"username" gorm:"uniqueIndex;not null"`
+	DisplayName  string         `json:"display_name"`
+	PasswordHash string         `json:"-" gorm:"not null"`
+	Role         Role           `json:"role" gorm:"not null;default:member"`
+	Timezone     *string        `json:"timezone"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// PreferredTimezone returns the user's timezone preference, or "" when they
+// have none.
+func (u User) PreferredTimezone() string {
+	if u.Timezone == nil {
+		return ""
+	}
+	return *u.Timezone
+}
+
+type RegisterRequest struct {
+	Username    string `json:"username" binding:"required"`
+	Password    string `json:"password" binding:"required"`
+	DisplayName string `json:"display_name"`
+	Timezone    string `json:"timezone"`
+}
+
+// PreferencesRequest updates the caller's preferences; omitted fields are
+// left unchanged and an empty timezone clears the preference.
+type PreferencesRequest struct {
+	Timezone *string `json:"timezone"`
+}
+
+type LoginRequest struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type TokenResponse struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	TokenType    string    `json:"token_type"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	User         User      `json:"user"`
+}
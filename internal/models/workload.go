@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// WorkloadItem is one open sub-task counted towards a user's workload.
+type WorkloadItem struct {
+	SubTaskID      uint      `json:"sub_task_id"`
+	TaskID         uint      `json:"task_id"`
+	Title          string    `json:"title"`
+	TaskTitle      string    `json:"task_title"`
+	EstimatedHours int       `json:"estimated_hours"`
+	Deadline       time.Time `json:"deadline"`
+	Overdue        bool      `json:"overdue"`
+}
+
+// WeekLoad sums the estimated hours of sub-tasks whose task is due in the
+// week starting at WeekStart (a Monday). Overdue work counts towards the
+// first week.
+type WeekLoad struct {
+	WeekStart     time.Time      `json:"week_start"`
+	PlannedHours  int            `json:"planned_hours"`
+	CapacityHours int            `json:"capacity_hours"`
+	Utilization   float64        `json:"utilization"`
+	Overallocated bool           `json:"overallocated"`
+	Items         []WorkloadItem `json:"items"`
+}
+
+type WorkloadResponse struct {
+	UserID        uint       `json:"user_id"`
+	Username      string     `json:"username"`
+	WorkspaceID   uint       `json:"workspace_id"`
+	CapacityHours int        `json:"capacity_hours"`
+	Weeks         []WeekLoad `json:"weeks"`
+	Overallocated bool       `json:"overallocated"`
+}
@@ -0,0 +1,133 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// WorkspaceRole is a user's role inside one workspace. It is separate from
+// the server-wide Role: owners manage members, invitations and LLM settings
+// of their workspace.
+type WorkspaceRole string
+
+const (
+	WorkspaceOwner  WorkspaceRole = "owner"
+	WorkspaceMember WorkspaceRole = "member"
+)
+
+func ParseWorkspaceRole(s string) (WorkspaceRole, bool) {
+	switch WorkspaceRole(s) {
+	case WorkspaceOwner, WorkspaceMember:
+		return WorkspaceRole(s), true
+	}
+	return "", false
+}
+
+// Workspace isolates tasks between groups sharing one server. The LLM
+// fields override the global openai settings when set; an empty value
+// inherits the global one. UrgencyPolicy names the urgency policy used for
+// the workspace's tasks; empty uses the default policy. CalendarID picks the
+// calendar whose working time is counted; nil counts wall-clock time.
+type Workspace struct {
+	ID            uint           `json:"id" gorm:"primaryKey"`
+	Name          string         `json:"name" gorm:"not null"`
+	CreatedBy     uint           `json:"created_by"`
+	LLMModel      string         `json:"-"`
+	LLMBaseURL    string         `json:"-"`
+	LLMAPIKey     string         `json:"-"`
+	UseLLM        *bool          `json:"-"`
+	UrgencyPolicy string         `json:"urgency_policy"`
+	CalendarID    *uint          `json:"calendar_id"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+type WorkspaceMembership struct {
+	ID          uint          `json:"id" gorm:"primaryKey"`
+	WorkspaceID uint          `json:"workspace_id" gorm:"uniqueIndex:idx_workspace_user;not null"`
+	UserID      uint          `json:"user_id" gorm:"uniqueIndex:idx_workspace_user;not null"`
+	Role        WorkspaceRole `json:"role" gorm:"not null"`
+	User        User          `json:"user" gorm:"foreignKey:UserID"`
+	// CalendarID is the member's own working calendar; nil uses the
+	// workspace's.
+	CalendarID *uint     `json:"calendar_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// WorkspaceInvitation is addressed to an existing user, who sees it under
+// their pending invitations until they accept or decline it.
+type WorkspaceInvitation struct {
+	ID          uint          `json:"id" gorm:"primaryKey"`
+	WorkspaceID uint          `json:"workspace_id" gorm:"index;not null"`
+	Workspace   Workspace     `json:"workspace" gorm:"foreignKey:WorkspaceID"`
+	UserID      uint          `json:"user_id" gorm:"index;not null"`
+	Role        WorkspaceRole `json:"role" gorm:"not null"`
+	InvitedBy   uint          `json:"invited_by"`
+	CreatedAt   time.Time     `json:"created_at"`
+}
+
+// Team groups members of one workspace.
+type Team struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	WorkspaceID uint      `json:"workspace_id" gorm:"index;not null"`
+	Name        string    `json:"name" gorm:"not null"`
+	Members     []User    `json:"members" gorm:"many2many:team_members"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// PersonalWorkspace is the workspace every user gets when their account is
+// created.
+func PersonalWorkspace(user User) Workspace {
+	return Workspace{
+		Name:      user.Username + "'s workspace",
+		CreatedBy: user.ID,
+	}
+}
+
+// WorkspaceSummary is a workspace as seen by one of its members.
+type WorkspaceSummary struct {
+	Workspace
+	Role WorkspaceRole `json:"role"`
+}
+
+type WorkspaceRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+type InvitationRequest struct {
+	Username string `json:"username" binding:"required"`
+	Role     string `json:"role"`
+}
+
+type MemberRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+type TeamRequest struct {
+	Name    string `json:"name" binding:"required"`
+	UserIDs []uint `json:"user_ids"`
+}
+
+// WorkspaceLLMSettings is the API shape of a workspace's LLM overrides. The
+// API key is write-only and returned masked.
+type WorkspaceLLMSettings struct {
+	Model     string `json:"model"`
+	BaseURL   string `json:"base_url"`
+	APIKey    string `json:"api_key"`
+	APIKeySet bool   `json:"api_key_set"`
+	UseLLM    *bool  `json:"use_llm"`
+}
+
+// WorkspaceLLMPatch updates overrides; an empty string clears one so the
+// global setting applies again.
+type WorkspaceLLMPatch struct {
+	Model   *string `json:"model"`
+	BaseURL *string `json:"base_url"`
+	APIKey  *string `json:"api_key"`
+	UseLLM  *bool   `json:"use_llm"`
+	// InheritUseLLM clears the use_llm override.
+	InheritUseLLM bool `json:"inherit_use_llm"`
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"task-manager/internal/database"
+	"task-manager/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrUserNotInWorkspace = errors.New("user is not a member of this workspace")
+
+// AssignTask sets or clears the assignee of a task. Assignees must belong to
+// the task's workspace.
+func (s *TaskService) AssignTask(id uint, assigneeID *uint, workspaceID uint) (*models.TaskResponse, error) {
+	db := database.GetDB()
+
+	if err := s.requireWorkspaceMember(db, workspaceID, assigneeID); err != nil {
+		return nil, err
+	}
+
+	result := db.Model(&models.Task{}).Scopes(inWorkspace(workspaceID)).Where("id = ?", id).Update("assignee_id", assigneeID)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to assign task: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, fmt.Errorf("failed to assign task: %w", gorm.ErrRecordNotFound)
+	}
+
+	if _, _, err := replanFrom(db, id, time.Now()); err != nil {
+		return nil, err
+	}
+
+	return s.GetTaskByID(id, workspaceID)
+}
+
+// AssignSubTask sets or clears the assignee of a sub-task. Unassigned
+// sub-tasks count towards the task assignee's workload.
+func (s *TaskService) AssignSubTask(taskID, subTaskID uint, assigneeID *uint, workspaceID uint) (*models.TaskResponse, error) {
+	db := database.GetDB()
+
+	if err := s.requireWorkspaceMember(db, workspaceID, assigneeID); err != nil {
+		return nil, err
+	}
+
+	var task models.Task
+	if err := db.Scopes(inWorkspace(workspaceID)).Select("id").First(&task, taskID).Error; err != nil {
+		return nil, fmt.Errorf("failed to find task: %w", err)
+	}
+
+	result := db.Model(&models.SubTask{}).Where("id = ? AND task_id = ?", subTaskID, taskID).Update("assignee_id", assigneeID)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to assign sub-task: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, fmt.Errorf("failed to assign sub-task: %w", gorm.ErrRecordNotFound)
+	}
+
+	if _, _, err := replanFrom(db, taskID, time.Now()); err != nil {
+		return nil, err
+	}
+
+	return s.GetTaskByID(taskID, workspaceID)
+}
+
+func (s *TaskService) AddWatcher(id, userID, workspaceID uint) (*models.TaskResponse, error) {
+	db := database.GetDB()
+
+	if err := s.requireWorkspaceMember(db, workspaceID, &userID); err != nil {
+		return nil, err
+	}
+
+	var task models.Task
+	if err := db.Scopes(inWorkspace(workspaceID)).First(&task, id).Error; err != nil {
+		return nil, fmt.Errorf("failed to find task: %w", err)
+	}
+
+	if err := db.Model(&task).Association("Watchers").Append(&models.User{ID: userID}); err != nil {
+		return nil, fmt.Errorf("failed to add watcher: %w", err)
+	}
+
+	return s.GetTaskByID(id, workspaceID)
+}
+
+func (s *TaskService) RemoveWatcher(id, userID, workspaceID uint) (*models.TaskResponse, error) {
+	db := database.GetDB()
+
+	var task models.Task
+	if err := db.Scopes(inWorkspace(workspaceID)).First(&task, id).Error; err != nil {
+		return nil, fmt.Errorf("failed to find task: %w", err)
+	}
+
+	if err := db.Model(&task).Association("Watchers").Delete(&models.User{ID: userID}); err != nil {
+		return nil, fmt.Errorf("failed to remove watcher: %w", err)
+	}
+
+	return s.GetTaskByID(id, workspaceID)
+}
+
+func (s *TaskService) requireWorkspaceMember(db *gorm.DB, workspaceID uint, userID *uint) error {
+	if userID == nil {
+		return nil
+	}
+
+	if _, err := NewWorkspaceService().membership(db, workspaceID, *userID); err != nil {
+		if errors.Is(err, ErrNotWorkspaceMember) {
+			return ErrUserNotInWorkspace
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,326 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"task-manager/internal/config"
+	"task-manager/internal/database"
+	"task-manager/internal/models"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const (
+	tokenTypeAccess  = "access"
+	tokenTypeRefresh = "refresh"
+
+	minPasswordLength = 8
+)
+
+var (
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	ErrInvalidToken       = errors.New("invalid or expired token")
+	ErrRegistrationClosed = errors.New("registration is disabled")
+	ErrUsernameTaken      = errors.New("username is already taken")
+	ErrInvalidRole        = errors.New("role must be admin, member or viewer")
+	ErrLastAdmin          = errors.New("cannot remove the last admin")
+	ErrPasswordTooShort   = fmt.Errorf("password must be at least %d characters", minPasswordLength)
+)
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     []byte
+)
+
+func dummyPasswordHash() []byte {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = bcrypt.GenerateFromPassword([]byte("not-a-real-password"), bcrypt.DefaultCost)
+	})
+	return dummyHash
+}
+
+// Claims are carried by both access and refresh tokens. Type tells them
+// apart so a refresh token cannot be used to call the API.
+type Claims struct {
+	Username string `json:"username"`
+	Type     string `json:"typ"`
+	jwt.RegisteredClaims
+}
+
+// UserID returns the authenticated user's ID from the subject claim.
+func (c *Claims) UserID() uint {
+	id, _ := strconv.ParseUint(c.Subject, 10, 32)
+	return uint(id)
+}
+
+type AuthService struct{}
+
+func NewAuthService() *AuthService {
+	return &AuthService{}
+}
+
+// Register creates a user. The first account can always be created and is
+// made an admin; afterwards auth.allow_registration must be enabled and new
+// accounts get auth.default_role.
+func (s *AuthService) Register(req models.RegisterRequest) (*models.User, error) {
+	db := database.GetDB()
+
+	username := strings.TrimSpace(req.Username)
+	if username == "" {
+		return nil, ErrInvalidCredentials
+	}
+	if len(req.Password) < minPasswordLength {
+		return nil, ErrPasswordTooShort
+	}
+
+	cfg := config.GetConfig().Auth
+
+	var count int64
+	if err := db.Model(&models.User{}).Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("failed to count users: %w", err)
+	}
+	if count > 0 && !cfg.AllowRegistration {
+		return nil, ErrRegistrationClosed
+	}
+
+	role := models.Role(cfg.DefaultRole)
+	if count == 0 {
+		role = models.RoleAdmin
+	}
+
+	var existing int64
+	if err := db.Unscoped().Model(&models.User{}).Where("username = ?", username).Count(&existing).Error; err != nil {
+		return nil, fmt.Errorf("failed to check username: %w", err)
+	}
+	if existing > 0 {
+		return nil, ErrUsernameTaken
+	}
+
+	loc, err := loadTimezone(req.Timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	user := models.User{
+		Username:     username,
+		DisplayName:  req.DisplayName,
+		PasswordHash: string(hash),
+		Role:         role,
+		Timezone:     timezonePreference(req.Timezone, loc),
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&user).Error; err != nil {
+			return fmt.Errorf("failed to create user: %w", err)
+		}
+
+		workspace := models.PersonalWorkspace(user)
+		if err := tx.Create(&workspace).Error; err != nil {
+			return fmt.Errorf("failed to create workspace: %w", err)
+		}
+		if err := tx.Create(&models.WorkspaceMembership{
+			WorkspaceID: workspace.ID,
+			UserID:      user.ID,
+			Role:        models.WorkspaceOwner,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to add workspace owner: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func (s *AuthService) Login(req models.LoginRequest) (*models.TokenResponse, error) {
+	db := database.GetDB()
+	var user models.User
+
+	err := db.Where("username = ?", strings.TrimSpace(req.Username)).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// Hash anyway so unknown users take as long as wrong passwords
+		bcrypt.CompareHashAndPassword(dummyPasswordHash(), []byte(req.Password))
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	return s.issueTokens(user)
+}
+
+// Refresh exchanges a valid refresh token for a new token pair.
+func (s *AuthService) Refresh(refreshToken string) (*models.TokenResponse, error) {
+	claims, err := s.parseToken(refreshToken, tokenTypeRefresh)
+	if err != nil {
+		return nil, err
+	}
+
+	var user models.User
+	if err := database.GetDB().First(&user, claims.UserID()).Error; err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	return s.issueTokens(user)
+}
+
+// ParseAccessToken validates an access token and returns its claims.
+func (s *AuthService) ParseAccessToken(token string) (*Claims, error) {
+	return s.parseToken(token, tokenTypeAccess)
+}
+
+func (s *AuthService) GetUser(id uint) (*models.User, error) {
+	var user models.User
+	if err := database.GetDB().First(&user, id).Error; err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	return &user, nil
+}
+
+func (s *AuthService) ListUsers() ([]models.User, error) {
+	var users []models.User
+	if err := database.GetDB().Order("id").Find(&users).Error; err != nil {
+		return nil, fmt.Errorf("failed to get users: %w", err)
+	}
+	return users, nil
+}
+
+// UpdateUserRole assigns role to a user. The last admin cannot be demoted.
+func (s *AuthService) UpdateUserRole(id uint, roleName string) (*models.User, error) {
+	role, ok := models.ParseRole(roleName)
+	if !ok {
+		return nil, ErrInvalidRole
+	}
+
+	var user models.User
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&user, id).Error; err != nil {
+			return fmt.Errorf("failed to get user: %w", err)
+		}
+
+		if user.Role == models.RoleAdmin && role != models.RoleAdmin {
+			var admins int64
+			if err := tx.Model(&models.User{}).Where("role = ?", models.RoleAdmin).Count(&admins).Error; err != nil {
+				return fmt.Errorf("failed to count admins: %w", err)
+			}
+			if admins <= 1 {
+				return ErrLastAdmin
+			}
+		}
+
+		if err := tx.Model(&user).Update("role", role).Error; err != nil {
+			return fmt.Errorf("failed to update role: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// UpdatePreferences stores the preferences set in req for user id.
+func (s *AuthService) UpdatePreferences(id uint, req models.PreferencesRequest) (*models.User, error) {
+	user, err := s.GetUser(id)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{}
+	if req.Timezone != nil {
+		loc, err := loadTimezone(*req.Timezone)
+		if err != nil {
+			return nil, err
+		}
+		updates["timezone"] = timezonePreference(*req.Timezone, loc)
+	}
+	if len(updates) == 0 {
+		return user, nil
+	}
+
+	if err := database.GetDB().Model(user).Updates(updates).Error; err != nil {
+		return nil, fmt.Errorf("failed to update preferences: %w", err)
+	}
+
+	return s.GetUser(id)
+}
+
+// timezonePreference is the stored preference for a requested timezone
+// name: nil when none was given.
+func timezonePreference(name string, loc *time.Location) *string {
+	if strings.TrimSpace(name) == "" {
+		return nil
+	}
+	zone := loc.String()
+	return &zone
+}
+
+func (s *AuthService) issueTokens(user models.User) (*models.TokenResponse, error) {
+	cfg := config.GetConfig().Auth
+	now := time.Now()
+
+	accessExpiresAt := now.Add(cfg.AccessTokenTTL)
+	accessToken, err := s.signToken(user, tokenTypeAccess, now, accessExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := s.signToken(user, tokenTypeRefresh, now, now.Add(cfg.RefreshTokenTTL))
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TokenResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresAt:    accessExpiresAt,
+		User:         user,
+	}, nil
+}
+
+func (s *AuthService) signToken(user models.User, tokenType string, issuedAt, expiresAt time.Time) (string, error) {
+	claims := Claims{
+		Username: user.Username,
+		Type:     tokenType,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.FormatUint(uint64(user.ID), 10),
+			IssuedAt:  jwt.NewNumericDate(issuedAt),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(config.GetConfig().Auth.JWTSecret))
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token: %w", err)
+	}
+	return token, nil
+}
+
+func (s *AuthService) parseToken(raw, tokenType string) (*Claims, error) {
+	var claims Claims
+	_, err := jwt.ParseWithClaims(raw, &claims, func(t *jwt.Token) (interface{}, error) {
+		return []byte(config.GetConfig().Auth.JWTSecret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil || claims.Type != tokenType || claims.UserID() == 0 {
+		return nil, ErrInvalidToken
+	}
+	return &claims, nil
+}
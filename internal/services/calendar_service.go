@@ -0,0 +1,262 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"task-manager/internal/database"
+	"task-manager/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidTimezone     = errors.New("timezone must be an IANA name such as \"Europe/Berlin\"")
+	ErrInvalidWorkingDays  = errors.New("working_days must list weekday names such as \"monday\"")
+	ErrInvalidWorkingHours = errors.New("invalid working hours")
+	ErrInvalidHolidayDate  = errors.New("date must be formatted as YYYY-MM-DD")
+	ErrHolidayExists       = errors.New("this date is already a holiday")
+	ErrInvalidICS          = errors.New("file is not an iCalendar (.ics) file")
+)
+
+var defaultWorkingDays = []string{"monday", "tuesday", "wednesday", "thursday", "friday"}
+
+type CalendarService struct{}
+
+func NewCalendarService() *CalendarService {
+	return &CalendarService{}
+}
+
+func (s *CalendarService) ListCalendars(workspaceID uint) ([]models.Calendar, error) {
+	calendars := []models.Calendar{}
+	if err := database.GetDB().Scopes(inWorkspace(workspaceID)).Preload("Holidays", orderByDate).
+		Order("id").Find(&calendars).Error; err != nil {
+		return nil, fmt.Errorf("failed to list calendars: %w", err)
+	}
+	return calendars, nil
+}
+
+func (s *CalendarService) GetCalendar(id, workspaceID uint) (*models.Calendar, error) {
+	var calendar models.Calendar
+	if err := database.GetDB().Scopes(inWorkspace(workspaceID)).Preload("Holidays", orderByDate).
+		First(&calendar, id).Error; err != nil {
+		return nil, fmt.Errorf("failed to get calendar: %w", err)
+	}
+	return &calendar, nil
+}
+
+func (s *CalendarService) CreateCalendar(workspaceID uint, req models.CalendarRequest) (*models.Calendar, error) {
+	calendar := models.Calendar{WorkspaceID: workspaceID}
+	if err := applyCalendarRequest(&calendar, req); err != nil {
+		return nil, err
+	}
+
+	if err := database.GetDB().Create(&calendar).Error; err != nil {
+		return nil, fmt.Errorf("failed to create calendar: %w", err)
+	}
+
+	return s.GetCalendar(calendar.ID, workspaceID)
+}
+
+func (s *CalendarService) UpdateCalendar(id, workspaceID uint, req models.CalendarRequest) (*models.Calendar, error) {
+	calendar, err := s.GetCalendar(id, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyCalendarRequest(calendar, req); err != nil {
+		return nil, err
+	}
+
+	if err := database.GetDB().Model(calendar).
+		Select("Name", "Timezone", "WorkingDays", "DayStart", "DayEnd").
+		Updates(calendar).Error; err != nil {
+		return nil, fmt.Errorf("failed to update calendar: %w", err)
+	}
+
+	return s.GetCalendar(id, workspaceID)
+}
+
+// DeleteCalendar removes a calendar and its holidays. A workspace using it
+// goes back to wall-clock time and members using it to the workspace's
+// calendar.
+func (s *CalendarService) DeleteCalendar(id, workspaceID uint) error {
+	db := database.GetDB()
+
+	if _, err := s.GetCalendar(id, workspaceID); err != nil {
+		return err
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Workspace{}).Where("calendar_id = ?", id).Update("calendar_id", nil).Error; err != nil {
+			return fmt.Errorf("failed to detach calendar: %w", err)
+		}
+		if err := tx.Model(&models.WorkspaceMembership{}).Where("calendar_id = ?", id).Update("calendar_id", nil).Error; err != nil {
+			return fmt.Errorf("failed to detach calendar from members: %w", err)
+		}
+		if err := tx.Where("calendar_id = ?", id).Delete(&models.Holiday{}).Error; err != nil {
+			return fmt.Errorf("failed to delete holidays: %w", err)
+		}
+		if err := tx.Delete(&models.Calendar{}, id).Error; err != nil {
+			return fmt.Errorf("failed to delete calendar: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return replanWorkspace(db, workspaceID)
+}
+
+func (s *CalendarService) AddHoliday(calendarID, workspaceID uint, req models.HolidayRequest) (*models.Calendar, error) {
+	db := database.GetDB()
+
+	if _, err := s.GetCalendar(calendarID, workspaceID); err != nil {
+		return nil, err
+	}
+	if _, err := time.Parse(dateLayout, req.Date); err != nil {
+		return nil, ErrInvalidHolidayDate
+	}
+
+	var existing int64
+	if err := db.Model(&models.Holiday{}).Where("calendar_id = ? AND date = ?", calendarID, req.Date).
+		Count(&existing).Error; err != nil {
+		return nil, fmt.Errorf("failed to check holidays: %w", err)
+	}
+	if existing > 0 {
+		return nil, ErrHolidayExists
+	}
+
+	holiday := models.Holiday{CalendarID: calendarID, Date: req.Date, Name: strings.TrimSpace(req.Name)}
+	if err := db.Create(&holiday).Error; err != nil {
+		return nil, fmt.Errorf("failed to add holiday: %w", err)
+	}
+
+	return s.GetCalendar(calendarID, workspaceID)
+}
+
+func (s *CalendarService) RemoveHoliday(calendarID, holidayID, workspaceID uint) (*models.Calendar, error) {
+	if _, err := s.GetCalendar(calendarID, workspaceID); err != nil {
+		return nil, err
+	}
+
+	result := database.GetDB().Where("id = ? AND calendar_id = ?", holidayID, calendarID).Delete(&models.Holiday{})
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to remove holiday: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, fmt.Errorf("failed to remove holiday: %w", gorm.ErrRecordNotFound)
+	}
+
+	return s.GetCalendar(calendarID, workspaceID)
+}
+
+// ImportHolidays adds every day covered by the events of an ICS file as a
+// holiday. Days that already are holidays are skipped, as are recurring
+// events.
+func (s *CalendarService) ImportHolidays(calendarID, workspaceID uint, data []byte) (*models.HolidayImportResult, error) {
+	db := database.GetDB()
+
+	calendar, err := s.GetCalendar(calendarID, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := time.LoadLocation(calendar.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load timezone %q: %w", calendar.Timezone, err)
+	}
+
+	events, ok := parseICSEvents(data)
+	if !ok {
+		return nil, ErrInvalidICS
+	}
+
+	known := make(map[string]bool, len(calendar.Holidays))
+	for _, holiday := range calendar.Holidays {
+		known[holiday.Date] = true
+	}
+
+	result := &models.HolidayImportResult{}
+	var holidays []models.Holiday
+	for _, event := range events {
+		dates, ok := event.dates(loc)
+		if !ok || event.recurring {
+			result.Skipped++
+			continue
+		}
+		for _, date := range dates {
+			if known[date] {
+				result.Skipped++
+				continue
+			}
+			known[date] = true
+			holidays = append(holidays, models.Holiday{CalendarID: calendarID, Date: date, Name: event.summary})
+		}
+	}
+
+	if len(holidays) > 0 {
+		if err := db.Create(&holidays).Error; err != nil {
+			return nil, fmt.Errorf("failed to import holidays: %w", err)
+		}
+	}
+	result.Imported = len(holidays)
+
+	return result, nil
+}
+
+// applyCalendarRequest validates req and copies it into calendar, filling in
+// the defaults for empty fields.
+func applyCalendarRequest(calendar *models.Calendar, req models.CalendarRequest) error {
+	calendar.Name = strings.TrimSpace(req.Name)
+
+	calendar.Timezone = strings.TrimSpace(req.Timezone)
+	if calendar.Timezone == "" {
+		calendar.Timezone = "UTC"
+	}
+	if _, err := loadTimezone(calendar.Timezone); err != nil {
+		return err
+	}
+
+	days := req.WorkingDays
+	if len(days) == 0 {
+		days = defaultWorkingDays
+	}
+	seen := make(map[time.Weekday]bool, len(days))
+	calendar.WorkingDays = nil
+	for _, name := range days {
+		day, ok := parseWeekday(strings.ToLower(strings.TrimSpace(name)))
+		if !ok {
+			return fmt.Errorf("%w, got %q", ErrInvalidWorkingDays, name)
+		}
+		if !seen[day] {
+			seen[day] = true
+			calendar.WorkingDays = append(calendar.WorkingDays, weekdayName(day))
+		}
+	}
+
+	calendar.DayStart, calendar.DayEnd = req.DayStart, req.DayEnd
+	if calendar.DayStart == "" {
+		calendar.DayStart = "09:00"
+	}
+	if calendar.DayEnd == "" {
+		calendar.DayEnd = "17:00"
+	}
+	start, err := parseClock(calendar.DayStart)
+	if err != nil {
+		return err
+	}
+	end, err := parseClock(calendar.DayEnd)
+	if err != nil {
+		return err
+	}
+	if end <= start {
+		return fmt.Errorf("%w: day_end must be after day_start", ErrInvalidWorkingHours)
+	}
+
+	return nil
+}
+
+func orderByDate(db *gorm.DB) *gorm.DB {
+	return db.Order("date")
+}
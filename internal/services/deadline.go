@@ -0,0 +1,161 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"task-manager/internal/database"
+	"task-manager/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrInvalidDeadline = errors.New("deadline must be RFC 3339, a local date and time such as \"2024-05-10T17:00\", or a date such as \"2024-05-10\"")
+
+// localDeadlineLayouts are the deadline formats without a UTC offset; they
+// are read in the deadline's timezone.
+var localDeadlineLayouts = []string{
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+}
+
+// UpdateDeadline moves a task's deadline. The change is kept in the task
+// history and, unless the priority was set by hand, the priority follows the
+// new deadline. The response includes a fresh feasibility check.
+func (s *TaskService) UpdateDeadline(id uint, req models.DeadlineRequest, userID, workspaceID uint) (*models.TaskResponse, error) {
+	db := database.GetDB()
+
+	var task models.Task
+	if err := db.Scopes(inWorkspace(workspaceID)).First(&task, id).Error; err != nil {
+		return nil, fmt.Errorf("failed to find task: %w", err)
+	}
+
+	zone := req.Timezone
+	if zone == "" {
+		user, err := NewAuthService().GetUser(userID)
+		if err != nil {
+			return nil, err
+		}
+		zone = user.PreferredTimezone()
+	}
+	if zone == "" {
+		zone = task.DeadlineTimezone
+	}
+	loc, err := loadTimezone(zone)
+	if err != nil {
+		return nil, err
+	}
+	deadline, err := parseDeadline(req.Deadline, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	policies := newUrgencyPolicies()
+	policy, err := policies.forWorkspace(workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Task{}).Where("id = ?", id).Updates(map[string]interface{}{
+			"deadline":          deadline,
+			"deadline_timezone": loc.String(),
+		}).Error; err != nil {
+			return fmt.Errorf("failed to update deadline: %w", err)
+		}
+
+		if !task.Deadline.Equal(deadline) {
+			if err := tx.Create(&models.TaskHistory{
+				TaskID:   id,
+				Field:    "deadline",
+				OldValue: task.Deadline.UTC().Format(time.RFC3339),
+				NewValue: deadline.Format(time.RFC3339),
+				Source:   models.HistorySourceUser,
+				UserID:   &userID,
+			}).Error; err != nil {
+				return fmt.Errorf("failed to record deadline change: %w", err)
+			}
+		}
+
+		if task.PriorityOverride {
+			return nil
+		}
+		return s.changePriority(tx, &task, policy.Priority(deadline, time.Now()), false, models.HistorySourceUser, &userID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Tasks between the old and the new deadline change places with this
+	// one, so everything from the earlier of the two is replanned
+	from := deadline
+	if task.Deadline.Before(from) {
+		from = task.Deadline
+	}
+	if err := replanAfter(db, workspaceID, from, 0, time.Now()); err != nil {
+		return nil, err
+	}
+
+	if err := db.Preload("SubTasks").Preload("Watchers").First(&task, id).Error; err != nil {
+		return nil, fmt.Errorf("failed to load task: %w", err)
+	}
+	calendar, err := policies.calendar(workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	response := s.taskResponse(task, policy, calendar, now)
+	if response.Feasibility, err = s.feasibility(db, task, calendar, now); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// parseDeadline reads a deadline given as RFC 3339, as a local date and time
+// in loc, or as a date alone, which means the last second of that day in loc.
+// The result is in UTC.
+func parseDeadline(value string, loc *time.Location) (time.Time, error) {
+	value = strings.TrimSpace(value)
+
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return t.UTC(), nil
+	}
+	for _, layout := range localDeadlineLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	if t, err := time.ParseInLocation(dateLayout, value, loc); err == nil {
+		y, m, d := t.Date()
+		return time.Date(y, m, d, 23, 59, 59, 0, loc).UTC(), nil
+	}
+
+	return time.Time{}, ErrInvalidDeadline
+}
+
+// loadTimezone returns the location called name, or UTC when name is empty.
+func loadTimezone(name string) (*time.Location, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil || name == "Local" {
+		return nil, ErrInvalidTimezone
+	}
+	return loc, nil
+}
+
+// deadlineLocation returns the zone task deadlines are rendered in when the
+// viewer has no preference: the zone the deadline was given in.
+func deadlineLocation(name string) *time.Location {
+	loc, err := loadTimezone(name)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
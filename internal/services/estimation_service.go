@@ -0,0 +1,283 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"task-manager/internal/config"
+	"task-manager/internal/database"
+	"task-manager/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type EstimationService struct{}
+
+func NewEstimationService() *EstimationService {
+	return &EstimationService{}
+}
+
+// estimationSample is a finished sub-task with tracked time, credited to
+// the user responsible for it. startedAt and stoppedAt are when time was
+// first and last tracked on it.
+type estimationSample struct {
+	userID     uint
+	estimated  float64
+	actual     float64
+	startedAt  time.Time
+	stoppedAt  time.Time
+	finishedAt time.Time
+}
+
+// GetInsights reports the estimation bias of workspaceID, its members and
+// its teams.
+func (s *EstimationService) GetInsights(workspaceID uint) (*models.EstimationInsights, error) {
+	db := database.GetDB()
+	cfg := config.GetConfig().Estimation
+
+	samples, err := s.samples(db, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	var workspace models.Workspace
+	if err := db.Select("id", "name").First(&workspace, workspaceID).Error; err != nil {
+		return nil, fmt.Errorf("failed to get workspace: %w", err)
+	}
+
+	var memberships []models.WorkspaceMembership
+	if err := db.Preload("User").Where("workspace_id = ?", workspaceID).Order("id").Find(&memberships).Error; err != nil {
+		return nil, fmt.Errorf("failed to get members: %w", err)
+	}
+
+	var teams []models.Team
+	if err := db.Preload("Members").Where("workspace_id = ?", workspaceID).Order("id").Find(&teams).Error; err != nil {
+		return nil, fmt.Errorf("failed to get teams: %w", err)
+	}
+
+	insights := &models.EstimationInsights{
+		WorkspaceID: workspaceID,
+		Calibrate:   cfg.Calibrate,
+		MinSamples:  cfg.MinSamples,
+		Workspace:   estimationBias(workspace.Name, samples, cfg),
+		Users:       make([]models.EstimationBias, 0, len(memberships)),
+		Teams:       make([]models.EstimationBias, 0, len(teams)),
+	}
+	for _, membership := range memberships {
+		userID := membership.UserID
+		bias := estimationBias(membership.User.Username, samplesBy(samples, userID), cfg)
+		bias.UserID = &userID
+		insights.Users = append(insights.Users, bias)
+	}
+	for _, team := range teams {
+		teamID := team.ID
+		bias := estimationBias(team.Name, samplesBy(samples, memberIDs(team)...), cfg)
+		bias.TeamID = &teamID
+		insights.Teams = append(insights.Teams, bias)
+	}
+
+	return insights, nil
+}
+
+// FactorFor returns the factor new estimates made for userID in workspaceID
+// are scaled by: the user's own bias, else that of the first of their teams
+// with a calibrated bias, else the workspace's. It is 1 when calibration is
+// off or no bias is calibrated yet.
+func (s *EstimationService) FactorFor(workspaceID, userID uint) (float64, error) {
+	db := database.GetDB()
+	cfg := config.GetConfig().Estimation
+
+	if !cfg.Calibrate {
+		return 1, nil
+	}
+
+	samples, err := s.samples(db, workspaceID)
+	if err != nil {
+		return 1, err
+	}
+
+	if bias := estimationBias("", samplesBy(samples, userID), cfg); bias.Calibrated {
+		return bias.Factor, nil
+	}
+
+	var teams []models.Team
+	if err := db.Preload("Members").
+		Joins("JOIN team_members ON team_members.team_id = teams.id").
+		Where("teams.workspace_id = ? AND team_members.user_id = ?", workspaceID, userID).
+		Order("teams.id").Find(&teams).Error; err != nil {
+		return 1, fmt.Errorf("failed to get teams: %w", err)
+	}
+	for _, team := range teams {
+		if bias := estimationBias("", samplesBy(samples, memberIDs(team)...), cfg); bias.Calibrated {
+			return bias.Factor, nil
+		}
+	}
+
+	if bias := estimationBias("", samples, cfg); bias.Calibrated {
+		return bias.Factor, nil
+	}
+	return 1, nil
+}
+
+// samples collects the finished sub-tasks of workspaceID that have both an
+// estimate and finished time entries, most recently finished first. A
+// sub-task is finished when it or its task is completed, unless it was
+// cancelled. Each is credited to its assignee, else its task's assignee,
+// else whoever tracked the most time on it.
+func (s *EstimationService) samples(db *gorm.DB, workspaceID uint) ([]estimationSample, error) {
+	var tasks []models.Task
+	if err := db.Scopes(inWorkspace(workspaceID)).Preload("SubTasks").Find(&tasks).Error; err != nil {
+		return nil, fmt.Errorf("failed to get tasks: %w", err)
+	}
+
+	taskByID := make(map[uint]models.Task, len(tasks))
+	subTasks := make(map[uint]models.SubTask)
+	finishedAt := make(map[uint]time.Time)
+	var ids []uint
+	for _, task := range tasks {
+		taskByID[task.ID] = task
+		for _, subTask := range task.SubTasks {
+			if subTask.RawEstimate() <= 0 || subTask.Status == models.StatusCancelled {
+				continue
+			}
+			switch {
+			case subTask.Status == models.StatusCompleted:
+				finishedAt[subTask.ID] = subTask.UpdatedAt
+			case task.Status == models.StatusCompleted:
+				finishedAt[subTask.ID] = task.UpdatedAt
+			default:
+				continue
+			}
+			subTasks[subTask.ID] = subTask
+			ids = append(ids, subTask.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	var entries []models.TimeEntry
+	if err := db.Where("sub_task_id IN ? AND ended_at IS NOT NULL", ids).Find(&entries).Error; err != nil {
+		return nil, fmt.Errorf("failed to get time entries: %w", err)
+	}
+
+	tracked := make(map[uint]map[uint]time.Duration)
+	startedAt := make(map[uint]time.Time)
+	stoppedAt := make(map[uint]time.Time)
+	for _, entry := range entries {
+		if tracked[entry.SubTaskID] == nil {
+			tracked[entry.SubTaskID] = make(map[uint]time.Duration)
+		}
+		tracked[entry.SubTaskID][entry.UserID] += entry.Duration(*entry.EndedAt)
+		if started, ok := startedAt[entry.SubTaskID]; !ok || entry.StartedAt.Before(started) {
+			startedAt[entry.SubTaskID] = entry.StartedAt
+		}
+		if entry.EndedAt.After(stoppedAt[entry.SubTaskID]) {
+			stoppedAt[entry.SubTaskID] = *entry.EndedAt
+		}
+	}
+
+	var samples []estimationSample
+	for _, id := range ids {
+		byUser, ok := tracked[id]
+		if !ok {
+			continue
+		}
+		subTask := subTasks[id]
+
+		var total time.Duration
+		var topUser uint
+		for userID, duration := range byUser {
+			total += duration
+			if duration > byUser[topUser] || (duration == byUser[topUser] && userID < topUser) {
+				topUser = userID
+			}
+		}
+		if total <= 0 {
+			continue
+		}
+
+		userID := topUser
+		if subTask.AssigneeID != nil {
+			userID = *subTask.AssigneeID
+		} else if task := taskByID[subTask.TaskID]; task.AssigneeID != nil {
+			userID = *task.AssigneeID
+		}
+
+		samples = append(samples, estimationSample{
+			userID:     userID,
+			estimated:  float64(subTask.RawEstimate()),
+			actual:     total.Hours(),
+			startedAt:  startedAt[id],
+			stoppedAt:  stoppedAt[id],
+			finishedAt: finishedAt[id],
+		})
+	}
+
+	sort.SliceStable(samples, func(a, b int) bool {
+		return samples[a].finishedAt.After(samples[b].finishedAt)
+	})
+	return samples, nil
+}
+
+// estimationBias sums the latest cfg.MaxSamples samples.
+func estimationBias(name string, samples []estimationSample, cfg config.EstimationConfig) models.EstimationBias {
+	if len(samples) > cfg.MaxSamples {
+		samples = samples[:cfg.MaxSamples]
+	}
+
+	bias := models.EstimationBias{Name: name, Samples: len(samples), Factor: 1}
+	for _, sample := range samples {
+		bias.EstimatedHours += sample.estimated
+		bias.ActualHours += sample.actual
+	}
+	if bias.EstimatedHours > 0 {
+		factor := bias.ActualHours / bias.EstimatedHours
+		factor = math.Max(1/cfg.MaxFactor, math.Min(factor, cfg.MaxFactor))
+		bias.Factor = math.Round(factor*100) / 100
+	}
+	bias.EstimatedHours = roundHours(bias.EstimatedHours)
+	bias.ActualHours = roundHours(bias.ActualHours)
+	bias.BiasPercent = math.Round((bias.Factor - 1) * 100)
+	bias.Calibrated = bias.Samples >= cfg.MinSamples
+
+	return bias
+}
+
+// samplesBy keeps the samples credited to any of userIDs.
+func samplesBy(samples []estimationSample, userIDs ...uint) []estimationSample {
+	wanted := make(map[uint]bool, len(userIDs))
+	for _, id := range userIDs {
+		wanted[id] = true
+	}
+
+	var kept []estimationSample
+	for _, sample := range samples {
+		if wanted[sample.userID] {
+			kept = append(kept, sample)
+		}
+	}
+	return kept
+}
+
+func memberIDs(team models.Team) []uint {
+	ids := make([]uint, len(team.Members))
+	for i, member := range team.Members {
+		ids[i] = member.ID
+	}
+	return ids
+}
+
+// calibrateEstimate scales an estimate by factor, keeping non-zero estimates
+// at one hour or more.
+func calibrateEstimate(hours int, factor float64) int {
+	if hours <= 0 {
+		return hours
+	}
+	calibrated := int(math.Round(float64(hours) * factor))
+	if calibrated < 1 {
+		return 1
+	}
+	return calibrated
+}
@@ -0,0 +1,592 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"task-manager/internal/database"
+	"task-manager/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var csvHeader = []string{
+	"type", "id", "task_id", "title", "description", "deadline", "priority", "status",
+	"estimated_hours", "order", "dependencies", "technical_plan", "workflow", "documentation",
+	"created_at", "updated_at",
+}
+
+// csvOptionalColumns follow csvHeader in exports. Files from older versions
+// lack them, so imports do not require them.
+var csvOptionalColumns = []string{
+	"deadline_timezone", "assignee_id", "recurrence", "recurrence_start", "series_id",
+	"raw_estimated_hours", "estimate_factor",
+}
+
+// ErrInvalidImport marks import data that cannot be read, as opposed to a
+// failure to store it.
+var ErrInvalidImport = errors.New("invalid import")
+
+type ExportService struct{}
+
+func NewExportService() *ExportService {
+	return &ExportService{}
+}
+
+func (s *ExportService) Export(format models.ExportFormat, workspaceID uint) ([]byte, error) {
+	db := database.GetDB()
+	var tasks []models.Task
+
+	if err := db.Scopes(inWorkspace(workspaceID)).Preload("SubTasks", func(tx *gorm.DB) *gorm.DB {
+		return tx.Order(`"order", id`)
+	}).Order("id").Find(&tasks).Error; err != nil {
+		return nil, fmt.Errorf("failed to load tasks: %w", err)
+	}
+
+	switch format {
+	case models.ExportFormatJSON:
+		return json.MarshalIndent(models.WorkspaceExport{
+			Version:    models.ExportVersion,
+			ExportedAt: time.Now(),
+			Tasks:      tasks,
+		}, "", "  ")
+	case models.ExportFormatCSV:
+		return s.exportCSV(tasks)
+	case models.ExportFormatMarkdown:
+		return s.exportMarkdown(tasks), nil
+	default:
+		return nil, fmt.Errorf("unsupported export format: %s", format)
+	}
+}
+
+// Import loads tasks from a JSON or CSV export into workspaceID on behalf of
+// userID. Task and sub-task IDs are kept whenever they are free; conflicts
+// with tasks in the same workspace are resolved with mode, while IDs used by
+// another workspace are always imported under a new ID.
+func (s *ExportService) Import(format models.ExportFormat, data []byte, mode models.ConflictMode, userID uint, workspaceID uint) (*models.ImportResult, error) {
+	var tasks []models.Task
+	var err error
+
+	switch format {
+	case models.ExportFormatJSON:
+		var export models.WorkspaceExport
+		if err := json.Unmarshal(data, &export); err != nil {
+			return nil, fmt.Errorf("%w: failed to parse JSON: %v", ErrInvalidImport, err)
+		}
+		tasks = export.Tasks
+	case models.ExportFormatCSV:
+		tasks, err = s.parseCSV(data)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%w: unsupported format: %s", ErrInvalidImport, format)
+	}
+
+	switch mode {
+	case models.ConflictSkip, models.ConflictOverwrite, models.ConflictDuplicate:
+	default:
+		return nil, fmt.Errorf("%w: unsupported conflict mode: %s", ErrInvalidImport, mode)
+	}
+
+	result := &models.ImportResult{IDMap: make(map[uint]uint)}
+	series := make(map[uint]uint)
+	db := database.GetDB()
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		for _, task := range tasks {
+			if err := s.importTask(tx, task, mode, userID, workspaceID, result, series); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// importTask stores one task of an import. Series keys that do not belong to
+// an imported task are tracked in series, from the key in the file to the
+// key used in the workspace.
+func (s *ExportService) importTask(tx *gorm.DB, task models.Task, mode models.ConflictMode, userID uint, workspaceID uint, result *models.ImportResult, series map[uint]uint) error {
+	oldID := task.ID
+	subTasks := task.SubTasks
+	task.SubTasks = nil
+	// Watchers are not exported, and assignees only carry over when they
+	// belong to the target workspace.
+	task.Watchers = nil
+	task.AssigneeID = s.workspaceMemberOrNil(tx, workspaceID, task.AssigneeID)
+	task.WorkspaceID = workspaceID
+	task.OwnerID = userID
+	// Plans depend on the target workspace's people and calendars, so
+	// imported tasks are planned afresh when first looked at.
+	task.PlannedAt = nil
+	if task.CreatedBy == 0 {
+		task.CreatedBy = userID
+	}
+	// Series are keyed by the ID of their first instance, which is exported
+	// before the rest and may have been imported under a new ID
+	var orphanKey uint
+	if task.SeriesID != nil && *task.SeriesID != oldID {
+		if id, ok := result.IDMap[*task.SeriesID]; ok {
+			task.SeriesID = &id
+		} else if id, ok := series[*task.SeriesID]; ok {
+			task.SeriesID = &id
+		} else {
+			orphanKey = *task.SeriesID
+		}
+	}
+
+	exists, existingWorkspaceID, err := s.taskWorkspace(tx, task.ID)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case exists && existingWorkspaceID != workspaceID:
+		task.ID = 0
+		result.Created++
+	case exists && mode == models.ConflictSkip:
+		result.Skipped++
+		return nil
+	case exists && mode == models.ConflictOverwrite:
+		// Time entries point at the sub-tasks being replaced, whose IDs do
+		// not carry over
+		if err := tx.Where("task_id = ?", task.ID).Delete(&models.TimeEntry{}).Error; err != nil {
+			return fmt.Errorf("failed to replace time entries of task %d: %w", task.ID, err)
+		}
+		if err := tx.Unscoped().Where("task_id = ?", task.ID).Delete(&models.SubTask{}).Error; err != nil {
+			return fmt.Errorf("failed to replace sub-tasks of task %d: %w", task.ID, err)
+		}
+		if err := tx.Table("task_watchers").Where("task_id = ?", task.ID).Delete(nil).Error; err != nil {
+			return fmt.Errorf("failed to replace watchers of task %d: %w", task.ID, err)
+		}
+		if err := tx.Unscoped().Delete(&models.Task{}, task.ID).Error; err != nil {
+			return fmt.Errorf("failed to replace task %d: %w", task.ID, err)
+		}
+		result.Overwritten++
+	case exists && mode == models.ConflictDuplicate:
+		task.ID = 0
+		result.Duplicated++
+	default:
+		result.Created++
+	}
+
+	if err := tx.Create(&task).Error; err != nil {
+		return fmt.Errorf("failed to import task %q: %w", task.Title, err)
+	}
+	if task.ID != oldID {
+		result.IDMap[oldID] = task.ID
+	}
+
+	// The first instance of a series may have been purged before the
+	// export. Its key then stays unless this instance was renumbered, as the
+	// old key may now be another workspace's task; the series starts afresh
+	// from this instance instead.
+	renumbered := task.ID != oldID
+	switch {
+	case renumbered && (orphanKey != 0 || task.SeriesID != nil && *task.SeriesID == oldID):
+		if err := tx.Model(&task).UpdateColumn("series_id", task.ID).Error; err != nil {
+			return fmt.Errorf("failed to import task %q: %w", task.Title, err)
+		}
+		if orphanKey != 0 {
+			series[orphanKey] = task.ID
+		}
+	case orphanKey != 0:
+		series[orphanKey] = orphanKey
+	}
+
+	// Sub-task IDs are global, so one may already be taken by another task.
+	// Create them without dependencies first, then rewrite dependencies
+	// through the old-to-new ID map.
+	subTaskIDs := make(map[uint]uint, len(subTasks))
+	created := make([]models.SubTask, 0, len(subTasks))
+
+	for _, subTask := range subTasks {
+		oldSubTaskID := subTask.ID
+		subTask.TaskID = task.ID
+		subTask.Dependencies = nil
+		subTask.AssigneeID = s.workspaceMemberOrNil(tx, workspaceID, subTask.AssigneeID)
+		subTask.PlannedStart, subTask.PlannedEnd = nil, nil
+
+		taken, err := s.subTaskExists(tx, subTask.ID)
+		if err != nil {
+			return err
+		}
+		if taken {
+			subTask.ID = 0
+		}
+
+		if err := tx.Create(&subTask).Error; err != nil {
+			return fmt.Errorf("failed to import sub-task %q: %w", subTask.Title, err)
+		}
+		subTaskIDs[oldSubTaskID] = subTask.ID
+		created = append(created, subTask)
+	}
+
+	for i, subTask := range subTasks {
+		if len(subTask.Dependencies) == 0 {
+			continue
+		}
+
+		var dependencies []uint
+		for _, dependency := range subTask.Dependencies {
+			if id, ok := subTaskIDs[dependency]; ok {
+				dependencies = append(dependencies, id)
+			}
+		}
+
+		created[i].Dependencies = dependencies
+		if err := tx.Model(&created[i]).Select("Dependencies").Updates(&created[i]).Error; err != nil {
+			return fmt.Errorf("failed to import sub-task dependencies: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (s *ExportService) taskWorkspace(tx *gorm.DB, id uint) (bool, uint, error) {
+	if id == 0 {
+		return false, 0, nil
+	}
+
+	var existing models.Task
+	err := tx.Unscoped().Select("id", "workspace_id").First(&existing, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, 0, nil
+	}
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to check task %d: %w", id, err)
+	}
+	return true, existing.WorkspaceID, nil
+}
+
+func (s *ExportService) workspaceMemberOrNil(tx *gorm.DB, workspaceID uint, userID *uint) *uint {
+	if userID == nil {
+		return nil
+	}
+	if _, err := NewWorkspaceService().membership(tx, workspaceID, *userID); err != nil {
+		return nil
+	}
+	return userID
+}
+
+func (s *ExportService) subTaskExists(tx *gorm.DB, id uint) (bool, error) {
+	if id == 0 {
+		return false, nil
+	}
+
+	var existing models.SubTask
+	err := tx.Unscoped().Select("id").First(&existing, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to check sub-task %d: %w", id, err)
+	}
+	return true, nil
+}
+
+func (s *ExportService) exportCSV(tasks []models.Task) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+
+	header := append(append([]string{}, csvHeader...), csvOptionalColumns...)
+	if err := w.Write(header); err != nil {
+		return nil, fmt.Errorf("failed to write CSV: %w", err)
+	}
+
+	for _, task := range tasks {
+		record := []string{
+			"task",
+			strconv.FormatUint(uint64(task.ID), 10),
+			"",
+			task.Title,
+			task.Description,
+			task.Deadline.Format(time.RFC3339Nano),
+			task.Priority.String(),
+			task.Status.String(),
+			"", "", "",
+			task.TechnicalPlan,
+			task.Workflow,
+			task.Documentation,
+			task.CreatedAt.Format(time.RFC3339Nano),
+			task.UpdatedAt.Format(time.RFC3339Nano),
+			task.DeadlineTimezone,
+			csvUint(task.AssigneeID),
+			task.Recurrence,
+			csvTime(task.RecurrenceStart),
+			csvUint(task.SeriesID),
+			"", "",
+		}
+		if err := w.Write(record); err != nil {
+			return nil, fmt.Errorf("failed to write CSV: %w", err)
+		}
+
+		for _, subTask := range task.SubTasks {
+			dependencies := make([]string, 0, len(subTask.Dependencies))
+			for _, dependency := range subTask.Dependencies {
+				dependencies = append(dependencies, strconv.FormatUint(uint64(dependency), 10))
+			}
+
+			record := []string{
+				"subtask",
+				strconv.FormatUint(uint64(subTask.ID), 10),
+				strconv.FormatUint(uint64(subTask.TaskID), 10),
+				subTask.Title,
+				subTask.Description,
+				"",
+				subTask.Priority.String(),
+				subTask.Status.String(),
+				strconv.Itoa(subTask.EstimatedHours),
+				strconv.Itoa(subTask.Order),
+				strings.Join(dependencies, ";"),
+				"", "", "",
+				subTask.CreatedAt.Format(time.RFC3339Nano),
+				subTask.UpdatedAt.Format(time.RFC3339Nano),
+				"",
+				csvUint(subTask.AssigneeID),
+				"", "", "",
+				strconv.Itoa(subTask.RawEstimatedHours),
+				strconv.FormatFloat(subTask.EstimateFactor, 'f', -1, 64),
+			}
+			if err := w.Write(record); err != nil {
+				return nil, fmt.Errorf("failed to write CSV: %w", err)
+			}
+		}
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, fmt.Errorf("failed to write CSV: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (s *ExportService) parseCSV(data []byte) ([]models.Task, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to read CSV header: %v", ErrInvalidImport, err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[name] = i
+	}
+	for _, name := range csvHeader {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("%w: CSV is missing column %q", ErrInvalidImport, name)
+		}
+	}
+
+	var tasks []models.Task
+	taskIndex := make(map[uint]int)
+
+	for line := 2; ; line++ {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: failed to read CSV line %d: %v", ErrInvalidImport, line, err)
+		}
+
+		row := csvRow{record: record, columns: columns}
+
+		switch row.get("type") {
+		case "task":
+			task := models.Task{
+				ID:               row.uint("id"),
+				Title:            row.get("title"),
+				Description:      row.get("description"),
+				Deadline:         row.time("deadline"),
+				DeadlineTimezone: row.get("deadline_timezone"),
+				Priority:         row.priority(),
+				Status:           row.status(),
+				TechnicalPlan:    row.get("technical_plan"),
+				Workflow:         row.get("workflow"),
+				Documentation:    row.get("documentation"),
+				AssigneeID:       row.optionalUint("assignee_id"),
+				Recurrence:       row.get("recurrence"),
+				RecurrenceStart:  row.optionalTime("recurrence_start"),
+				SeriesID:         row.optionalUint("series_id"),
+				CreatedAt:        row.time("created_at"),
+				UpdatedAt:        row.time("updated_at"),
+			}
+			if task.Deadline.IsZero() {
+				return nil, fmt.Errorf("%w: CSV line %d: task has no valid deadline", ErrInvalidImport, line)
+			}
+			taskIndex[task.ID] = len(tasks)
+			tasks = append(tasks, task)
+		case "subtask":
+			taskID := row.uint("task_id")
+			i, ok := taskIndex[taskID]
+			if !ok {
+				return nil, fmt.Errorf("%w: CSV line %d: sub-task references unknown task %d", ErrInvalidImport, line, taskID)
+			}
+
+			var dependencies []uint
+			for _, dependency := range strings.Split(row.get("dependencies"), ";") {
+				if id, err := strconv.ParseUint(strings.TrimSpace(dependency), 10, 32); err == nil {
+					dependencies = append(dependencies, uint(id))
+				}
+			}
+
+			estimatedHours, _ := strconv.Atoi(row.get("estimated_hours"))
+			rawEstimatedHours, _ := strconv.Atoi(row.get("raw_estimated_hours"))
+			order, _ := strconv.Atoi(row.get("order"))
+			// Files without the column predate calibration, which is the
+			// same as a factor of 1
+			estimateFactor, err := strconv.ParseFloat(row.get("estimate_factor"), 64)
+			if err != nil || estimateFactor <= 0 {
+				estimateFactor = 1
+			}
+
+			tasks[i].SubTasks = append(tasks[i].SubTasks, models.SubTask{
+				ID:                row.uint("id"),
+				TaskID:            taskID,
+				Title:             row.get("title"),
+				Description:       row.get("description"),
+				EstimatedHours:    estimatedHours,
+				RawEstimatedHours: rawEstimatedHours,
+				EstimateFactor:    estimateFactor,
+				Priority:          row.priority(),
+				Status:            row.status(),
+				Order:             order,
+				Dependencies:      dependencies,
+				AssigneeID:        row.optionalUint("assignee_id"),
+				CreatedAt:         row.time("created_at"),
+				UpdatedAt:         row.time("updated_at"),
+			})
+		default:
+			return nil, fmt.Errorf("%w: CSV line %d: unknown row type %q", ErrInvalidImport, line, row.get("type"))
+		}
+	}
+
+	return tasks, nil
+}
+
+func (s *ExportService) exportMarkdown(tasks []models.Task) []byte {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# Task Manager Export\n\n_Exported at %s_\n", time.Now().Format(time.RFC3339))
+
+	for _, task := range tasks {
+		fmt.Fprintf(&b, "\n## #%d %s\n\n", task.ID, task.Title)
+		deadline := task.Deadline.In(deadlineLocation(task.DeadlineTimezone))
+		fmt.Fprintf(&b, "- **Deadline:** %s (%s)\n", deadline.Format("2006-01-02 15:04:05 -07:00"), deadline.Location())
+		fmt.Fprintf(&b, "- **Priority:** %s\n", task.Priority)
+		fmt.Fprintf(&b, "- **Status:** %s\n", task.Status)
+
+		if task.Description != "" {
+			fmt.Fprintf(&b, "\n%s\n", task.Description)
+		}
+
+		if len(task.SubTasks) > 0 {
+			b.WriteString("\n### Sub-tasks\n\n")
+			b.WriteString("| ID | Title | Estimated Hours | Priority | Status | Depends On |\n")
+			b.WriteString("|----|-------|-----------------|----------|--------|------------|\n")
+			for _, subTask := range task.SubTasks {
+				dependencies := make([]string, 0, len(subTask.Dependencies))
+				for _, dependency := range subTask.Dependencies {
+					dependencies = append(dependencies, fmt.Sprintf("#%d", dependency))
+				}
+				fmt.Fprintf(&b, "| %d | %s | %d | %s | %s | %s |\n",
+					subTask.ID,
+					strings.ReplaceAll(subTask.Title, "|", "\\|"),
+					subTask.EstimatedHours,
+					subTask.Priority,
+					subTask.Status,
+					strings.Join(dependencies, ", "),
+				)
+			}
+		}
+
+		for _, section := range []struct{ title, body string }{
+			{"Technical Plan", task.TechnicalPlan},
+			{"Workflow", task.Workflow},
+			{"Documentation", task.Documentation},
+		} {
+			if section.body == "" {
+				continue
+			}
+			fmt.Fprintf(&b, "\n### %s\n\n%s\n", section.title, section.body)
+		}
+	}
+
+	return []byte(b.String())
+}
+
+type csvRow struct {
+	record  []string
+	columns map[string]int
+}
+
+func (r csvRow) get(name string) string {
+	i, ok := r.columns[name]
+	if !ok || i >= len(r.record) {
+		return ""
+	}
+	return r.record[i]
+}
+
+func (r csvRow) uint(name string) uint {
+	id, _ := strconv.ParseUint(r.get(name), 10, 32)
+	return uint(id)
+}
+
+func (r csvRow) time(name string) time.Time {
+	t, _ := time.Parse(time.RFC3339Nano, r.get(name))
+	return t
+}
+
+// optionalUint is nil for an empty or missing column.
+func (r csvRow) optionalUint(name string) *uint {
+	if id := r.uint(name); id != 0 {
+		return &id
+	}
+	return nil
+}
+
+// optionalTime is nil for an empty or missing column.
+func (r csvRow) optionalTime(name string) *time.Time {
+	if t := r.time(name); !t.IsZero() {
+		return &t
+	}
+	return nil
+}
+
+func csvUint(id *uint) string {
+	if id == nil {
+		return ""
+	}
+	return strconv.FormatUint(uint64(*id), 10)
+}
+
+func csvTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+func (r csvRow) priority() models.Priority {
+	p, _ := models.ParsePriority(r.get("priority"))
+	return p
+}
+
+func (r csvRow) status() models.TaskStatus {
+	st, _ := models.ParseTaskStatus(r.get("status"))
+	return st
+}
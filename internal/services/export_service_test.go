@@ -0,0 +1,145 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"task-manager/internal/models"
+	"testing"
+	"time"
+)
+
+func TestCSVRoundTrip(t *testing.T) {
+	at := func(value string) time.Time {
+		t.Helper()
+		parsed, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", value, err)
+		}
+		return parsed
+	}
+	id := func(n uint) *uint { return &n }
+	start := at("2024-12-02T09:00:00Z")
+
+	tasks := []models.Task{
+		{
+			ID:               7,
+			Title:            "Release 1.2",
+			Description:      "Ship it, then \"announce\" it\non two lines",
+			Deadline:         at("2024-12-20T16:00:00.5Z"),
+			DeadlineTimezone: "Europe/Berlin",
+			Priority:         models.PriorityHigh,
+			Status:           models.StatusInProgress,
+			TechnicalPlan:    "plan",
+			Workflow:         "workflow",
+			Documentation:    "docs",
+			AssigneeID:       id(3),
+			CreatedAt:        at("2024-11-01T08:00:00Z"),
+			UpdatedAt:        at("2024-11-02T08:00:00Z"),
+			SubTasks: []models.SubTask{
+				{
+					ID: 70, TaskID: 7, Title: "Build", Description: "make, test",
+					EstimatedHours: 6, RawEstimatedHours: 4, EstimateFactor: 1.5,
+					Priority: models.PriorityMedium, Status: models.StatusCompleted, Order: 1,
+					AssigneeID: id(4),
+					CreatedAt:  at("2024-11-01T08:00:00Z"), UpdatedAt: at("2024-11-03T08:00:00Z"),
+				},
+				{
+					ID: 71, TaskID: 7, Title: "Publish",
+					EstimatedHours: 2, RawEstimatedHours: 2, EstimateFactor: 1,
+					Priority: models.PriorityUrgent, Status: models.StatusPending, Order: 2,
+					Dependencies: []uint{70, 69},
+					CreatedAt:    at("2024-11-01T08:00:00Z"), UpdatedAt: at("2024-11-01T08:00:00Z"),
+				},
+			},
+		},
+		{
+			ID:              9,
+			Title:           "Weekly report",
+			Deadline:        at("2024-12-06T16:00:00Z"),
+			Priority:        models.PriorityLow,
+			Status:          models.StatusPending,
+			Recurrence:      "FREQ=WEEKLY;BYDAY=FR",
+			RecurrenceStart: &start,
+			SeriesID:        id(5),
+			CreatedAt:       at("2024-11-29T08:00:00Z"),
+			UpdatedAt:       at("2024-11-29T08:00:00Z"),
+		},
+	}
+
+	s := NewExportService()
+	data, err := s.exportCSV(tasks)
+	if err != nil {
+		t.Fatalf("exportCSV: %v", err)
+	}
+	parsed, err := s.parseCSV(data)
+	if err != nil {
+		t.Fatalf("parseCSV: %v", err)
+	}
+
+	if !reflect.DeepEqual(parsed, tasks) {
+		t.Errorf("parseCSV(exportCSV(tasks)) =\n%+v\nwant\n%+v", parsed, tasks)
+	}
+}
+
+func TestParseCSV(t *testing.T) {
+	header := strings.Join(csvHeader, ",")
+
+	tests := []struct {
+		name string
+		data string
+		want []models.Task
+		err  bool
+	}{
+		{
+			// Files from before calibration have no estimate columns
+			name: "without the optional columns",
+			data: header + "\n" +
+				"task,1,,Old,,2024-12-20T16:00:00Z,high,pending,,,,,,,,\n" +
+				"subtask,2,1,Step,,,low,pending,5,1,,,,,,\n",
+			want: []models.Task{{
+				ID: 1, Title: "Old", Deadline: time.Date(2024, 12, 20, 16, 0, 0, 0, time.UTC),
+				Priority: models.PriorityHigh,
+				SubTasks: []models.SubTask{{ID: 2, TaskID: 1, Title: "Step", EstimatedHours: 5, EstimateFactor: 1, Order: 1}},
+			}},
+		},
+		{
+			name: "missing column",
+			data: "type,id,title\ntask,1,Old\n",
+			err:  true,
+		},
+		{
+			name: "unknown row type",
+			data: header + "\nnote,1,,Old,,2024-12-20T16:00:00Z,high,pending,,,,,,,,\n",
+			err:  true,
+		},
+		{
+			name: "sub-task before its task",
+			data: header + "\nsubtask,2,1,Step,,,low,pending,5,1,,,,,,\n",
+			err:  true,
+		},
+		{
+			name: "task without a deadline",
+			data: header + "\ntask,1,,Old,,soon,high,pending,,,,,,,,\n",
+			err:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tasks, err := NewExportService().parseCSV([]byte(tt.data))
+			if tt.err {
+				if !errors.Is(err, ErrInvalidImport) {
+					t.Errorf("parseCSV() error = %v, want ErrInvalidImport", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseCSV: %v", err)
+			}
+			if !reflect.DeepEqual(tasks, tt.want) {
+				t.Errorf("parseCSV() =\n%+v\nwant\n%+v", tasks, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,284 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"task-manager/internal/config"
+	"task-manager/internal/database"
+	"task-manager/internal/llm"
+	"task-manager/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// atRiskLoad is the share of the assignees' capacity above which a plan is
+// considered at risk.
+const atRiskLoad = 0.8
+
+// CheckFeasibility checks whether a task's open sub-tasks fit before its
+// deadline. With suggest, plans that do not fit get scope-cut suggestions
+// from the LLM.
+func (s *TaskService) CheckFeasibility(id, workspaceID uint, suggest bool) (*models.Feasibility, error) {
+	db := database.GetDB()
+
+	var task models.Task
+	if err := db.Scopes(inWorkspace(workspaceID)).Preload("SubTasks").First(&task, id).Error; err != nil {
+		return nil, fmt.Errorf("failed to find task: %w", err)
+	}
+
+	calendar, err := newUrgencyPolicies().calendar(workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	feasibility, err := s.feasibility(db, task, calendar, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if !suggest || feasibility.Verdict == models.FeasibilityFeasible {
+		return feasibility, nil
+	}
+
+	override, err := NewWorkspaceService().LLMOverride(workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []llm.WorkItem
+	for _, subTask := range task.SubTasks {
+		if isOpen(subTask.Status) {
+			items = append(items, llm.WorkItem{Title: subTask.Title, EstimatedHours: subTask.EstimatedHours})
+		}
+	}
+
+	deadline := task.Deadline.In(deadlineLocation(task.DeadlineTimezone))
+	scopeCuts, err := s.llmClient.WithOverride(override).
+		SuggestScopeCuts(task.Title, task.Description, deadline, items, feasibility.CapacityHours, feasibility.Reasons)
+	if err != nil {
+		return nil, fmt.Errorf("unable to suggest scope cuts - %w", err)
+	}
+	feasibility.ScopeCuts = scopeCuts
+
+	return feasibility, nil
+}
+
+// feasibility compares the open sub-tasks of task with the time left before
+// its deadline, counted on calendar, and with the capacity of the people
+// doing them. Sub-tasks count for their assignee, else the task's assignee,
+// else its owner. Each person has workload.weekly_capacity_hours a week, less
+// their open work on other tasks due no later than this one.
+func (s *TaskService) feasibility(db *gorm.DB, task models.Task, calendar *WorkingCalendar, now time.Time) (*models.Feasibility, error) {
+	hoursLeft := calendar.Between(now, task.Deadline).Hours()
+
+	feasibility := &models.Feasibility{
+		Verdict:      models.FeasibilityFeasible,
+		Reasons:      []string{},
+		CheckedAt:    now,
+		Deadline:     task.Deadline,
+		HoursLeft:    roundHours(math.Max(hoursLeft, 0)),
+		WorkingTime:  calendar != nil,
+		CriticalPath: []uint{},
+		Assignees:    []models.AssigneeCapacity{},
+	}
+	flag := func(verdict models.FeasibilityVerdict, format string, args ...interface{}) {
+		if verdict == models.FeasibilityInfeasible || feasibility.Verdict == models.FeasibilityFeasible {
+			feasibility.Verdict = verdict
+		}
+		feasibility.Reasons = append(feasibility.Reasons, fmt.Sprintf(format, args...))
+	}
+
+	var open []models.SubTask
+	for _, subTask := range task.SubTasks {
+		if isOpen(subTask.Status) {
+			open = append(open, subTask)
+			feasibility.RemainingHours += subTask.EstimatedHours
+		}
+	}
+	feasibility.CriticalPath, feasibility.CriticalPathHours = criticalPath(open)
+
+	if !isOpen(task.Status) {
+		return feasibility, nil
+	}
+	if hoursLeft <= 0 {
+		flag(models.FeasibilityInfeasible, "The deadline has passed")
+		return feasibility, nil
+	}
+	if len(open) == 0 {
+		return feasibility, nil
+	}
+
+	unit := "hours"
+	if calendar != nil {
+		unit = "working hours"
+	}
+	weekly := float64(config.GetConfig().Workload.WeeklyCapacityHours)
+	personCapacity := hoursLeft * math.Min(1, weekly/calendar.HoursPerWeek())
+
+	taskHours := make(map[uint]int)
+	for _, subTask := range open {
+		taskHours[responsibleFor(task, subTask)] += subTask.EstimatedHours
+	}
+	userIDs := make([]uint, 0, len(taskHours))
+	for userID := range taskHours {
+		userIDs = append(userIDs, userID)
+	}
+	sort.Slice(userIDs, func(a, b int) bool { return userIDs[a] < userIDs[b] })
+
+	otherHours, err := committedHours(db, task, userIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var users []models.User
+	if err := db.Unscoped().Where("id IN ?", userIDs).Find(&users).Error; err != nil {
+		return nil, fmt.Errorf("failed to get assignees: %w", err)
+	}
+	usernames := make(map[uint]string, len(users))
+	for _, user := range users {
+		usernames[user.ID] = user.Username
+	}
+
+	for _, userID := range userIDs {
+		capacity := math.Max(0, personCapacity-float64(otherHours[userID]))
+		feasibility.CapacityHours += capacity
+		feasibility.Assignees = append(feasibility.Assignees, models.AssigneeCapacity{
+			UserID:        userID,
+			Username:      usernames[userID],
+			TaskHours:     taskHours[userID],
+			OtherHours:    otherHours[userID],
+			CapacityHours: roundHours(capacity),
+		})
+		if float64(taskHours[userID]) > capacity {
+			reason := fmt.Sprintf("%s needs %d hours for this task but has %.0f hours of capacity left before the deadline",
+				usernames[userID], taskHours[userID], capacity)
+			if otherHours[userID] > 0 {
+				reason += fmt.Sprintf(", with %d hours committed to tasks due no later", otherHours[userID])
+			}
+			flag(models.FeasibilityAtRisk, "%s", reason)
+		}
+	}
+	feasibility.CapacityHours = roundHours(feasibility.CapacityHours)
+
+	critical := float64(feasibility.CriticalPathHours)
+	switch {
+	case critical > hoursLeft:
+		flag(models.FeasibilityInfeasible, "The critical path takes %d hours, more than the %.0f %s left before the deadline",
+			feasibility.CriticalPathHours, hoursLeft, unit)
+	case critical > personCapacity:
+		flag(models.FeasibilityAtRisk, "The critical path takes %d hours, more than the %.0f hours one person can work before the deadline",
+			feasibility.CriticalPathHours, personCapacity)
+	}
+
+	remaining := float64(feasibility.RemainingHours)
+	switch {
+	case remaining > feasibility.CapacityHours:
+		flag(models.FeasibilityInfeasible, "%d hours of work remain but the assignees have %.0f hours of capacity before the deadline",
+			feasibility.RemainingHours, feasibility.CapacityHours)
+	case remaining > atRiskLoad*feasibility.CapacityHours:
+		flag(models.FeasibilityAtRisk, "%d hours of work remain, over %.0f%% of the assignees' %.0f hours of capacity before the deadline",
+			feasibility.RemainingHours, atRiskLoad*100, feasibility.CapacityHours)
+	}
+
+	return feasibility, nil
+}
+
+// responsibleFor is the user a sub-task's work counts for.
+func responsibleFor(task models.Task, subTask models.SubTask) uint {
+	if subTask.AssigneeID != nil {
+		return *subTask.AssigneeID
+	}
+	if task.AssigneeID != nil {
+		return *task.AssigneeID
+	}
+	return task.OwnerID
+}
+
+// committedHours sums the open work of userIDs on the other open tasks of
+// task's workspace that are due no later than task.
+func committedHours(db *gorm.DB, task models.Task, userIDs []uint) (map[uint]int, error) {
+	var others []models.Task
+	if err := db.Scopes(inWorkspace(task.WorkspaceID)).
+		Preload("SubTasks", "status IN ?", openStatuses).
+		Where("id <> ? AND status IN ? AND deadline <= ?", task.ID, openStatuses, task.Deadline).
+		Find(&others).Error; err != nil {
+		return nil, fmt.Errorf("failed to get other tasks: %w", err)
+	}
+
+	wanted := make(map[uint]bool, len(userIDs))
+	for _, userID := range userIDs {
+		wanted[userID] = true
+	}
+
+	hours := make(map[uint]int)
+	for _, other := range others {
+		for _, subTask := range other.SubTasks {
+			if userID := responsibleFor(other, subTask); wanted[userID] {
+				hours[userID] += subTask.EstimatedHours
+			}
+		}
+	}
+	return hours, nil
+}
+
+// criticalPath returns the chain of dependent sub-tasks with the most
+// estimated hours, first to last, and its total. Dependencies outside
+// subTasks are ignored, and cycles are broken where they are found.
+func criticalPath(subTasks []models.SubTask) ([]uint, int) {
+	byID := make(map[uint]models.SubTask, len(subTasks))
+	for _, subTask := range subTasks {
+		byID[subTask.ID] = subTask
+	}
+
+	longest := make(map[uint]int, len(subTasks))
+	previous := make(map[uint]uint, len(subTasks))
+	visiting := make(map[uint]bool, len(subTasks))
+
+	var walk func(id uint) int
+	walk = func(id uint) int {
+		if hours, ok := longest[id]; ok {
+			return hours
+		}
+		if visiting[id] {
+			return 0
+		}
+		visiting[id] = true
+
+		best := 0
+		for _, dependency := range byID[id].Dependencies {
+			if _, ok := byID[dependency]; !ok {
+				continue
+			}
+			if hours := walk(dependency); hours > best {
+				best = hours
+				previous[id] = dependency
+			}
+		}
+
+		visiting[id] = false
+		longest[id] = best + byID[id].EstimatedHours
+		return longest[id]
+	}
+
+	var last uint
+	total := 0
+	for _, subTask := range subTasks {
+		if hours := walk(subTask.ID); hours > total {
+			total = hours
+			last = subTask.ID
+		}
+	}
+	if total == 0 {
+		return []uint{}, 0
+	}
+
+	path := []uint{last}
+	for id, ok := previous[last]; ok && len(path) <= len(subTasks); id, ok = previous[id] {
+		path = append(path, id)
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path, total
+}
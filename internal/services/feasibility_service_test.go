@@ -0,0 +1,84 @@
+package services
+
+import (
+	"reflect"
+	"task-manager/internal/models"
+	"testing"
+)
+
+func TestCriticalPath(t *testing.T) {
+	subTask := func(id uint, hours int, dependencies ...uint) models.SubTask {
+		return models.SubTask{ID: id, EstimatedHours: hours, Dependencies: dependencies}
+	}
+
+	tests := []struct {
+		name     string
+		subTasks []models.SubTask
+		path     []uint
+		total    int
+	}{
+		{
+			name:  "no sub-tasks",
+			path:  []uint{},
+			total: 0,
+		},
+		{
+			name:     "independent sub-tasks",
+			subTasks: []models.SubTask{subTask(1, 3), subTask(2, 5), subTask(3, 4)},
+			path:     []uint{2},
+			total:    5,
+		},
+		{
+			name:     "chain",
+			subTasks: []models.SubTask{subTask(1, 2), subTask(2, 3, 1), subTask(3, 4, 2)},
+			path:     []uint{1, 2, 3},
+			total:    9,
+		},
+		{
+			name:     "diamond takes the longer branch",
+			subTasks: []models.SubTask{subTask(1, 1), subTask(2, 2, 1), subTask(3, 6, 1), subTask(4, 1, 2, 3)},
+			path:     []uint{1, 3, 4},
+			total:    8,
+		},
+		{
+			name:     "dependency outside the task",
+			subTasks: []models.SubTask{subTask(1, 2, 99), subTask(2, 3, 1)},
+			path:     []uint{1, 2},
+			total:    5,
+		},
+		{
+			name:     "depends on itself",
+			subTasks: []models.SubTask{subTask(1, 4, 1)},
+			path:     []uint{1},
+			total:    4,
+		},
+		{
+			name:     "cycle",
+			subTasks: []models.SubTask{subTask(1, 2, 3), subTask(2, 3, 1), subTask(3, 4, 2)},
+			path:     []uint{2, 3, 1},
+			total:    9,
+		},
+		{
+			// The walk reaches 1 first, so 2's dependency on 1 is dropped
+			name:     "cycle with a tail",
+			subTasks: []models.SubTask{subTask(1, 1, 2), subTask(2, 1, 1), subTask(3, 5, 2), subTask(4, 2, 3)},
+			path:     []uint{2, 3, 4},
+			total:    8,
+		},
+		{
+			name:     "two sub-tasks waiting on each other",
+			subTasks: []models.SubTask{subTask(1, 5, 2), subTask(2, 5, 1)},
+			path:     []uint{2, 1},
+			total:    10,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, total := criticalPath(tt.subTasks)
+			if !reflect.DeepEqual(path, tt.path) || total != tt.total {
+				t.Errorf("criticalPath() = %v, %d, want %v, %d", path, total, tt.path, tt.total)
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"task-manager/internal/config"
+	"task-manager/internal/database"
+	"task-manager/internal/models"
+	"time"
+)
+
+// maxFlowFactor bounds how many hours of working time a sub-task may take
+// per hour of tracked effort, so work that was picked up again weeks later
+// does not dominate forecasts.
+const maxFlowFactor = 10
+
+// The default spread, used while a workspace has too few finished sub-tasks:
+// work takes from 75% to twice its estimate, most likely as estimated.
+const (
+	defaultErrorLow  = 0.75
+	defaultErrorMode = 1
+	defaultErrorHigh = 2
+)
+
+// forecastDraw is one finished sub-task's outcome: its effort over its
+// estimate, and the working time from when time was first tracked on it to
+// when it was last tracked, over its effort.
+type forecastDraw struct {
+	effort float64
+	flow   float64
+}
+
+// Forecast simulates the open sub-tasks of a task forecast.trials times and
+// reports when the work is likely to be done. Each run gives every sub-task
+// the estimate error and cycle time of a finished sub-task drawn at random
+// from the workspace's history, less time already tracked on it, and plans
+// the result like the scheduler does.
+func (s *ScheduleService) Forecast(id, workspaceID uint) (*models.Forecast, error) {
+	db := database.GetDB()
+	cfg := config.GetConfig().Forecast
+
+	var task models.Task
+	if err := db.Scopes(inWorkspace(workspaceID)).Preload("SubTasks").First(&task, id).Error; err != nil {
+		return nil, fmt.Errorf("failed to find task: %w", err)
+	}
+
+	calendars, err := loadAssigneeCalendars(db, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	samples, err := NewEstimationService().samples(db, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if len(samples) > cfg.MaxSamples {
+		samples = samples[:cfg.MaxSamples]
+	}
+
+	now := time.Now()
+	tracked, err := trackedHours(task, now)
+	if err != nil {
+		return nil, err
+	}
+
+	forecast := &models.Forecast{
+		TaskID:     task.ID,
+		Title:      task.Title,
+		Deadline:   task.Deadline,
+		ForecastAt: now,
+		Basis:      models.ForecastDefault,
+		Samples:    len(samples),
+		Trials:     cfg.Trials,
+	}
+
+	var draws []forecastDraw
+	if len(samples) >= cfg.MinSamples {
+		forecast.Basis = models.ForecastHistory
+		for _, sample := range samples {
+			draws = append(draws, forecastDraw{
+				effort: sample.actual / sample.estimated,
+				flow:   flowFactor(sample, calendars.forUser(sample.userID)),
+			})
+		}
+	}
+
+	if !isOpen(task.Status) {
+		task.SubTasks = nil
+	}
+	for _, subTask := range task.SubTasks {
+		if isOpen(subTask.Status) {
+			forecast.RemainingHours += subTask.EstimatedHours
+		}
+	}
+
+	busy, err := busyUntil(db, task)
+	if err != nil {
+		return nil, err
+	}
+
+	forecast.EstimatedEnd = planEnd(schedule(task, calendars, busy, now, estimatedDuration), now)
+
+	ends := simulate(task, calendars, busy, draws, tracked, cfg.Trials, now)
+	onTime := 0
+	for _, end := range ends {
+		if !end.After(task.Deadline) {
+			onTime++
+		}
+	}
+
+	forecast.P50 = percentile(ends, 0.50)
+	forecast.P85 = percentile(ends, 0.85)
+	forecast.P95 = percentile(ends, 0.95)
+	forecast.OnTimeProbability = math.Round(float64(onTime)/float64(len(ends))*1000) / 1000
+
+	return forecast, nil
+}
+
+// simulate plans the open sub-tasks of task trials times, after the work in
+// busy, and returns when each run ends, earliest first. Each sub-task takes
+// its uncalibrated estimate times the effort of a random draw, less the
+// hours already tracked on it, stretched by the draw's flow. Without draws
+// the effort follows the default spread around the calibrated estimate.
+func simulate(task models.Task, calendars *assigneeCalendars, busy map[uint]time.Time, draws []forecastDraw, tracked map[uint]float64, trials int, now time.Time) []time.Time {
+	// Seeding with the task ID gives the same forecast for the same data
+	random := rand.New(rand.NewSource(int64(task.ID)))
+	durations := make(map[uint]time.Duration, len(task.SubTasks))
+	ends := make([]time.Time, trials)
+
+	for trial := range ends {
+		for _, subTask := range task.SubTasks {
+			var hours float64
+			if len(draws) > 0 {
+				draw := draws[random.Intn(len(draws))]
+				hours = math.Max(uncalibratedEstimate(subTask)*draw.effort-tracked[subTask.ID], 0) * draw.flow
+			} else {
+				effort := triangular(random, defaultErrorLow, defaultErrorMode, defaultErrorHigh)
+				hours = math.Max(float64(subTask.EstimatedHours)*effort-tracked[subTask.ID], 0)
+			}
+			durations[subTask.ID] = time.Duration(hours * float64(time.Hour)).Round(time.Minute)
+		}
+
+		ends[trial] = planEnd(schedule(task, calendars, busy, now, func(subTask models.SubTask) time.Duration {
+			return durations[subTask.ID]
+		}), now)
+	}
+
+	sort.Slice(ends, func(a, b int) bool { return ends[a].Before(ends[b]) })
+	return ends
+}
+
+// uncalibratedEstimate is a sub-task's estimate without the calibration
+// factor, which history-based draws apply in their own way. That is the
+// LLM's raw estimate unless the estimate was edited since, in which case the
+// factor is taken back out of the edited one.
+func uncalibratedEstimate(subTask models.SubTask) float64 {
+	raw := subTask.RawEstimate()
+	factor := subTask.EstimateFactor
+	if subTask.RawEstimatedHours <= 0 || factor <= 0 || calibrateEstimate(raw, factor) == subTask.EstimatedHours {
+		return float64(raw)
+	}
+	return float64(subTask.EstimatedHours) / factor
+}
+
+// trackedHours sums the time tracked so far on each sub-task of task,
+// counting running timers up to now.
+func trackedHours(task models.Task, now time.Time) (map[uint]float64, error) {
+	var entries []models.TimeEntry
+	if err := database.GetDB().Where("task_id = ?", task.ID).Find(&entries).Error; err != nil {
+		return nil, fmt.Errorf("failed to get time entries: %w", err)
+	}
+
+	hours := make(map[uint]float64)
+	for _, entry := range entries {
+		hours[entry.SubTaskID] += entry.Duration(now).Hours()
+	}
+	return hours, nil
+}
+
+// flowFactor is the working time over which a sample's time was tracked,
+// over the hours tracked, between 1 and maxFlowFactor.
+func flowFactor(sample estimationSample, calendar *WorkingCalendar) float64 {
+	elapsed := calendar.Between(sample.startedAt, sample.stoppedAt).Hours()
+	return math.Max(1, math.Min(elapsed/sample.actual, maxFlowFactor))
+}
+
+// planEnd is when the last planned sub-task ends, or now when none is left.
+func planEnd(planned []models.PlannedSubTask, now time.Time) time.Time {
+	end := now
+	for _, subTask := range planned {
+		if subTask.End.After(end) {
+			end = subTask.End
+		}
+	}
+	return end.UTC()
+}
+
+// percentile returns the value p of the way through sorted.
+func percentile(sorted []time.Time, p float64) time.Time {
+	i := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
+
+// triangular draws from the triangular distribution between low and high
+// peaking at mode.
+func triangular(random *rand.Rand, low, mode, high float64) float64 {
+	u := random.Float64()
+	if split := (mode - low) / (high - low); u < split {
+		return low + math.Sqrt(u*(high-low)*(mode-low))
+	}
+	return high - math.Sqrt((1-u)*(high-low)*(high-mode))
+}
@@ -0,0 +1,158 @@
+package services
+
+import (
+	"task-manager/internal/models"
+	"testing"
+	"time"
+)
+
+// forecastTask has 18 hours of open work for one person: sub-tasks 1 and 2
+// in a chain, and 3, which waits for nothing. Done work is not simulated.
+func forecastTask() models.Task {
+	return models.Task{
+		ID: 42,
+		SubTasks: []models.SubTask{
+			{ID: 1, EstimatedHours: 8, Status: models.StatusPending, Order: 1},
+			{ID: 2, EstimatedHours: 4, Status: models.StatusPending, Order: 2, Dependencies: []uint{1}},
+			{ID: 3, EstimatedHours: 6, Status: models.StatusPending, Order: 3},
+			{ID: 4, EstimatedHours: 40, Status: models.StatusCompleted, Order: 4},
+		},
+	}
+}
+
+func TestSimulate(t *testing.T) {
+	now := time.Date(2030, 1, 7, 9, 0, 0, 0, time.UTC)
+	wallClock := &assigneeCalendars{byUser: map[uint]*WorkingCalendar{}}
+	after := func(hours float64) time.Time {
+		return now.Add(time.Duration(hours * float64(time.Hour)))
+	}
+
+	tests := []struct {
+		name          string
+		draws         []forecastDraw
+		tracked       map[uint]float64
+		busy          map[uint]time.Time
+		p50, p85, p95 time.Time
+	}{
+		{
+			name:  "default spread",
+			draws: nil,
+			p50:   time.Date(2030, 1, 8, 7, 16, 0, 0, time.UTC),
+			p85:   time.Date(2030, 1, 8, 10, 41, 0, 0, time.UTC),
+			p95:   time.Date(2030, 1, 8, 12, 51, 0, 0, time.UTC),
+		},
+		{
+			name:  "history as estimated",
+			draws: []forecastDraw{{effort: 1, flow: 1}},
+			p50:   after(18),
+			p85:   after(18),
+			p95:   after(18),
+		},
+		{
+			name:    "tracked time is taken off",
+			draws:   []forecastDraw{{effort: 1, flow: 1}},
+			tracked: map[uint]float64{1: 8, 3: 2},
+			p50:     after(8),
+			p85:     after(8),
+			p95:     after(8),
+		},
+		{
+			name:  "after work on earlier tasks",
+			draws: []forecastDraw{{effort: 1, flow: 1}},
+			busy:  map[uint]time.Time{0: after(10)},
+			p50:   after(28),
+			p85:   after(28),
+			p95:   after(28),
+		},
+		{
+			name:  "slow flow stretches the work",
+			draws: []forecastDraw{{effort: 1, flow: 2}},
+			p50:   after(36),
+			p85:   after(36),
+			p95:   after(36),
+		},
+		{
+			name:  "mixed history",
+			draws: []forecastDraw{{effort: 1, flow: 1}, {effort: 2, flow: 1}},
+			p50:   after(28),
+			p85:   after(32),
+			p95:   after(36),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ends := simulate(forecastTask(), wallClock, tt.busy, tt.draws, tt.tracked, 2000, now)
+
+			got := [3]time.Time{percentile(ends, 0.50), percentile(ends, 0.85), percentile(ends, 0.95)}
+			want := [3]time.Time{tt.p50, tt.p85, tt.p95}
+			if got != want {
+				t.Errorf("P50/P85/P95 = %v, want %v", got, want)
+			}
+
+			// The task ID seeds the draws, so a rerun gives the same result
+			again := simulate(forecastTask(), wallClock, tt.busy, tt.draws, tt.tracked, 2000, now)
+			for i := range ends {
+				if !ends[i].Equal(again[i]) {
+					t.Fatalf("run %d ended at %v, then at %v", i, ends[i], again[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSimulateDefaultSpreadBounds(t *testing.T) {
+	now := time.Date(2030, 1, 7, 9, 0, 0, 0, time.UTC)
+	ends := simulate(forecastTask(), &assigneeCalendars{}, nil, nil, nil, 2000, now)
+
+	// Work takes from 75% to twice its estimate
+	earliest, latest := now.Add(13*time.Hour+30*time.Minute), now.Add(36*time.Hour)
+	if ends[0].Before(earliest) || ends[len(ends)-1].After(latest) {
+		t.Errorf("runs end from %v to %v, want within %v and %v", ends[0], ends[len(ends)-1], earliest, latest)
+	}
+}
+
+func TestUncalibratedEstimate(t *testing.T) {
+	tests := []struct {
+		name    string
+		subTask models.SubTask
+		want    float64
+	}{
+		{"calibrated", models.SubTask{EstimatedHours: 6, RawEstimatedHours: 4, EstimateFactor: 1.5}, 4},
+		{"before calibration", models.SubTask{EstimatedHours: 7}, 7},
+		{"edited", models.SubTask{EstimatedHours: 20, RawEstimatedHours: 5, EstimateFactor: 1}, 20},
+		{"edited and calibrated", models.SubTask{EstimatedHours: 10, RawEstimatedHours: 4, EstimateFactor: 2}, 5},
+		{"without a factor", models.SubTask{EstimatedHours: 10, RawEstimatedHours: 4}, 4},
+	}
+
+	for _, tt := range tests {
+		if got := uncalibratedEstimate(tt.subTask); got != tt.want {
+			t.Errorf("%s: uncalibratedEstimate() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	base := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	sorted := make([]time.Time, 20)
+	for i := range sorted {
+		sorted[i] = base.Add(time.Duration(i+1) * time.Hour)
+	}
+
+	tests := []struct {
+		p    float64
+		want int
+	}{
+		{0, 1},
+		{0.5, 10},
+		{0.85, 17},
+		{0.95, 19},
+		{1, 20},
+	}
+
+	for _, tt := range tests {
+		if got := percentile(sorted, tt.p); !got.Equal(base.Add(time.Duration(tt.want) * time.Hour)) {
+			t.Errorf("percentile(%v) = %v, want hour %d", tt.p, got, tt.want)
+		}
+	}
+}
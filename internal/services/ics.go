@@ -0,0 +1,206 @@
+package services
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxEventDays bounds how many days one ICS event may cover, so a malformed
+// end date cannot turn into years of holidays.
+const maxEventDays = 366
+
+const icsUTCLayout = "20060102T150405Z"
+
+// icsLineLength is the longest content line iCalendar allows, in octets,
+// before it must be folded.
+const icsLineLength = 75
+
+// icsEvent is the subset of a VEVENT needed for holidays.
+type icsEvent struct {
+	summary   string
+	start     string
+	startTZID string
+	end       string
+	endTZID   string
+	recurring bool
+}
+
+// parseICSEvents reads the VEVENTs of an iCalendar file. It reports false
+// when data does not look like iCalendar at all.
+func parseICSEvents(data []byte) ([]icsEvent, bool) {
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\n ", "")
+	text = strings.ReplaceAll(text, "\n\t", "")
+
+	var events []icsEvent
+	var current *icsEvent
+	calendar := false
+
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(line, "\r")
+		colon := strings.Index(line, ":")
+		if colon < 0 {
+			continue
+		}
+		params := strings.Split(line[:colon], ";")
+		name := strings.ToUpper(params[0])
+		value := line[colon+1:]
+
+		switch {
+		case name == "BEGIN" && strings.EqualFold(value, "VCALENDAR"):
+			calendar = true
+		case name == "BEGIN" && strings.EqualFold(value, "VEVENT"):
+			current = &icsEvent{}
+		case name == "END" && strings.EqualFold(value, "VEVENT"):
+			if current != nil {
+				events = append(events, *current)
+			}
+			current = nil
+		case current == nil:
+		case name == "SUMMARY":
+			current.summary = unescapeICSText(value)
+		case name == "DTSTART":
+			current.start, current.startTZID = value, icsParam(params, "TZID")
+		case name == "DTEND":
+			current.end, current.endTZID = value, icsParam(params, "TZID")
+		case name == "RRULE" || name == "RDATE":
+			current.recurring = true
+		}
+	}
+
+	return events, calendar
+}
+
+// dates returns the days the event covers in loc. DTEND is exclusive, as in
+// all-day events; an event without one covers its start day.
+func (e icsEvent) dates(loc *time.Location) ([]string, bool) {
+	start, ok := parseICSTime(e.start, e.startTZID, loc)
+	if !ok {
+		return nil, false
+	}
+	day := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, loc)
+
+	last := day
+	if e.end != "" {
+		end, ok := parseICSTime(e.end, e.endTZID, loc)
+		if !ok {
+			return nil, false
+		}
+		end = end.Add(-time.Nanosecond)
+		last = time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, loc)
+	}
+	if last.Before(day) {
+		last = day
+	}
+
+	var dates []string
+	for d := day; !d.After(last); d = d.AddDate(0, 0, 1) {
+		if len(dates) == maxEventDays {
+			return nil, false
+		}
+		dates = append(dates, d.Format(dateLayout))
+	}
+	return dates, true
+}
+
+// parseICSTime parses a DATE or DATE-TIME value into loc. UTC times are
+// converted; floating times and times with a TZID keep their local date.
+func parseICSTime(value, tzid string, loc *time.Location) (time.Time, bool) {
+	if t, err := time.ParseInLocation("20060102", value, loc); err == nil {
+		return t, true
+	}
+	if t, err := time.Parse(icsUTCLayout, value); err == nil {
+		return t.In(loc), true
+	}
+	if tzid != "" {
+		if zone, err := time.LoadLocation(tzid); err == nil {
+			if t, err := time.ParseInLocation("20060102T150405", value, zone); err == nil {
+				return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, loc), true
+			}
+		}
+	}
+	if t, err := time.ParseInLocation("20060102T150405", value, loc); err == nil {
+		return t, true
+	}
+	return time.Time{}, false
+}
+
+func icsParam(params []string, name string) string {
+	for _, param := range params[1:] {
+		if key, value, ok := strings.Cut(param, "="); ok && strings.EqualFold(key, name) {
+			return strings.Trim(value, `"`)
+		}
+	}
+	return ""
+}
+
+func unescapeICSText(value string) string {
+	return strings.NewReplacer(`\n`, " ", `\N`, " ", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(value)
+}
+
+// icsBlock is a stretch of planned work, written as a VEVENT.
+type icsBlock struct {
+	uid         string
+	summary     string
+	description string
+	start       time.Time
+	end         time.Time
+}
+
+// writeICS renders blocks as an iCalendar file called name. Times are
+// written in UTC.
+func writeICS(name string, stamp time.Time, blocks []icsBlock) []byte {
+	var b strings.Builder
+	line := func(content string) {
+		b.WriteString(foldICSLine(content))
+		b.WriteString("\r\n")
+	}
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//task-manager//planned work//EN")
+	line("CALSCALE:GREGORIAN")
+	line("METHOD:PUBLISH")
+	line("X-WR-CALNAME:" + escapeICSText(name))
+	for _, block := range blocks {
+		line("BEGIN:VEVENT")
+		line("UID:" + block.uid)
+		line("DTSTAMP:" + stamp.UTC().Format(icsUTCLayout))
+		line("DTSTART:" + block.start.UTC().Format(icsUTCLayout))
+		line("DTEND:" + block.end.UTC().Format(icsUTCLayout))
+		line("SUMMARY:" + escapeICSText(block.summary))
+		if block.description != "" {
+			line("DESCRIPTION:" + escapeICSText(block.description))
+		}
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+
+	return []byte(b.String())
+}
+
+// foldICSLine breaks a content line into lines of at most icsLineLength
+// octets, without splitting characters.
+func foldICSLine(content string) string {
+	if len(content) <= icsLineLength {
+		return content
+	}
+
+	var b strings.Builder
+	width := 0
+	for _, r := range content {
+		size := utf8.RuneLen(r)
+		if width+size > icsLineLength {
+			b.WriteString("\r\n ")
+			width = 1
+		}
+		b.WriteRune(r)
+		width += size
+	}
+	return b.String()
+}
+
+func escapeICSText(value string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", "").Replace(value)
+}
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseICSEvents(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		calendar bool
+		want     []icsEvent
+	}{
+		{
+			name:     "not iCalendar",
+			data:     "title,date\nNew Year,2025-01-01\n",
+			calendar: false,
+		},
+		{
+			name:     "all-day event",
+			data:     "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nSUMMARY:Christmas Day\r\nDTSTART;VALUE=DATE:20241225\r\nDTEND;VALUE=DATE:20241226\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n",
+			calendar: true,
+			want:     []icsEvent{{summary: "Christmas Day", start: "20241225", end: "20241226"}},
+		},
+		{
+			name:     "folded lines",
+			data:     "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nSUMMARY:Tag der Deutschen \r\n Einheit\r\nDTSTART;VALUE=DATE:2024\r\n\t1003\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n",
+			calendar: true,
+			want:     []icsEvent{{summary: "Tag der Deutschen Einheit", start: "20241003"}},
+		},
+		{
+			name:     "bare line feeds and escapes",
+			data:     "BEGIN:VCALENDAR\nBEGIN:VEVENT\nSUMMARY:Office closed\\, all staff\\; see\\nintranet\nDTSTART:20241231T120000Z\nEND:VEVENT\nEND:VCALENDAR\n",
+			calendar: true,
+			want:     []icsEvent{{summary: "Office closed, all staff; see intranet", start: "20241231T120000Z"}},
+		},
+		{
+			name:     "time zone parameters and recurrence",
+			data:     "BEGIN:VCALENDAR\nBEGIN:VEVENT\nSUMMARY:Retreat\nDTSTART;TZID=\"America/New_York\":20241220T090000\nDTEND;TZID=America/New_York:20241220T170000\nRRULE:FREQ=YEARLY\nEND:VEVENT\nEND:VCALENDAR\n",
+			calendar: true,
+			want: []icsEvent{{
+				summary: "Retreat", start: "20241220T090000", startTZID: "America/New_York",
+				end: "20241220T170000", endTZID: "America/New_York", recurring: true,
+			}},
+		},
+		{
+			name:     "properties outside events",
+			data:     "BEGIN:VCALENDAR\nX-WR-CALNAME:Holidays\nSUMMARY:stray\nBEGIN:VEVENT\nSUMMARY:Boxing Day\nDTSTART:20241226\nEND:VEVENT\nEND:VCALENDAR\n",
+			calendar: true,
+			want:     []icsEvent{{summary: "Boxing Day", start: "20241226"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, calendar := parseICSEvents([]byte(tt.data))
+			if calendar != tt.calendar {
+				t.Errorf("calendar = %v, want %v", calendar, tt.calendar)
+			}
+			if !reflect.DeepEqual(events, tt.want) {
+				t.Errorf("events = %+v, want %+v", events, tt.want)
+			}
+		})
+	}
+}
+
+func TestICSEventDates(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatalf("LoadLocation: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		event icsEvent
+		want  []string
+		ok    bool
+	}{
+		{
+			name:  "single all-day event",
+			event: icsEvent{start: "20241225", end: "20241226"},
+			want:  []string{"2024-12-25"},
+			ok:    true,
+		},
+		{
+			name:  "multi-day all-day event",
+			event: icsEvent{start: "20241224", end: "20241227"},
+			want:  []string{"2024-12-24", "2024-12-25", "2024-12-26"},
+			ok:    true,
+		},
+		{
+			name:  "all-day event across a month",
+			event: icsEvent{start: "20241230", end: "20250102"},
+			want:  []string{"2024-12-30", "2024-12-31", "2025-01-01"},
+			ok:    true,
+		},
+		{
+			name:  "without an end",
+			event: icsEvent{start: "20241003"},
+			want:  []string{"2024-10-03"},
+			ok:    true,
+		},
+		{
+			name:  "end before start",
+			event: icsEvent{start: "20241003", end: "20241001"},
+			want:  []string{"2024-10-03"},
+			ok:    true,
+		},
+		{
+			name:  "UTC time on the next local day",
+			event: icsEvent{start: "20241224T233000Z"},
+			want:  []string{"2024-12-25"},
+			ok:    true,
+		},
+		{
+			name:  "timed event ending at midnight",
+			event: icsEvent{start: "20241224T120000", end: "20241226T000000"},
+			want:  []string{"2024-12-24", "2024-12-25"},
+			ok:    true,
+		},
+		{
+			name:  "TZID keeps its local date",
+			event: icsEvent{start: "20241224T200000", startTZID: "America/New_York"},
+			want:  []string{"2024-12-24"},
+			ok:    true,
+		},
+		{
+			name:  "unparseable start",
+			event: icsEvent{start: "tomorrow"},
+		},
+		{
+			name:  "longer than maxEventDays",
+			event: icsEvent{start: "20240101", end: "20260101"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dates, ok := tt.event.dates(loc)
+			if ok != tt.ok || !reflect.DeepEqual(dates, tt.want) {
+				t.Errorf("dates() = %v, %v, want %v, %v", dates, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestWriteICSFoldsLines(t *testing.T) {
+	start := time.Date(2024, 12, 2, 9, 0, 0, 0, time.UTC)
+	summary := strings.Repeat("Überarbeitung, ", 8)
+
+	data := writeICS("Plan", start, []icsBlock{{uid: "1@test", summary: summary, start: start, end: start.Add(time.Hour)}})
+
+	for _, line := range strings.Split(strings.TrimSuffix(string(data), "\r\n"), "\r\n") {
+		if len(line) > icsLineLength {
+			t.Errorf("line of %d octets: %q", len(line), line)
+		}
+	}
+
+	events, calendar := parseICSEvents(data)
+	if !calendar || len(events) != 1 {
+		t.Fatalf("parseICSEvents = %v, %v", events, calendar)
+	}
+	if events[0].summary != summary {
+		t.Errorf("summary = %q, want %q", events[0].summary, summary)
+	}
+}
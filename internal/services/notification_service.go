@@ -0,0 +1,75 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"task-manager/internal/config"
+	"task-manager/internal/models"
+	"time"
+)
+
+type NotificationService struct {
+	client *http.Client
+}
+
+func NewNotificationService() *NotificationService {
+	return &NotificationService{
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+// Notify posts event to the configured webhook in the background. It does
+// nothing when no webhook is set or the event is not in notifications.events.
+func (s *NotificationService) Notify(event string, data interface{}) {
+	cfg := config.GetConfig().Notifications
+	if cfg.WebhookURL == "" || !eventEnabled(cfg.Events, event) {
+		return
+	}
+
+	go func() {
+		if err := s.send(cfg.WebhookURL, models.WebhookEvent{
+			Event:      event,
+			OccurredAt: time.Now(),
+			Data:       data,
+		}); err != nil {
+			log.Printf("Failed to deliver %s notification: %v", event, err)
+		}
+	}()
+}
+
+func (s *NotificationService) send(url string, payload models.WebhookEvent) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	resp, err := s.client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to send event: %w", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// eventEnabled reports whether event is in events; an empty list enables
+// every event.
+func eventEnabled(events []string, event string) bool {
+	if len(events) == 0 {
+		return true
+	}
+	for _, e := range events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,173 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"task-manager/internal/config"
+	"task-manager/internal/database"
+	"task-manager/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrInvalidPriority = errors.New("priority must be one of low, medium, high or urgent")
+
+// openStatuses are the statuses whose priority still matters.
+var openStatuses = []models.TaskStatus{models.StatusPending, models.StatusInProgress}
+
+// RecalculatePriorities re-derives the priority of every open task without a
+// manual override from its deadline and its workspace's urgency policy.
+// Changes are recorded in the task history and escalations are sent as
+// task.escalated events. It returns the number of tasks whose priority
+// changed.
+func (s *TaskService) RecalculatePriorities() (int, error) {
+	db := database.GetDB()
+	var tasks []models.Task
+
+	if err := db.Where("status IN ? AND priority_override = ?", openStatuses, false).Find(&tasks).Error; err != nil {
+		return 0, fmt.Errorf("failed to find open tasks: %w", err)
+	}
+
+	policies := newUrgencyPolicies()
+	notifier := NewNotificationService()
+	now := time.Now()
+	changed := 0
+	for _, task := range tasks {
+		policy, err := policies.forWorkspace(task.WorkspaceID)
+		if err != nil {
+			// One broken workspace should not hold up the others
+			log.Printf("Priority recalculation failed for task %d: %v", task.ID, err)
+			continue
+		}
+		priority := policy.Priority(task.Deadline, now)
+		if priority == task.Priority {
+			continue
+		}
+
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			return s.changePriority(tx, &task, priority, false, models.HistorySourceScheduler, nil)
+		}); err != nil {
+			log.Printf("Priority recalculation failed for task %d: %v", task.ID, err)
+			continue
+		}
+		changed++
+
+		if priority > task.Priority {
+			notifier.Notify(models.EventTaskEscalated, models.TaskEscalation{
+				TaskID:      task.ID,
+				WorkspaceID: task.WorkspaceID,
+				Title:       task.Title,
+				Deadline:    task.Deadline,
+				OldPriority: task.Priority.String(),
+				NewPriority: priority.String(),
+				AssigneeID:  task.AssigneeID,
+			})
+		}
+	}
+
+	return changed, nil
+}
+
+// StartPriorityRecalculation runs RecalculatePriorities in the background on
+// the configured interval. It does nothing when the interval is 0.
+func (s *TaskService) StartPriorityRecalculation() {
+	interval := config.GetConfig().Scheduling.RecalculateInterval
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if changed, err := s.RecalculatePriorities(); err != nil {
+				log.Printf("Priority recalculation failed: %v", err)
+			} else if changed > 0 {
+				log.Printf("Priority recalculation updated %d task(s)", changed)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// SetPriority overrides a task's priority by hand. A nil priority removes the
+// override and returns the task to its deadline-based priority.
+func (s *TaskService) SetPriority(id uint, priorityName *string, userID, workspaceID uint) (*models.TaskResponse, error) {
+	db := database.GetDB()
+
+	var task models.Task
+	if err := db.Scopes(inWorkspace(workspaceID)).First(&task, id).Error; err != nil {
+		return nil, fmt.Errorf("failed to find task: %w", err)
+	}
+
+	policy, err := newUrgencyPolicies().forWorkspace(workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	priority := policy.Priority(task.Deadline, time.Now())
+	override := priorityName != nil
+	if override {
+		p, ok := models.ParsePriority(*priorityName)
+		if !ok {
+			return nil, ErrInvalidPriority
+		}
+		priority = p
+	}
+
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		return s.changePriority(tx, &task, priority, override, models.HistorySourceUser, &userID)
+	}); err != nil {
+		return nil, err
+	}
+
+	return s.GetTaskByID(id, workspaceID)
+}
+
+// GetTaskHistory lists the recorded changes to a task, newest first.
+func (s *TaskService) GetTaskHistory(id uint, workspaceID uint) ([]models.TaskHistory, error) {
+	db := database.GetDB()
+
+	var task models.Task
+	if err := db.Scopes(inWorkspace(workspaceID)).Select("id").First(&task, id).Error; err != nil {
+		return nil, fmt.Errorf("failed to find task: %w", err)
+	}
+
+	history := []models.TaskHistory{}
+	if err := db.Where("task_id = ?", id).Order("created_at DESC, id DESC").Find(&history).Error; err != nil {
+		return nil, fmt.Errorf("failed to get task history: %w", err)
+	}
+
+	return history, nil
+}
+
+// changePriority stores a new priority and override flag and records the
+// priority change, if any, in the task history.
+func (s *TaskService) changePriority(tx *gorm.DB, task *models.Task, priority models.Priority, override bool, source models.HistorySource, userID *uint) error {
+	if err := tx.Model(&models.Task{}).Where("id = ?", task.ID).Updates(map[string]interface{}{
+		"priority":          priority,
+		"priority_override": override,
+	}).Error; err != nil {
+		return fmt.Errorf("failed to update priority: %w", err)
+	}
+
+	if priority == task.Priority {
+		return nil
+	}
+
+	if err := tx.Create(&models.TaskHistory{
+		TaskID:   task.ID,
+		Field:    "priority",
+		OldValue: task.Priority.String(),
+		NewValue: priority.String(),
+		Source:   source,
+		UserID:   userID,
+	}).Error; err != nil {
+		return fmt.Errorf("failed to record priority change: %w", err)
+	}
+
+	return nil
+}
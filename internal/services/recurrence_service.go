@@ -0,0 +1,340 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"task-manager/internal/config"
+	"task-manager/internal/database"
+	"task-manager/internal/llm"
+	"task-manager/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SetRecurrence makes a task repeat on an RRULE, or ends its series when the
+// rule is empty. The rule applies to every instance of the series and counts
+// from this task's deadline, so changing it restarts COUNT here. The next
+// instance is created at once if it is already due.
+func (s *TaskService) SetRecurrence(id uint, value string, workspaceID uint) (*models.TaskResponse, error) {
+	db := database.GetDB()
+
+	var task models.Task
+	if err := db.Scopes(inWorkspace(workspaceID)).First(&task, id).Error; err != nil {
+		return nil, fmt.Errorf("failed to find task: %w", err)
+	}
+
+	recurrence, err := normalizeRRULE(value)
+	if err != nil {
+		return nil, err
+	}
+
+	seriesID := task.ID
+	if task.SeriesID != nil {
+		seriesID = *task.SeriesID
+	}
+
+	updates := map[string]interface{}{"recurrence": recurrence}
+	if recurrence != "" {
+		updates["recurrence_start"] = task.Deadline
+		updates["series_id"] = seriesID
+	}
+	// Trashed instances follow too, so restoring one does not bring back an
+	// old rule
+	if err := db.Unscoped().Model(&models.Task{}).Where("workspace_id = ? AND (id = ? OR series_id = ?)", workspaceID, task.ID, seriesID).Updates(updates).Error; err != nil {
+		return nil, fmt.Errorf("failed to update recurrence: %w", err)
+	}
+
+	if recurrence != "" {
+		if _, err := s.nextInstance(db, seriesID, workspaceID, time.Now()); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.GetTaskByID(id, workspaceID)
+}
+
+// PreviewRecurrence lists the next count deadlines of a task's series after
+// its latest instance.
+func (s *TaskService) PreviewRecurrence(id uint, count int, workspaceID uint) (*models.RecurrencePreview, error) {
+	db := database.GetDB()
+
+	var task models.Task
+	if err := db.Scopes(inWorkspace(workspaceID)).First(&task, id).Error; err != nil {
+		return nil, fmt.Errorf("failed to find task: %w", err)
+	}
+
+	preview := &models.RecurrencePreview{
+		TaskID:      task.ID,
+		SeriesID:    task.SeriesID,
+		Recurrence:  task.Recurrence,
+		Timezone:    task.DeadlineTimezone,
+		Occurrences: []time.Time{},
+		Ended:       true,
+	}
+	if task.Recurrence == "" || task.SeriesID == nil || task.RecurrenceStart == nil {
+		return preview, nil
+	}
+
+	rule, err := parseRRULE(task.Recurrence)
+	if err != nil {
+		return nil, err
+	}
+	latest, err := latestInstance(db, *task.SeriesID, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	preview.Occurrences = rule.upcoming(*task.RecurrenceStart, latest.Deadline, deadlineLocation(latest.DeadlineTimezone), count)
+	preview.Ended = len(preview.Occurrences) < count
+	return preview, nil
+}
+
+// GenerateRecurringTasks creates the instances of recurring series that are
+// due, at most one per series, and returns how many it created.
+func (s *TaskService) GenerateRecurringTasks() (int, error) {
+	db := database.GetDB()
+
+	var series []struct {
+		SeriesID    uint
+		WorkspaceID uint
+	}
+	if err := db.Model(&models.Task{}).Where("recurrence <> '' AND series_id IS NOT NULL").
+		Distinct("series_id", "workspace_id").Find(&series).Error; err != nil {
+		return 0, fmt.Errorf("failed to find recurring tasks: %w", err)
+	}
+
+	now := time.Now()
+	created := 0
+	for _, one := range series {
+		task, err := s.nextInstance(db, one.SeriesID, one.WorkspaceID, now)
+		if err != nil {
+			// One broken series should not hold up the others
+			log.Printf("Recurring task generation failed for series %d: %v", one.SeriesID, err)
+			continue
+		}
+		if task != nil {
+			created++
+		}
+	}
+
+	return created, nil
+}
+
+// StartRecurrenceGeneration runs GenerateRecurringTasks in the background on
+// the configured interval. It does nothing when the interval is 0.
+func (s *TaskService) StartRecurrenceGeneration() {
+	interval := config.GetConfig().Recurrence.CheckInterval
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if created, err := s.GenerateRecurringTasks(); err != nil {
+				log.Printf("Recurring task generation failed: %v", err)
+			} else if created > 0 {
+				log.Printf("Recurring task generation created %d task(s)", created)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// nextInstance creates the instance that follows the latest one of a series
+// once that one is completed or the next deadline is within the lead time.
+// The new instance is a copy of the latest one with its sub-tasks reset to
+// pending; with recurrence.use_llm its plan and sub-tasks are generated
+// afresh instead. It returns nil when no instance is due.
+func (s *TaskService) nextInstance(db *gorm.DB, seriesID, workspaceID uint, now time.Time) (*models.Task, error) {
+	cfg := config.GetConfig().Recurrence
+
+	latest, err := latestInstance(db, seriesID, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	// A trashed latest instance pauses the series until it is restored
+	if latest.DeletedAt.Valid || latest.Recurrence == "" || latest.RecurrenceStart == nil {
+		return nil, nil
+	}
+
+	rule, err := parseRRULE(latest.Recurrence)
+	if err != nil {
+		return nil, err
+	}
+	loc := deadlineLocation(latest.DeadlineTimezone)
+	deadline, ok := rule.next(*latest.RecurrenceStart, latest.Deadline, loc)
+	if !ok {
+		return nil, nil
+	}
+	if latest.Status != models.StatusCompleted && deadline.Sub(now) > cfg.LeadTime {
+		return nil, nil
+	}
+
+	if err := db.Preload("SubTasks").Preload("Watchers").First(&latest, latest.ID).Error; err != nil {
+		return nil, fmt.Errorf("failed to load latest instance: %w", err)
+	}
+
+	policy, err := newUrgencyPolicies().forWorkspace(latest.WorkspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	task := models.Task{
+		Title:            latest.Title,
+		Description:      latest.Description,
+		Deadline:         deadline,
+		DeadlineTimezone: latest.DeadlineTimezone,
+		Priority:         policy.Priority(deadline, now),
+		Status:           models.StatusPending,
+		WorkspaceID:      latest.WorkspaceID,
+		CreatedBy:        latest.CreatedBy,
+		OwnerID:          latest.OwnerID,
+		AssigneeID:       latest.AssigneeID,
+		TechnicalPlan:    latest.TechnicalPlan,
+		Workflow:         latest.Workflow,
+		Recurrence:       latest.Recurrence,
+		RecurrenceStart:  latest.RecurrenceStart,
+		SeriesID:         &seriesID,
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+
+	// The LLM is asked before the transaction so a slow reply does not hold
+	// the database. Checking first keeps a lost race from paying for it.
+	var suggestions []llm.SubTaskSuggestion
+	var factor float64
+	if cfg.UseLLM {
+		if exists, err := occurrenceExists(db, seriesID, workspaceID, deadline); err != nil || exists {
+			return nil, err
+		}
+
+		override, err := NewWorkspaceService().LLMOverride(latest.WorkspaceID)
+		if err != nil {
+			return nil, err
+		}
+		llmClient := s.llmClient.WithOverride(override)
+
+		if task.TechnicalPlan, err = llmClient.GenerateTechnicalPlan(task.Title, task.Description, deadline.In(loc)); err != nil {
+			return nil, fmt.Errorf("unable to generate technical plan - %w", err)
+		}
+		if task.Workflow, err = llmClient.GenerateWorkflow(task.Title, task.Description); err != nil {
+			return nil, fmt.Errorf("unable to generate workflow - %w", err)
+		}
+		if suggestions, err = llmClient.GenerateSubTasks(task.Title, task.Description); err != nil {
+			return nil, fmt.Errorf("unable to generate sub-tasks - %w", err)
+		}
+		if factor, err = NewEstimationService().FactorFor(latest.WorkspaceID, latest.CreatedBy); err != nil {
+			return nil, err
+		}
+	}
+
+	created := false
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// Completing an instance and the background job may race for the
+		// same occurrence
+		if exists, err := occurrenceExists(tx, seriesID, workspaceID, deadline); err != nil || exists {
+			return err
+		}
+
+		if err := tx.Create(&task).Error; err != nil {
+			return fmt.Errorf("failed to create recurring task: %w", err)
+		}
+		for _, watcher := range latest.Watchers {
+			if err := tx.Model(&task).Association("Watchers").Append(&models.User{ID: watcher.ID}); err != nil {
+				return fmt.Errorf("failed to copy watchers: %w", err)
+			}
+		}
+
+		if cfg.UseLLM {
+			if err := s.createSubTasks(tx, task.ID, suggestions, factor); err != nil {
+				return err
+			}
+		} else if err := copySubTasks(tx, task.ID, latest.SubTasks, now); err != nil {
+			return err
+		}
+
+		created = true
+		return nil
+	})
+	if err != nil || !created {
+		return nil, err
+	}
+
+	if _, _, err := replanFrom(db, task.ID, now); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// occurrenceExists reports whether a series already has an instance, trashed
+// or not, due at or after deadline.
+func occurrenceExists(db *gorm.DB, seriesID, workspaceID uint, deadline time.Time) (bool, error) {
+	var existing int64
+	if err := db.Unscoped().Model(&models.Task{}).Where("series_id = ? AND workspace_id = ? AND deadline >= ?", seriesID, workspaceID, deadline).Count(&existing).Error; err != nil {
+		return false, fmt.Errorf("failed to check recurring task: %w", err)
+	}
+	return existing > 0, nil
+}
+
+// copySubTasks gives a task pending copies of template's sub-tasks, with
+// dependencies pointing at the copies.
+func copySubTasks(tx *gorm.DB, taskID uint, template []models.SubTask, now time.Time) error {
+	idByTemplate := make(map[uint]uint, len(template))
+	subTasks := make([]models.SubTask, 0, len(template))
+
+	for _, original := range template {
+		subTask := models.SubTask{
+			TaskID:            taskID,
+			Title:             original.Title,
+			Description:       original.Description,
+			EstimatedHours:    original.EstimatedHours,
+			RawEstimatedHours: original.RawEstimatedHours,
+			EstimateFactor:    original.EstimateFactor,
+			Priority:          original.Priority,
+			Status:            models.StatusPending,
+			Order:             original.Order,
+			AssigneeID:        original.AssigneeID,
+			CreatedAt:         now,
+			UpdatedAt:         now,
+		}
+		if err := tx.Create(&subTask).Error; err != nil {
+			return fmt.Errorf("failed to create sub-task: %w", err)
+		}
+
+		idByTemplate[original.ID] = subTask.ID
+		subTasks = append(subTasks, subTask)
+	}
+
+	for i, original := range template {
+		var dependencies []uint
+		for _, dependency := range original.Dependencies {
+			if id, ok := idByTemplate[dependency]; ok {
+				dependencies = append(dependencies, id)
+			}
+		}
+		if len(dependencies) == 0 {
+			continue
+		}
+
+		subTasks[i].Dependencies = dependencies
+		if err := tx.Model(&subTasks[i]).Select("Dependencies").Updates(&subTasks[i]).Error; err != nil {
+			return fmt.Errorf("failed to save sub-task dependencies: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// latestInstance is the instance of a series with the latest deadline,
+// including trashed ones.
+func latestInstance(db *gorm.DB, seriesID, workspaceID uint) (models.Task, error) {
+	var task models.Task
+	if err := db.Unscoped().Where("series_id = ? AND workspace_id = ?", seriesID, workspaceID).Order("deadline DESC, id DESC").First(&task).Error; err != nil {
+		return task, fmt.Errorf("failed to find latest instance: %w", err)
+	}
+	return task, nil
+}
@@ -0,0 +1,395 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidRecurrence = errors.New("invalid recurrence rule")
+
+// recurrenceHorizonYears bounds how far after the start occurrences are
+// searched, so a rule that never matches, such as the 30th of February,
+// cannot loop forever.
+const recurrenceHorizonYears = 100
+
+// maxRecurrenceCount is the largest COUNT accepted.
+const maxRecurrenceCount = 10000
+
+// The forms of UNTIL: a UTC date-time, a local date-time read in the
+// series' timezone, and a date, which includes that whole day.
+const (
+	rruleUTCLayout   = "20060102T150405Z"
+	rruleLocalLayout = "20060102T150405"
+	rruleDateLayout  = "20060102"
+)
+
+var rruleWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+var rruleByDayPattern = regexp.MustCompile(`^([+-]?\d{1,2})?([A-Z]{2})$`)
+
+// rruleWeekday is a BYDAY entry. N is the occurrence of Weekday within the
+// month or year, counted from the end when negative; 0 means every one.
+type rruleWeekday struct {
+	n       int
+	weekday time.Weekday
+}
+
+// recurrenceRule is an RFC 5545 RRULE with the DAILY, WEEKLY, MONTHLY and
+// YEARLY frequencies and the INTERVAL, COUNT, UNTIL, BYDAY, BYMONTHDAY,
+// BYMONTH and WKST parts.
+type recurrenceRule struct {
+	freq       string
+	interval   int
+	count      int
+	until      string
+	byDay      []rruleWeekday
+	byMonthDay []int
+	byMonth    []time.Month
+	weekStart  time.Weekday
+}
+
+// parseRRULE reads a rule such as "FREQ=WEEKLY;BYDAY=MO,WE", optionally
+// prefixed with "RRULE:".
+func parseRRULE(value string) (*recurrenceRule, error) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	value = strings.TrimPrefix(value, "RRULE:")
+
+	rule := &recurrenceRule{interval: 1, weekStart: time.Monday}
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(value, ";") {
+		name, arg, ok := strings.Cut(part, "=")
+		if !ok || arg == "" {
+			return nil, fmt.Errorf("%w: %q is not NAME=VALUE", ErrInvalidRecurrence, part)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("%w: %s is given twice", ErrInvalidRecurrence, name)
+		}
+		seen[name] = true
+
+		var err error
+		switch name {
+		case "FREQ":
+			switch arg {
+			case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+				rule.freq = arg
+			default:
+				err = fmt.Errorf("%w: FREQ must be DAILY, WEEKLY, MONTHLY or YEARLY", ErrInvalidRecurrence)
+			}
+		case "INTERVAL":
+			rule.interval, err = parseRRULEInt(name, arg, 1, 1000)
+		case "COUNT":
+			rule.count, err = parseRRULEInt(name, arg, 1, maxRecurrenceCount)
+		case "UNTIL":
+			rule.until = arg
+			_, err = rule.untilIn(time.UTC)
+		case "BYDAY":
+			for _, day := range strings.Split(arg, ",") {
+				match := rruleByDayPattern.FindStringSubmatch(day)
+				if match == nil {
+					return nil, fmt.Errorf("%w: %q is not a weekday such as MO or 1MO", ErrInvalidRecurrence, day)
+				}
+				weekday, ok := rruleWeekdays[match[2]]
+				if !ok {
+					return nil, fmt.Errorf("%w: %q is not a weekday such as MO or 1MO", ErrInvalidRecurrence, day)
+				}
+				n := 0
+				if match[1] != "" {
+					if n, err = parseRRULEInt(name, match[1], -53, 53); err != nil || n == 0 {
+						return nil, fmt.Errorf("%w: %q is not a weekday such as MO or 1MO", ErrInvalidRecurrence, day)
+					}
+				}
+				rule.byDay = append(rule.byDay, rruleWeekday{n: n, weekday: weekday})
+			}
+		case "BYMONTHDAY":
+			for _, day := range strings.Split(arg, ",") {
+				n, err := parseRRULEInt(name, day, -31, 31)
+				if err != nil || n == 0 {
+					return nil, fmt.Errorf("%w: BYMONTHDAY must list days from 1 to 31 or -31 to -1", ErrInvalidRecurrence)
+				}
+				rule.byMonthDay = append(rule.byMonthDay, n)
+			}
+		case "BYMONTH":
+			for _, month := range strings.Split(arg, ",") {
+				n, err := parseRRULEInt(name, month, 1, 12)
+				if err != nil {
+					return nil, err
+				}
+				rule.byMonth = append(rule.byMonth, time.Month(n))
+			}
+		case "WKST":
+			weekday, ok := rruleWeekdays[arg]
+			if !ok {
+				err = fmt.Errorf("%w: WKST must be a weekday such as MO", ErrInvalidRecurrence)
+			}
+			rule.weekStart = weekday
+		default:
+			err = fmt.Errorf("%w: %s is not supported", ErrInvalidRecurrence, name)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch {
+	case rule.freq == "":
+		return nil, fmt.Errorf("%w: FREQ is required", ErrInvalidRecurrence)
+	case rule.count > 0 && rule.until != "":
+		return nil, fmt.Errorf("%w: COUNT and UNTIL cannot both be given", ErrInvalidRecurrence)
+	case rule.freq == "WEEKLY" && len(rule.byMonthDay) > 0:
+		return nil, fmt.Errorf("%w: BYMONTHDAY cannot be used with FREQ=WEEKLY", ErrInvalidRecurrence)
+	}
+	if rule.freq == "DAILY" || rule.freq == "WEEKLY" {
+		for _, day := range rule.byDay {
+			if day.n != 0 {
+				return nil, fmt.Errorf("%w: numbered BYDAY needs FREQ=MONTHLY or YEARLY", ErrInvalidRecurrence)
+			}
+		}
+	}
+
+	return rule, nil
+}
+
+// normalizeRRULE checks a rule and returns it as stored: upper case without
+// the "RRULE:" prefix. An empty rule stays empty.
+func normalizeRRULE(value string) (string, error) {
+	value = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(value)), "RRULE:")
+	if value == "" {
+		return "", nil
+	}
+	if _, err := parseRRULE(value); err != nil {
+		return "", err
+	}
+	return value, nil
+}
+
+func parseRRULEInt(name, value string, min, max int) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < min || n > max {
+		return 0, fmt.Errorf("%w: %s must be a number from %d to %d", ErrInvalidRecurrence, name, min, max)
+	}
+	return n, nil
+}
+
+// untilIn returns the last moment the rule may fall on, reading local
+// values in loc. It is the zero time when the rule has no UNTIL.
+func (r *recurrenceRule) untilIn(loc *time.Location) (time.Time, error) {
+	if r.until == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(rruleUTCLayout, r.until); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation(rruleLocalLayout, r.until, loc); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation(rruleDateLayout, r.until, loc); err == nil {
+		return t.AddDate(0, 0, 1).Add(-time.Second), nil
+	}
+	return time.Time{}, fmt.Errorf("%w: UNTIL must be a date such as 20240510 or a time such as 20240510T170000Z", ErrInvalidRecurrence)
+}
+
+// occurrences calls fn with each occurrence of the rule from start, read in
+// loc, in order until fn returns false or the rule ends. Start, unless past
+// UNTIL, is the first occurrence and counts towards COUNT; later ones keep
+// its time of day.
+func (r *recurrenceRule) occurrences(start time.Time, loc *time.Location, fn func(time.Time) bool) {
+	start = start.In(loc)
+	until, _ := r.untilIn(loc)
+
+	emitted := 0
+	emit := func(t time.Time) bool {
+		if !until.IsZero() && t.After(until) {
+			return false
+		}
+		emitted++
+		return fn(t) && (r.count == 0 || emitted < r.count)
+	}
+	if !emit(start) {
+		return
+	}
+
+	first, horizon := r.periodStart(start), start.AddDate(recurrenceHorizonYears, 0, 0)
+	for k := 0; ; k++ {
+		period := r.period(first, k*r.interval)
+		if period.After(horizon) {
+			return
+		}
+		for _, day := range r.expand(period, start) {
+			y, m, d := day.Date()
+			t := time.Date(y, m, d, start.Hour(), start.Minute(), start.Second(), 0, loc)
+			if !t.After(start) {
+				continue
+			}
+			if !emit(t) {
+				return
+			}
+		}
+	}
+}
+
+// next returns the first occurrence after after, reporting false when the
+// rule has ended by then.
+func (r *recurrenceRule) next(start, after time.Time, loc *time.Location) (time.Time, bool) {
+	upcoming := r.upcoming(start, after, loc, 1)
+	if len(upcoming) == 0 {
+		return time.Time{}, false
+	}
+	return upcoming[0], true
+}
+
+// upcoming returns up to n occurrences after after.
+func (r *recurrenceRule) upcoming(start, after time.Time, loc *time.Location, n int) []time.Time {
+	var times []time.Time
+	r.occurrences(start, loc, func(t time.Time) bool {
+		if t.After(after) {
+			times = append(times, t.UTC())
+		}
+		return len(times) < n
+	})
+	return times
+}
+
+// periodStart is the first day of the day, week, month or year start falls
+// in.
+func (r *recurrenceRule) periodStart(start time.Time) time.Time {
+	y, m, d := start.Date()
+	switch r.freq {
+	case "WEEKLY":
+		back := (int(start.Weekday()) - int(r.weekStart) + 7) % 7
+		return time.Date(y, m, d-back, 0, 0, 0, 0, start.Location())
+	case "MONTHLY":
+		return time.Date(y, m, 1, 0, 0, 0, 0, start.Location())
+	case "YEARLY":
+		return time.Date(y, time.January, 1, 0, 0, 0, 0, start.Location())
+	}
+	return time.Date(y, m, d, 0, 0, 0, 0, start.Location())
+}
+
+// period is the first day of the period n periods after first.
+func (r *recurrenceRule) period(first time.Time, n int) time.Time {
+	switch r.freq {
+	case "WEEKLY":
+		return first.AddDate(0, 0, 7*n)
+	case "MONTHLY":
+		return first.AddDate(0, n, 0)
+	case "YEARLY":
+		return first.AddDate(n, 0, 0)
+	}
+	return first.AddDate(0, 0, n)
+}
+
+// expand returns the days of the period beginning at first that the rule
+// falls on, in order. Without BYDAY or BYMONTHDAY the rule repeats start's
+// weekday, day of the month or date.
+func (r *recurrenceRule) expand(first, start time.Time) []time.Time {
+	var end time.Time
+	switch r.freq {
+	case "WEEKLY":
+		end = first.AddDate(0, 0, 7)
+	case "MONTHLY":
+		end = first.AddDate(0, 1, 0)
+	case "YEARLY":
+		end = first.AddDate(1, 0, 0)
+	default:
+		end = first.AddDate(0, 0, 1)
+	}
+
+	var days []time.Time
+	for day := first; day.Before(end); day = day.AddDate(0, 0, 1) {
+		if len(r.byMonth) > 0 && !containsMonth(r.byMonth, day.Month()) {
+			continue
+		}
+		if len(r.byMonthDay) > 0 && !r.matchesMonthDay(day) {
+			continue
+		}
+		if len(r.byDay) == 0 && len(r.byMonthDay) == 0 {
+			switch r.freq {
+			case "WEEKLY":
+				if day.Weekday() != start.Weekday() {
+					continue
+				}
+			case "MONTHLY":
+				if day.Day() != start.Day() {
+					continue
+				}
+			case "YEARLY":
+				if day.Day() != start.Day() || len(r.byMonth) == 0 && day.Month() != start.Month() {
+					continue
+				}
+			}
+		}
+		days = append(days, day)
+	}
+
+	if len(r.byDay) > 0 {
+		days = r.filterWeekdays(days)
+	}
+	return days
+}
+
+func (r *recurrenceRule) matchesMonthDay(day time.Time) bool {
+	// The day after the month's last day is day 1 of the next month
+	length := time.Date(day.Year(), day.Month()+1, 0, 0, 0, 0, 0, day.Location()).Day()
+	for _, n := range r.byMonthDay {
+		if n == day.Day() || n < 0 && length+1+n == day.Day() {
+			return true
+		}
+	}
+	return false
+}
+
+// filterWeekdays keeps the days BYDAY picks. Numbered entries count within
+// the month for MONTHLY rules and YEARLY rules with BYMONTH, and within the
+// year otherwise.
+func (r *recurrenceRule) filterWeekdays(days []time.Time) []time.Time {
+	scope := func(day time.Time) int {
+		if r.freq == "MONTHLY" || r.freq == "YEARLY" && len(r.byMonth) > 0 {
+			return int(day.Month())
+		}
+		return 0
+	}
+
+	// Position of each day among the days of its weekday in its scope,
+	// from the start and from the end
+	type key struct {
+		scope   int
+		weekday time.Weekday
+	}
+	totals := make(map[key]int)
+	for _, day := range days {
+		totals[key{scope(day), day.Weekday()}]++
+	}
+
+	seen := make(map[key]int)
+	var kept []time.Time
+	for _, day := range days {
+		k := key{scope(day), day.Weekday()}
+		seen[k]++
+		for _, entry := range r.byDay {
+			if entry.weekday != day.Weekday() {
+				continue
+			}
+			if entry.n == 0 || entry.n == seen[k] || entry.n == seen[k]-totals[k]-1 {
+				kept = append(kept, day)
+				break
+			}
+		}
+	}
+	return kept
+}
+
+func containsMonth(months []time.Month, month time.Month) bool {
+	for _, m := range months {
+		if m == month {
+			return true
+		}
+	}
+	return false
+}
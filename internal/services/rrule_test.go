@@ -0,0 +1,344 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestRecurrenceRuleOccurrences follows the examples of RFC 5545 section
+// 3.8.5.3, which start at 09:00 in New York.
+func TestRecurrenceRuleOccurrences(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("LoadLocation: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		rule  string
+		start string
+		want  []string
+		// ends is set when the rule has no occurrences after want
+		ends bool
+	}{
+		{
+			name:  "daily for 10 occurrences",
+			rule:  "FREQ=DAILY;COUNT=10",
+			start: "1997-09-02",
+			want:  []string{"1997-09-02", "1997-09-03", "1997-09-04", "1997-09-05", "1997-09-06", "1997-09-07", "1997-09-08", "1997-09-09", "1997-09-10", "1997-09-11"},
+			ends:  true,
+		},
+		{
+			name:  "every other day",
+			rule:  "FREQ=DAILY;INTERVAL=2",
+			start: "1997-09-02",
+			want:  []string{"1997-09-02", "1997-09-04", "1997-09-06", "1997-09-08"},
+		},
+		{
+			name:  "weekly on Tuesday and Thursday for five weeks",
+			rule:  "FREQ=WEEKLY;UNTIL=19971007T000000Z;WKST=SU;BYDAY=TU,TH",
+			start: "1997-09-02",
+			want:  []string{"1997-09-02", "1997-09-04", "1997-09-09", "1997-09-11", "1997-09-16", "1997-09-18", "1997-09-23", "1997-09-25", "1997-09-30", "1997-10-02"},
+			ends:  true,
+		},
+		{
+			name:  "every other week on Monday, Wednesday and Friday",
+			rule:  "FREQ=WEEKLY;INTERVAL=2;WKST=SU;BYDAY=MO,WE,FR",
+			start: "1997-09-01",
+			want:  []string{"1997-09-01", "1997-09-03", "1997-09-05", "1997-09-15", "1997-09-17", "1997-09-19", "1997-09-29", "1997-10-01", "1997-10-03", "1997-10-13"},
+		},
+		{
+			name:  "every other week with WKST=MO",
+			rule:  "FREQ=WEEKLY;INTERVAL=2;COUNT=4;BYDAY=TU,SU;WKST=MO",
+			start: "1997-08-05",
+			want:  []string{"1997-08-05", "1997-08-10", "1997-08-19", "1997-08-24"},
+			ends:  true,
+		},
+		{
+			name:  "every other week with WKST=SU",
+			rule:  "FREQ=WEEKLY;INTERVAL=2;COUNT=4;BYDAY=TU,SU;WKST=SU",
+			start: "1997-08-05",
+			want:  []string{"1997-08-05", "1997-08-17", "1997-08-19", "1997-08-31"},
+			ends:  true,
+		},
+		{
+			name:  "monthly on the first Friday",
+			rule:  "FREQ=MONTHLY;COUNT=10;BYDAY=1FR",
+			start: "1997-09-05",
+			want:  []string{"1997-09-05", "1997-10-03", "1997-11-07", "1997-12-05", "1998-01-02", "1998-02-06", "1998-03-06", "1998-04-03", "1998-05-01", "1998-06-05"},
+			ends:  true,
+		},
+		{
+			name:  "every other month on the first and last Sunday",
+			rule:  "FREQ=MONTHLY;INTERVAL=2;COUNT=10;BYDAY=1SU,-1SU",
+			start: "1997-09-07",
+			want:  []string{"1997-09-07", "1997-09-28", "1997-11-02", "1997-11-30", "1998-01-04", "1998-01-25", "1998-03-01", "1998-03-29", "1998-05-03", "1998-05-31"},
+			ends:  true,
+		},
+		{
+			name:  "monthly on the second-to-last Monday",
+			rule:  "FREQ=MONTHLY;COUNT=6;BYDAY=-2MO",
+			start: "1997-09-22",
+			want:  []string{"1997-09-22", "1997-10-20", "1997-11-17", "1997-12-22", "1998-01-19", "1998-02-16"},
+			ends:  true,
+		},
+		{
+			name:  "monthly on the third-to-last day",
+			rule:  "FREQ=MONTHLY;BYMONTHDAY=-3",
+			start: "1997-09-28",
+			want:  []string{"1997-09-28", "1997-10-29", "1997-11-28", "1997-12-29", "1998-01-29", "1998-02-26"},
+		},
+		{
+			name:  "monthly on the 2nd and 15th",
+			rule:  "FREQ=MONTHLY;COUNT=10;BYMONTHDAY=2,15",
+			start: "1997-09-02",
+			want:  []string{"1997-09-02", "1997-09-15", "1997-10-02", "1997-10-15", "1997-11-02", "1997-11-15", "1997-12-02", "1997-12-15", "1998-01-02", "1998-01-15"},
+			ends:  true,
+		},
+		{
+			name:  "monthly on the first and last day",
+			rule:  "FREQ=MONTHLY;COUNT=10;BYMONTHDAY=1,-1",
+			start: "1997-09-30",
+			want:  []string{"1997-09-30", "1997-10-01", "1997-10-31", "1997-11-01", "1997-11-30", "1997-12-01", "1997-12-31", "1998-01-01", "1998-01-31", "1998-02-01"},
+			ends:  true,
+		},
+		{
+			name:  "days missing from a month are skipped",
+			rule:  "FREQ=MONTHLY;BYMONTHDAY=15,30;COUNT=5",
+			start: "2007-01-15",
+			want:  []string{"2007-01-15", "2007-01-30", "2007-02-15", "2007-03-15", "2007-03-30"},
+			ends:  true,
+		},
+		{
+			name:  "every Friday the 13th",
+			rule:  "FREQ=MONTHLY;BYDAY=FR;BYMONTHDAY=13",
+			start: "1998-02-13",
+			want:  []string{"1998-02-13", "1998-03-13", "1998-11-13", "1999-08-13", "2000-10-13"},
+		},
+		{
+			name:  "yearly in June and July",
+			rule:  "FREQ=YEARLY;COUNT=10;BYMONTH=6,7",
+			start: "1997-06-10",
+			want:  []string{"1997-06-10", "1997-07-10", "1998-06-10", "1998-07-10", "1999-06-10", "1999-07-10", "2000-06-10", "2000-07-10", "2001-06-10", "2001-07-10"},
+			ends:  true,
+		},
+		{
+			name:  "yearly on the 20th Monday",
+			rule:  "FREQ=YEARLY;BYDAY=20MO",
+			start: "1997-05-19",
+			want:  []string{"1997-05-19", "1998-05-18", "1999-05-17"},
+		},
+		{
+			name:  "every Thursday in March",
+			rule:  "FREQ=YEARLY;BYMONTH=3;BYDAY=TH",
+			start: "1997-03-13",
+			want:  []string{"1997-03-13", "1997-03-20", "1997-03-27", "1998-03-05", "1998-03-12", "1998-03-19", "1998-03-26"},
+		},
+		{
+			name:  "US presidential election day",
+			rule:  "FREQ=YEARLY;INTERVAL=4;BYMONTH=11;BYDAY=TU;BYMONTHDAY=2,3,4,5,6,7,8",
+			start: "1996-11-05",
+			want:  []string{"1996-11-05", "2000-11-07", "2004-11-02"},
+		},
+		{
+			name:  "UNTIL as a UTC time is inclusive",
+			rule:  "FREQ=DAILY;UNTIL=19970905T130000Z",
+			start: "1997-09-02",
+			want:  []string{"1997-09-02", "1997-09-03", "1997-09-04", "1997-09-05"},
+			ends:  true,
+		},
+		{
+			name:  "UNTIL as a UTC time just before an occurrence",
+			rule:  "FREQ=DAILY;UNTIL=19970905T125959Z",
+			start: "1997-09-02",
+			want:  []string{"1997-09-02", "1997-09-03", "1997-09-04"},
+			ends:  true,
+		},
+		{
+			name:  "UNTIL as a local time",
+			rule:  "FREQ=DAILY;UNTIL=19970905T090000",
+			start: "1997-09-02",
+			want:  []string{"1997-09-02", "1997-09-03", "1997-09-04", "1997-09-05"},
+			ends:  true,
+		},
+		{
+			name:  "UNTIL as a date includes that day",
+			rule:  "FREQ=DAILY;UNTIL=19970905",
+			start: "1997-09-02",
+			want:  []string{"1997-09-02", "1997-09-03", "1997-09-04", "1997-09-05"},
+			ends:  true,
+		},
+		{
+			name:  "UNTIL before the start",
+			rule:  "FREQ=WEEKLY;UNTIL=19970101",
+			start: "1997-09-02",
+			ends:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := parseRRULE(tt.rule)
+			if err != nil {
+				t.Fatalf("parseRRULE(%q): %v", tt.rule, err)
+			}
+			start, err := time.ParseInLocation("2006-01-02 15:04", tt.start+" 09:00", loc)
+			if err != nil {
+				t.Fatalf("ParseInLocation: %v", err)
+			}
+
+			// One more than expected shows whether the rule ends
+			var got []string
+			rule.occurrences(start, loc, func(at time.Time) bool {
+				if hour, minute, _ := at.In(loc).Clock(); hour != 9 || minute != 0 {
+					t.Errorf("occurrence at %v, want 09:00 in New York", at)
+				}
+				got = append(got, at.In(loc).Format("2006-01-02"))
+				return len(got) <= len(tt.want)
+			})
+
+			want := tt.want
+			if len(got) > len(want) {
+				if tt.ends {
+					t.Errorf("rule continues on %s", got[len(want)])
+				}
+				got = got[:len(want)]
+			} else if !tt.ends {
+				t.Errorf("rule ends after %d occurrences", len(got))
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("occurrences = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+// TestRecurrenceRuleUntilDecember runs RFC 5545's "daily until December
+// 24, 1997" across the end of summer time.
+func TestRecurrenceRuleUntilDecember(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("LoadLocation: %v", err)
+	}
+	rule, err := parseRRULE("RRULE:FREQ=DAILY;UNTIL=19971224T000000Z")
+	if err != nil {
+		t.Fatalf("parseRRULE: %v", err)
+	}
+
+	var got []time.Time
+	rule.occurrences(time.Date(1997, 9, 2, 9, 0, 0, 0, loc), loc, func(at time.Time) bool {
+		got = append(got, at)
+		return true
+	})
+
+	if len(got) != 113 {
+		t.Fatalf("%d occurrences, want 113", len(got))
+	}
+	if last := got[len(got)-1]; !last.Equal(time.Date(1997, 12, 23, 9, 0, 0, 0, loc)) {
+		t.Errorf("last occurrence = %v, want 1997-12-23 09:00 EST", last)
+	}
+}
+
+func TestRecurrenceRuleNext(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("LoadLocation: %v", err)
+	}
+	start := time.Date(1997, 9, 5, 9, 0, 0, 0, loc)
+
+	tests := []struct {
+		name  string
+		rule  string
+		after time.Time
+		want  time.Time
+		ok    bool
+	}{
+		{
+			name:  "after the start",
+			rule:  "FREQ=MONTHLY;BYDAY=1FR",
+			after: start,
+			want:  time.Date(1997, 10, 3, 9, 0, 0, 0, loc),
+			ok:    true,
+		},
+		{
+			name:  "between occurrences",
+			rule:  "FREQ=MONTHLY;BYDAY=1FR",
+			after: time.Date(1997, 12, 20, 0, 0, 0, 0, loc),
+			want:  time.Date(1998, 1, 2, 9, 0, 0, 0, loc),
+			ok:    true,
+		},
+		{
+			name:  "before the start",
+			rule:  "FREQ=MONTHLY;BYDAY=1FR",
+			after: time.Date(1997, 1, 1, 0, 0, 0, 0, loc),
+			want:  start,
+			ok:    true,
+		},
+		{
+			name:  "after the last occurrence",
+			rule:  "FREQ=MONTHLY;COUNT=3;BYDAY=1FR",
+			after: time.Date(1997, 11, 7, 9, 0, 0, 0, loc),
+		},
+		{
+			name:  "a date that never comes",
+			rule:  "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30",
+			after: start,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := parseRRULE(tt.rule)
+			if err != nil {
+				t.Fatalf("parseRRULE(%q): %v", tt.rule, err)
+			}
+			got, ok := rule.next(start, tt.after, loc)
+			if ok != tt.ok || !got.Equal(tt.want) {
+				t.Errorf("next() = %v, %v, want %v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestNormalizeRRULE(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+		err   bool
+	}{
+		{name: "empty", value: "  ", want: ""},
+		{name: "prefix and case", value: " rrule:freq=weekly;byday=mo,we ", want: "FREQ=WEEKLY;BYDAY=MO,WE"},
+		{name: "ordinal weekdays", value: "FREQ=MONTHLY;BYDAY=+1MO,-1FR", want: "FREQ=MONTHLY;BYDAY=+1MO,-1FR"},
+		{name: "no FREQ", value: "BYDAY=MO", err: true},
+		{name: "unknown FREQ", value: "FREQ=HOURLY", err: true},
+		{name: "unsupported part", value: "FREQ=MONTHLY;BYSETPOS=-1", err: true},
+		{name: "part given twice", value: "FREQ=DAILY;FREQ=WEEKLY", err: true},
+		{name: "COUNT and UNTIL", value: "FREQ=DAILY;COUNT=3;UNTIL=19970905", err: true},
+		{name: "malformed UNTIL", value: "FREQ=DAILY;UNTIL=1997-09-05", err: true},
+		{name: "ordinal weekday in a weekly rule", value: "FREQ=WEEKLY;BYDAY=1MO", err: true},
+		{name: "zeroth weekday", value: "FREQ=MONTHLY;BYDAY=0MO", err: true},
+		{name: "unknown weekday", value: "FREQ=WEEKLY;BYDAY=XX", err: true},
+		{name: "BYMONTHDAY of zero", value: "FREQ=MONTHLY;BYMONTHDAY=0", err: true},
+		{name: "BYMONTHDAY out of range", value: "FREQ=MONTHLY;BYMONTHDAY=-32", err: true},
+		{name: "BYMONTHDAY in a weekly rule", value: "FREQ=WEEKLY;BYMONTHDAY=1", err: true},
+		{name: "unknown WKST", value: "FREQ=WEEKLY;WKST=XX", err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizeRRULE(tt.value)
+			if tt.err {
+				if !errors.Is(err, ErrInvalidRecurrence) {
+					t.Errorf("normalizeRRULE(%q) = %q, %v, want ErrInvalidRecurrence", tt.value, got, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("normalizeRRULE(%q) = %q, %v, want %q", tt.value, got, err, tt.want)
+			}
+		})
+	}
+}
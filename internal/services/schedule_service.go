@@ -0,0 +1,453 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"task-manager/internal/database"
+	"task-manager/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ScheduleService struct{}
+
+func NewScheduleService() *ScheduleService {
+	return &ScheduleService{}
+}
+
+// GetPlan returns the planned start and end of a task's open sub-tasks.
+// Tasks that were never planned are planned now.
+func (s *ScheduleService) GetPlan(id, workspaceID uint) (*models.TaskPlan, error) {
+	db := database.GetDB()
+
+	var task models.Task
+	if err := db.Scopes(inWorkspace(workspaceID)).Preload("SubTasks").First(&task, id).Error; err != nil {
+		return nil, fmt.Errorf("failed to find task: %w", err)
+	}
+	if task.PlannedAt == nil {
+		return s.Replan(id, workspaceID)
+	}
+
+	var planned []models.PlannedSubTask
+	for _, subTask := range task.SubTasks {
+		if subTask.PlannedStart == nil || subTask.PlannedEnd == nil || !isOpen(subTask.Status) {
+			continue
+		}
+		planned = append(planned, plannedSubTask(task, subTask, *subTask.PlannedStart, *subTask.PlannedEnd))
+	}
+	sortPlan(planned)
+
+	return taskPlan(task, planned), nil
+}
+
+// Replan schedules a task's open sub-tasks again from now on.
+func (s *ScheduleService) Replan(id, workspaceID uint) (*models.TaskPlan, error) {
+	db := database.GetDB()
+
+	var task models.Task
+	if err := db.Scopes(inWorkspace(workspaceID)).Select("id").First(&task, id).Error; err != nil {
+		return nil, fmt.Errorf("failed to find task: %w", err)
+	}
+
+	plannedTask, planned, err := replanFrom(db, id, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return taskPlan(*plannedTask, planned), nil
+}
+
+// PlanICS renders the planned work on one task as an iCalendar file.
+func (s *ScheduleService) PlanICS(id, workspaceID uint) ([]byte, error) {
+	plan, err := s.GetPlan(id, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	calendars, err := loadAssigneeCalendars(database.GetDB(), workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	blocks := planBlocks(plan, calendars, nil)
+	return writeICS(plan.Title, plan.PlannedAt, blocks), nil
+}
+
+// ScheduleICS renders the planned work on every open task of a workspace as
+// an iCalendar file, optionally only the work userID is responsible for.
+// Tasks that were never planned are planned first.
+func (s *ScheduleService) ScheduleICS(workspaceID uint, userID *uint) ([]byte, error) {
+	db := database.GetDB()
+
+	var workspace models.Workspace
+	if err := db.Select("id", "name").First(&workspace, workspaceID).Error; err != nil {
+		return nil, fmt.Errorf("failed to get workspace: %w", err)
+	}
+
+	var tasks []models.Task
+	if err := db.Scopes(inWorkspace(workspaceID)).Select("id").
+		Where("status IN ?", openStatuses).Order("deadline, id").Find(&tasks).Error; err != nil {
+		return nil, fmt.Errorf("failed to get tasks: %w", err)
+	}
+
+	calendars, err := loadAssigneeCalendars(db, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	var blocks []icsBlock
+	stamp := time.Now()
+	for _, task := range tasks {
+		plan, err := s.GetPlan(task.ID, workspaceID)
+		if err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, planBlocks(plan, calendars, userID)...)
+	}
+
+	return writeICS(workspace.Name, stamp, blocks), nil
+}
+
+// replanTask schedules the open sub-tasks of a task from now on and saves
+// their planned start and end. Finished sub-tasks, and all sub-tasks of a
+// finished task, lose theirs.
+func replanTask(db *gorm.DB, taskID uint, now time.Time) (*models.Task, []models.PlannedSubTask, error) {
+	var task models.Task
+	if err := db.Preload("SubTasks").First(&task, taskID).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to find task: %w", err)
+	}
+
+	calendars, err := loadAssigneeCalendars(db, task.WorkspaceID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var planned []models.PlannedSubTask
+	if isOpen(task.Status) {
+		busy, err := busyUntil(db, task)
+		if err != nil {
+			return nil, nil, err
+		}
+		planned = schedule(task, calendars, busy, now, estimatedDuration)
+	}
+	bySubTask := make(map[uint]models.PlannedSubTask, len(planned))
+	for _, subTask := range planned {
+		bySubTask[subTask.SubTaskID] = subTask
+	}
+
+	// Columns are updated directly so planning leaves updated_at alone; it
+	// dates when a sub-task was finished for estimation.
+	err = db.Transaction(func(tx *gorm.DB) error {
+		for i, subTask := range task.SubTasks {
+			var start, end *time.Time
+			if p, ok := bySubTask[subTask.ID]; ok {
+				start, end = &p.Start, &p.End
+			}
+			if err := tx.Model(&models.SubTask{}).Where("id = ?", subTask.ID).UpdateColumns(map[string]interface{}{
+				"planned_start": start,
+				"planned_end":   end,
+			}).Error; err != nil {
+				return fmt.Errorf("failed to save sub-task plan: %w", err)
+			}
+			task.SubTasks[i].PlannedStart, task.SubTasks[i].PlannedEnd = start, end
+		}
+
+		if err := tx.Model(&models.Task{}).Where("id = ?", taskID).UpdateColumn("planned_at", now).Error; err != nil {
+			return fmt.Errorf("failed to save plan: %w", err)
+		}
+		task.PlannedAt = &now
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &task, planned, nil
+}
+
+// replanFrom replans a task and then the open tasks of its workspace that
+// come after it, whose plans wait on the work of those before them.
+func replanFrom(db *gorm.DB, taskID uint, now time.Time) (*models.Task, []models.PlannedSubTask, error) {
+	task, planned, err := replanTask(db, taskID, now)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := replanAfter(db, task.WorkspaceID, task.Deadline, task.ID, now); err != nil {
+		return nil, nil, err
+	}
+	return task, planned, nil
+}
+
+// replanAfter replans the open tasks of a workspace that come after the
+// given deadline and task ID in the order busyUntil uses. Tasks are
+// replanned in that order, so each sees the new plans of those before it.
+func replanAfter(db *gorm.DB, workspaceID uint, deadline time.Time, id uint, now time.Time) error {
+	var ids []uint
+	if err := db.Model(&models.Task{}).Scopes(inWorkspace(workspaceID)).
+		Where("status IN ? AND (deadline > ? OR (deadline = ? AND id > ?))", openStatuses, deadline, deadline, id).
+		Order("deadline, id").Pluck("id", &ids).Error; err != nil {
+		return fmt.Errorf("failed to get tasks: %w", err)
+	}
+
+	for _, id := range ids {
+		if _, _, err := replanTask(db, id, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// replanWorkspace replans every open task of a workspace, for when the
+// working time of its members changes.
+func replanWorkspace(db *gorm.DB, workspaceID uint) error {
+	return replanAfter(db, workspaceID, time.Time{}, 0, time.Now())
+}
+
+// schedule plans the open sub-tasks of task from now on. Sub-tasks are taken
+// in dependency order, the highest priority first among those whose
+// dependencies are planned. Each starts once its dependencies end and the
+// person responsible for it is free, which is not before busy says, and
+// runs for its duration in that person's working hours.
+func schedule(task models.Task, calendars *assigneeCalendars, busy map[uint]time.Time, now time.Time, duration func(models.SubTask) time.Duration) []models.PlannedSubTask {
+	open := make(map[uint]bool, len(task.SubTasks))
+	var pending []models.SubTask
+	for _, subTask := range task.SubTasks {
+		if isOpen(subTask.Status) {
+			open[subTask.ID] = true
+			pending = append(pending, subTask)
+		}
+	}
+	sort.SliceStable(pending, func(a, b int) bool {
+		if pending[a].Priority != pending[b].Priority {
+			return pending[a].Priority > pending[b].Priority
+		}
+		if pending[a].Order != pending[b].Order {
+			return pending[a].Order < pending[b].Order
+		}
+		return pending[a].ID < pending[b].ID
+	})
+
+	// Plans start on the next whole minute
+	if start := now.Truncate(time.Minute); start.Before(now) {
+		now = start.Add(time.Minute)
+	}
+
+	ends := make(map[uint]time.Time, len(pending))
+	free := make(map[uint]time.Time, len(busy))
+	for userID, until := range busy {
+		free[userID] = until
+	}
+	planned := make([]models.PlannedSubTask, 0, len(pending))
+
+	for len(pending) > 0 {
+		// A dependency cycle leaves nothing ready; it is broken at the most
+		// important sub-task
+		next := 0
+		for i, subTask := range pending {
+			if dependenciesPlanned(subTask, open, ends) {
+				next = i
+				break
+			}
+		}
+		subTask := pending[next]
+		pending = append(pending[:next], pending[next+1:]...)
+
+		userID := responsibleFor(task, subTask)
+		calendar := calendars.forUser(userID)
+
+		ready := now
+		if t := free[userID]; t.After(ready) {
+			ready = t
+		}
+		for _, dependency := range subTask.Dependencies {
+			if t := ends[dependency]; t.After(ready) {
+				ready = t
+			}
+		}
+
+		start := calendar.Add(ready, 0)
+		end := calendar.Add(start, duration(subTask))
+		ends[subTask.ID], free[userID] = end, end
+
+		planned = append(planned, plannedSubTask(task, subTask, start.UTC(), end.UTC()))
+	}
+
+	sortPlan(planned)
+	return planned
+}
+
+// busyUntil returns when each person's planned work ends on the open tasks
+// of task's workspace that come before it: those due earlier, or due at the
+// same time and created first. Later tasks wait for that work, so planning
+// them in this order settles in one pass.
+func busyUntil(db *gorm.DB, task models.Task) (map[uint]time.Time, error) {
+	var others []models.Task
+	if err := db.Scopes(inWorkspace(task.WorkspaceID)).
+		Preload("SubTasks", "status IN ? AND planned_end IS NOT NULL", openStatuses).
+		Where("id <> ? AND status IN ? AND (deadline < ? OR (deadline = ? AND id < ?))",
+			task.ID, openStatuses, task.Deadline, task.Deadline, task.ID).
+		Find(&others).Error; err != nil {
+		return nil, fmt.Errorf("failed to get other tasks: %w", err)
+	}
+
+	busy := make(map[uint]time.Time)
+	for _, other := range others {
+		for _, subTask := range other.SubTasks {
+			userID := responsibleFor(other, subTask)
+			if end := *subTask.PlannedEnd; end.After(busy[userID]) {
+				busy[userID] = end
+			}
+		}
+	}
+	return busy, nil
+}
+
+func estimatedDuration(subTask models.SubTask) time.Duration {
+	return time.Duration(subTask.EstimatedHours) * time.Hour
+}
+
+func dependenciesPlanned(subTask models.SubTask, open map[uint]bool, ends map[uint]time.Time) bool {
+	for _, dependency := range subTask.Dependencies {
+		if _, done := ends[dependency]; open[dependency] && !done {
+			return false
+		}
+	}
+	return true
+}
+
+func plannedSubTask(task models.Task, subTask models.SubTask, start, end time.Time) models.PlannedSubTask {
+	dependencies := subTask.Dependencies
+	if dependencies == nil {
+		dependencies = []uint{}
+	}
+	return models.PlannedSubTask{
+		SubTaskID:      subTask.ID,
+		Title:          subTask.Title,
+		Status:         subTask.Status,
+		Priority:       subTask.Priority,
+		EstimatedHours: subTask.EstimatedHours,
+		Dependencies:   dependencies,
+		UserID:         responsibleFor(task, subTask),
+		Start:          start,
+		End:            end,
+		Late:           end.After(task.Deadline),
+	}
+}
+
+func sortPlan(planned []models.PlannedSubTask) {
+	sort.SliceStable(planned, func(a, b int) bool {
+		if !planned[a].Start.Equal(planned[b].Start) {
+			return planned[a].Start.Before(planned[b].Start)
+		}
+		return planned[a].SubTaskID < planned[b].SubTaskID
+	})
+}
+
+func taskPlan(task models.Task, planned []models.PlannedSubTask) *models.TaskPlan {
+	plan := &models.TaskPlan{
+		TaskID:   task.ID,
+		Title:    task.Title,
+		Deadline: task.Deadline,
+		SubTasks: planned,
+	}
+	if task.PlannedAt != nil {
+		plan.PlannedAt = *task.PlannedAt
+	}
+	if plan.SubTasks == nil {
+		plan.SubTasks = []models.PlannedSubTask{}
+	}
+
+	for _, subTask := range planned {
+		if plan.End == nil || subTask.End.After(*plan.End) {
+			end := subTask.End
+			plan.End = &end
+		}
+	}
+	plan.Late = plan.End != nil && plan.End.After(task.Deadline)
+
+	return plan
+}
+
+// planBlocks splits the planned sub-tasks into stretches of their
+// assignee's working time, optionally only those userID is responsible for.
+func planBlocks(plan *models.TaskPlan, calendars *assigneeCalendars, userID *uint) []icsBlock {
+	var blocks []icsBlock
+	for _, subTask := range plan.SubTasks {
+		if userID != nil && subTask.UserID != *userID {
+			continue
+		}
+
+		description := fmt.Sprintf("Estimated %d hours. The task is due %s.",
+			subTask.EstimatedHours, plan.Deadline.UTC().Format(time.RFC3339))
+		if subTask.Late {
+			description += " Planned to end after the deadline."
+		}
+
+		for i, block := range calendars.forUser(subTask.UserID).Blocks(subTask.Start, subTask.End) {
+			blocks = append(blocks, icsBlock{
+				uid:         fmt.Sprintf("subtask-%d-%d@task-manager", subTask.SubTaskID, i),
+				summary:     plan.Title + ": " + subTask.Title,
+				description: description,
+				start:       block[0],
+				end:         block[1],
+			})
+		}
+	}
+	return blocks
+}
+
+// assigneeCalendars holds the working calendar of each member of a
+// workspace: their own, else the workspace's.
+type assigneeCalendars struct {
+	workspace *WorkingCalendar
+	byUser    map[uint]*WorkingCalendar
+}
+
+func loadAssigneeCalendars(db *gorm.DB, workspaceID uint) (*assigneeCalendars, error) {
+	var workspace models.Workspace
+	if err := db.Select("id", "calendar_id").First(&workspace, workspaceID).Error; err != nil {
+		return nil, fmt.Errorf("failed to get workspace: %w", err)
+	}
+
+	var memberships []models.WorkspaceMembership
+	if err := db.Where("workspace_id = ? AND calendar_id IS NOT NULL", workspaceID).
+		Find(&memberships).Error; err != nil {
+		return nil, fmt.Errorf("failed to get member calendars: %w", err)
+	}
+
+	loaded := make(map[uint]*WorkingCalendar)
+	load := func(id *uint) (*WorkingCalendar, error) {
+		if id == nil {
+			return nil, nil
+		}
+		if calendar, ok := loaded[*id]; ok {
+			return calendar, nil
+		}
+		calendar, err := loadWorkingCalendar(id)
+		if err != nil {
+			return nil, err
+		}
+		loaded[*id] = calendar
+		return calendar, nil
+	}
+
+	calendars := &assigneeCalendars{byUser: make(map[uint]*WorkingCalendar, len(memberships))}
+	var err error
+	if calendars.workspace, err = load(workspace.CalendarID); err != nil {
+		return nil, err
+	}
+	for _, membership := range memberships {
+		if calendars.byUser[membership.UserID], err = load(membership.CalendarID); err != nil {
+			return nil, err
+		}
+	}
+
+	return calendars, nil
+}
+
+func (c *assigneeCalendars) forUser(userID uint) *WorkingCalendar {
+	if calendar, ok := c.byUser[userID]; ok {
+		return calendar
+	}
+	return c.workspace
+}
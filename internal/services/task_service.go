@@ -1,13 +1,21 @@
 package services
 
 import (
+	"errors"
 	"fmt"
-	"math"
+	"log"
 	"sort"
 	"task-manager/internal/database"
 	"task-manager/internal/llm"
 	"task-manager/internal/models"
 	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidStatus   = errors.New("status must be 0 (pending), 1 (in progress), 2 (completed) or 3 (cancelled)")
+	ErrInvalidEstimate = errors.New("estimated_hours must not be negative")
 )
 
 type TaskService struct {
@@ -20,30 +28,75 @@ func NewTaskService() *TaskService {
 	}
 }
 
-func (s *TaskService) CreateTask(req models.TaskRequest) (*models.TaskResponse, error) {
+// CreateTask creates a task in workspaceID, generating its plan with the
+// workspace's LLM settings.
+func (s *TaskService) CreateTask(req models.TaskRequest, userID uint, workspaceID uint) (*models.TaskResponse, error) {
 	db := database.GetDB()
 
+	override, err := NewWorkspaceService().LLMOverride(workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	llmClient := s.llmClient.WithOverride(override)
+
+	policies := newUrgencyPolicies()
+	policy, err := policies.forWorkspace(workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	// A deadline without an offset is read in the request's timezone, or the
+	// creator's preference
+	zone := req.Timezone
+	if zone == "" {
+		creator, err := NewAuthService().GetUser(userID)
+		if err != nil {
+			return nil, err
+		}
+		zone = creator.PreferredTimezone()
+	}
+	loc, err := loadTimezone(zone)
+	if err != nil {
+		return nil, err
+	}
+	deadline, err := parseDeadline(req.Deadline, loc)
+	if err != nil {
+		return nil, err
+	}
+	recurrence, err := normalizeRRULE(req.Recurrence)
+	if err != nil {
+		return nil, err
+	}
+
 	// Calculate priority based on deadline
-	priority := s.calculatePriority(req.Deadline)
+	priority := policy.Priority(deadline, time.Now())
 
 	task := models.Task{
-		Title:       req.Title,
-		Description: req.Description,
-		Deadline:    req.Deadline,
-		Priority:    priority,
-		Status:      models.StatusPending,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+		Title:            req.Title,
+		Description:      req.Description,
+		Deadline:         deadline,
+		DeadlineTimezone: loc.String(),
+		Priority:         priority,
+		Status:           models.StatusPending,
+		WorkspaceID:      workspaceID,
+		CreatedBy:        userID,
+		OwnerID:          userID,
+		CreatedAt:        time.Now(),
+		UpdatedAt:        time.Now(),
+	}
+	if recurrence != "" {
+		task.Recurrence = recurrence
+		task.RecurrenceStart = &deadline
 	}
 
 	// Generate LLM content
-	technicalPlan, err := s.llmClient.GenerateTechnicalPlan(req.Title, req.Description, req.Deadline)
+	technicalPlan, err := llmClient.GenerateTechnicalPlan(req.Title, req.Description, deadline.In(loc))
 	if err != nil {
 		return nil, fmt.Errorf("unable to generate technical plan - %w", err)
 	}
 	task.TechnicalPlan = technicalPlan
 
-	workflow, err := s.llmClient.GenerateWorkflow(req.Title, req.Description)
+	workflow, err := llmClient.GenerateWorkflow(req.Title, req.Description)
 	if err != nil {
 		return nil, fmt.Errorf("unable to generate workflow - %w", err)
 	}
@@ -53,65 +106,134 @@ func (s *TaskService) CreateTask(req models.TaskRequest) (*models.TaskResponse,
 	if err := db.Create(&task).Error; err != nil {
 		return nil, fmt.Errorf("failed to create task: %w", err)
 	}
+	// A recurring task is the first instance of its series
+	if recurrence != "" {
+		task.SeriesID = &task.ID
+		if err := db.Model(&task).UpdateColumn("series_id", task.ID).Error; err != nil {
+			return nil, fmt.Errorf("failed to start series: %w", err)
+		}
+	}
 
 	// Generate and save sub-tasks
-	subTaskSuggestions, err := s.llmClient.GenerateSubTasks(req.Title, req.Description)
+	subTaskSuggestions, err := llmClient.GenerateSubTasks(req.Title, req.Description)
 	if err != nil {
 		return nil, fmt.Errorf("unable to generate sub-tasks - %w", err)
 	}
 
-	for _, suggestion := range subTaskSuggestions {
+	// Scale the LLM's estimates by how far off past estimates turned out
+	factor, err := NewEstimationService().FactorFor(workspaceID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.createSubTasks(db, task.ID, subTaskSuggestions, factor); err != nil {
+		return nil, err
+	}
+
+	if _, _, err := replanFrom(db, task.ID, time.Now()); err != nil {
+		return nil, err
+	}
+
+	// A rule whose next occurrence is already within the lead time gets its
+	// next instance now rather than on the next background run
+	if task.SeriesID != nil {
+		if _, err := s.nextInstance(db, task.ID, workspaceID, time.Now()); err != nil {
+			log.Printf("Failed to create the next instance of series %d: %v", task.ID, err)
+		}
+	}
+
+	// Load task with sub-tasks
+	if err := db.Preload("SubTasks").Preload("Watchers").First(&task, task.ID).Error; err != nil {
+		return nil, fmt.Errorf("failed to load task with sub-tasks: %w", err)
+	}
+
+	calendar, err := policies.calendar(workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	response := s.taskResponse(task, policy, calendar, now)
+	if response.Feasibility, err = s.feasibility(db, task, calendar, now); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// createSubTasks saves the LLM's sub-task suggestions for a task, scaling
+// their estimates by factor.
+func (s *TaskService) createSubTasks(db *gorm.DB, taskID uint, suggestions []llm.SubTaskSuggestion, factor float64) error {
+	// Suggestions reference each other by order, so remember which ID each
+	// order ended up with and resolve dependencies once all rows exist.
+	idByOrder := make(map[int]uint)
+	subTasks := make([]models.SubTask, 0, len(suggestions))
+
+	for _, suggestion := range suggestions {
 		subTaskPriority := s.parsePriority(suggestion.Priority)
 		subTask := models.SubTask{
-			TaskID:         task.ID,
-			Title:          suggestion.Title,
-			Description:    suggestion.Description,
-			EstimatedHours: suggestion.EstimatedHours,
-			Priority:       subTaskPriority,
-			Status:         models.StatusPending,
-			Order:          suggestion.Order,
-			CreatedAt:      time.Now(),
-			UpdatedAt:      time.Now(),
+			TaskID:            taskID,
+			Title:             suggestion.Title,
+			Description:       suggestion.Description,
+			EstimatedHours:    calibrateEstimate(suggestion.EstimatedHours, factor),
+			RawEstimatedHours: suggestion.EstimatedHours,
+			EstimateFactor:    factor,
+			Priority:          subTaskPriority,
+			Status:            models.StatusPending,
+			Order:             suggestion.Order,
+			CreatedAt:         time.Now(),
+			UpdatedAt:         time.Now(),
 		}
 
 		if err := db.Create(&subTask).Error; err != nil {
-			return nil, fmt.Errorf("failed to create sub-task: %w", err)
+			return fmt.Errorf("failed to create sub-task: %w", err)
 		}
-	}
 
-	// Load task with sub-tasks
-	if err := db.Preload("SubTasks").First(&task, task.ID).Error; err != nil {
-		return nil, fmt.Errorf("failed to load task with sub-tasks: %w", err)
+		idByOrder[suggestion.Order] = subTask.ID
+		subTasks = append(subTasks, subTask)
 	}
 
-	urgencyScore := s.calculateUrgencyScore(task.Deadline)
-	timeRemaining := s.formatTimeRemaining(task.Deadline)
+	for i, suggestion := range suggestions {
+		var dependencies []uint
+		for _, order := range suggestion.Dependencies {
+			if id, ok := idByOrder[order]; ok {
+				dependencies = append(dependencies, id)
+			}
+		}
+		if len(dependencies) == 0 {
+			continue
+		}
 
-	return &models.TaskResponse{
-		Task:          task,
-		UrgencyScore:  urgencyScore,
-		TimeRemaining: timeRemaining,
-	}, nil
+		subTasks[i].Dependencies = dependencies
+		if err := db.Model(&subTasks[i]).Select("Dependencies").Updates(&subTasks[i]).Error; err != nil {
+			return fmt.Errorf("failed to save sub-task dependencies: %w", err)
+		}
+	}
+
+	return nil
 }
 
-func (s *TaskService) GetAllTasks() ([]models.TaskResponse, error) {
+func (s *TaskService) GetAllTasks(workspaceID uint) ([]models.TaskResponse, error) {
 	db := database.GetDB()
 	var tasks []models.Task
 
-	if err := db.Preload("SubTasks").Find(&tasks).Error; err != nil {
+	if err := db.Scopes(inWorkspace(workspaceID)).Preload("SubTasks").Preload("Watchers").Find(&tasks).Error; err != nil {
 		return nil, fmt.Errorf("failed to get tasks: %w", err)
 	}
 
+	policies := newUrgencyPolicies()
+	policy, err := policies.forWorkspace(workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	calendar, err := policies.calendar(workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
 	var responses []models.TaskResponse
 	for _, task := range tasks {
-		urgencyScore := s.calculateUrgencyScore(task.Deadline)
-		timeRemaining := s.formatTimeRemaining(task.Deadline)
-
-		responses = append(responses, models.TaskResponse{
-			Task:          task,
-			UrgencyScore:  urgencyScore,
-			TimeRemaining: timeRemaining,
-		})
+		responses = append(responses, s.taskResponse(task, policy, calendar, now))
 	}
 
 	// Sort by urgency score (highest first)
@@ -122,92 +244,156 @@ func (s *TaskService) GetAllTasks() ([]models.TaskResponse, error) {
 	return responses, nil
 }
 
-func (s *TaskService) GetTaskByID(id uint) (*models.TaskResponse, error) {
+func (s *TaskService) GetTaskByID(id uint, workspaceID uint) (*models.TaskResponse, error) {
 	db := database.GetDB()
 	var task models.Task
 
-	if err := db.Preload("SubTasks").First(&task, id).Error; err != nil {
+	if err := db.Scopes(inWorkspace(workspaceID)).Preload("SubTasks").Preload("Watchers").First(&task, id).Error; err != nil {
 		return nil, fmt.Errorf("failed to get task: %w", err)
 	}
 
-	urgencyScore := s.calculateUrgencyScore(task.Deadline)
-	timeRemaining := s.formatTimeRemaining(task.Deadline)
+	policies := newUrgencyPolicies()
+	policy, err := policies.forWorkspace(workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	calendar, err := policies.calendar(workspaceID)
+	if err != nil {
+		return nil, err
+	}
 
-	return &models.TaskResponse{
-		Task:          task,
-		UrgencyScore:  urgencyScore,
-		TimeRemaining: timeRemaining,
-	}, nil
+	response := s.taskResponse(task, policy, calendar, time.Now())
+	return &response, nil
 }
 
-func (s *TaskService) UpdateTaskStatus(id uint, status models.TaskStatus) error {
+func (s *TaskService) UpdateTaskStatus(id uint, status models.TaskStatus, workspaceID uint) error {
 	db := database.GetDB()
 	
-	if err := db.Model(&models.Task{}).Where("id = ?", id).Update("status", status).Error; err != nil {
-		return fmt.Errorf("failed to update task status: %w", err)
+	result := db.Model(&models.Task{}).Scopes(inWorkspace(workspaceID)).Where("id = ?", id).Update("status", status)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update task status: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("failed to update task status: %w", gorm.ErrRecordNotFound)
+	}
+
+	if _, _, err := replanFrom(db, id, time.Now()); err != nil {
+		return err
+	}
+
+	// Completing an instance of a recurring task brings on the next one.
+	// The status change stands if that fails; the background job retries.
+	if status == models.StatusCompleted {
+		var task models.Task
+		if err := db.Select("id", "series_id").First(&task, id).Error; err != nil {
+			return fmt.Errorf("failed to find task: %w", err)
+		}
+		if task.SeriesID != nil {
+			if _, err := s.nextInstance(db, *task.SeriesID, workspaceID, time.Now()); err != nil {
+				log.Printf("Failed to create the next instance of series %d: %v", *task.SeriesID, err)
+			}
+		}
 	}
 
 	return nil
 }
 
-func (s *TaskService) DeleteTask(id uint) error {
+// UpdateSubTask changes a sub-task's status or estimate and replans the
+// task's remaining work.
+func (s *TaskService) UpdateSubTask(taskID, subTaskID uint, req models.SubTaskRequest, workspaceID uint) (*models.TaskResponse, error) {
 	db := database.GetDB()
-	
-	if err := db.Delete(&models.Task{}, id).Error; err != nil {
-		return fmt.Errorf("failed to delete task: %w", err)
+
+	updates := make(map[string]interface{})
+	if req.Status != nil {
+		if *req.Status < models.StatusPending || *req.Status > models.StatusCancelled {
+			return nil, ErrInvalidStatus
+		}
+		updates["status"] = *req.Status
+	}
+	if req.EstimatedHours != nil {
+		if *req.EstimatedHours < 0 {
+			return nil, ErrInvalidEstimate
+		}
+		updates["estimated_hours"] = *req.EstimatedHours
 	}
 
-	return nil
-}
+	var task models.Task
+	if err := db.Scopes(inWorkspace(workspaceID)).Select("id").First(&task, taskID).Error; err != nil {
+		return nil, fmt.Errorf("failed to find task: %w", err)
+	}
 
-func (s *TaskService) calculatePriority(deadline time.Time) models.Priority {
-	now := time.Now()
-	timeUntilDeadline := deadline.Sub(now)
+	var subTask models.SubTask
+	if err := db.Where("task_id = ?", taskID).First(&subTask, subTaskID).Error; err != nil {
+		return nil, fmt.Errorf("failed to find sub-task: %w", err)
+	}
 
-	switch {
-	case timeUntilDeadline <= 24*time.Hour:
-		return models.PriorityUrgent
-	case timeUntilDeadline <= 3*24*time.Hour:
-		return models.PriorityHigh
-	case timeUntilDeadline <= 7*24*time.Hour:
-		return models.PriorityMedium
-	default:
-		return models.PriorityLow
+	if len(updates) > 0 {
+		if err := db.Model(&subTask).Updates(updates).Error; err != nil {
+			return nil, fmt.Errorf("failed to update sub-task: %w", err)
+		}
+		if _, _, err := replanFrom(db, taskID, time.Now()); err != nil {
+			return nil, err
+		}
 	}
+
+	return s.GetTaskByID(taskID, workspaceID)
 }
 
-func (s *TaskService) calculateUrgencyScore(deadline time.Time) float64 {
+// DeleteTask moves a task and its sub-tasks to the trash. Both share the same
+// deletion timestamp so RestoreTask can bring back exactly this batch.
+func (s *TaskService) DeleteTask(id uint, workspaceID uint) error {
+	db := database.GetDB()
 	now := time.Now()
-	timeUntilDeadline := deadline.Sub(now)
-	
-	if timeUntilDeadline <= 0 {
-		return 100.0 // Overdue
+
+	var task models.Task
+	if err := db.Scopes(inWorkspace(workspaceID)).Select("id", "deadline").First(&task, id).Error; err != nil {
+		return fmt.Errorf("failed to delete task: %w", err)
 	}
 
-	hoursUntilDeadline := timeUntilDeadline.Hours()
-	
-	// Exponential decay function for urgency
-	// Score approaches 100 as deadline approaches
-	score := 100 * (1 - math.Exp(-10/hoursUntilDeadline))
-	
-	return math.Min(score, 100.0)
-}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Task{}).Scopes(inWorkspace(workspaceID)).Where("id = ?", id).Update("deleted_at", now)
+		if result.Error != nil {
+			return fmt.Errorf("failed to delete task: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("failed to delete task: %w", gorm.ErrRecordNotFound)
+		}
 
-func (s *TaskService) formatTimeRemaining(deadline time.Time) string {
-	now := time.Now()
-	timeUntilDeadline := deadline.Sub(now)
+		if err := tx.Model(&models.SubTask{}).Where("task_id = ?", id).Update("deleted_at", now).Error; err != nil {
+			return fmt.Errorf("failed to delete sub-tasks: %w", err)
+		}
+
+		// Nobody can reach a trashed task's timers to stop them
+		return stopTimers(tx, now, "task_id = ?", id)
+	})
+	if err != nil {
+		return err
+	}
+
+	// Later tasks no longer wait for this one's work
+	return replanAfter(db, workspaceID, task.Deadline, task.ID, now)
+}
 
-	if timeUntilDeadline <= 0 {
-		return "Overdue"
+// inWorkspace limits a task query to tasks in workspaceID.
+func inWorkspace(workspaceID uint) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("workspace_id = ?", workspaceID)
 	}
+}
 
-	days := int(timeUntilDeadline.Hours() / 24)
-	hours := int(timeUntilDeadline.Hours()) % 24
+// taskResponse scores task with policy and counts its time remaining on
+// calendar. The deadline is rendered in the zone it was given in.
+func (s *TaskService) taskResponse(task models.Task, policy UrgencyPolicy, calendar *WorkingCalendar, now time.Time) models.TaskResponse {
+	timeRemaining, remaining := remainingTime(calendar, task.Deadline, now)
 
-	if days > 0 {
-		return fmt.Sprintf("%d days, %d hours", days, hours)
+	response := models.TaskResponse{
+		Task:          task,
+		UrgencyScore:  policy.Score(task, now),
+		TimeRemaining: timeRemaining,
+		Remaining:     remaining,
 	}
-	return fmt.Sprintf("%d hours", hours)
+	response.Localize(deadlineLocation(task.DeadlineTimezone))
+	return response
 }
 
 func (s *TaskService) parsePriority(priorityStr string) models.Priority {
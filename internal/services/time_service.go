@@ -0,0 +1,354 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"task-manager/internal/database"
+	"task-manager/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrTimerRunning     = errors.New("you already have a running timer; stop it first")
+	ErrNoRunningTimer   = errors.New("no timer of yours is running on this sub-task")
+	ErrNoTimer          = errors.New("you have no running timer")
+	ErrInvalidTimeEntry = errors.New("ended_at must be after started_at and not in the future")
+	ErrNotEntryOwner    = errors.New("time entries can only be deleted by the user who recorded them")
+)
+
+type TimeService struct{}
+
+func NewTimeService() *TimeService {
+	return &TimeService{}
+}
+
+// StartTimer starts a timer for userID on a sub-task. A user can only run
+// one timer at a time.
+func (s *TimeService) StartTimer(taskID, subTaskID, userID, workspaceID uint, req models.TimerRequest) (*models.TimeEntry, error) {
+	db := database.GetDB()
+
+	if _, err := s.findSubTask(db, taskID, subTaskID, workspaceID); err != nil {
+		return nil, err
+	}
+
+	entry := models.TimeEntry{
+		TaskID:    taskID,
+		SubTaskID: subTaskID,
+		UserID:    userID,
+		StartedAt: time.Now(),
+		Note:      strings.TrimSpace(req.Note),
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		running, err := s.runningTimer(tx, userID)
+		if err != nil {
+			return err
+		}
+		if running != nil {
+			return ErrTimerRunning
+		}
+		if err := tx.Create(&entry).Error; err != nil {
+			return fmt.Errorf("failed to start timer: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		// The running-timer index also catches a timer started concurrently
+		if running, _ := s.runningTimer(db, userID); running != nil {
+			return nil, ErrTimerRunning
+		}
+		return nil, err
+	}
+
+	return &entry, nil
+}
+
+// StopTimer stops userID's running timer on a sub-task.
+func (s *TimeService) StopTimer(taskID, subTaskID, userID, workspaceID uint) (*models.TimeEntry, error) {
+	db := database.GetDB()
+
+	if _, err := s.findSubTask(db, taskID, subTaskID, workspaceID); err != nil {
+		return nil, err
+	}
+
+	running, err := s.runningTimer(db, userID)
+	if err != nil {
+		return nil, err
+	}
+	if running == nil || running.SubTaskID != subTaskID {
+		return nil, ErrNoRunningTimer
+	}
+
+	now := time.Now()
+	running.EndedAt = &now
+	if err := db.Model(running).Update("ended_at", now).Error; err != nil {
+		return nil, fmt.Errorf("failed to stop timer: %w", err)
+	}
+
+	return running, nil
+}
+
+// StopRunningTimer stops userID's running timer wherever it runs, even on a
+// task that has since been trashed or in a workspace they have left.
+func (s *TimeService) StopRunningTimer(userID uint) (*models.TimeEntry, error) {
+	db := database.GetDB()
+
+	running, err := s.runningTimer(db, userID)
+	if err != nil {
+		return nil, err
+	}
+	if running == nil {
+		return nil, ErrNoTimer
+	}
+
+	now := time.Now()
+	running.EndedAt = &now
+	if err := db.Model(running).Update("ended_at", now).Error; err != nil {
+		return nil, fmt.Errorf("failed to stop timer: %w", err)
+	}
+
+	return running, nil
+}
+
+// RunningTimer returns userID's running timer, or nil when none is running.
+func (s *TimeService) RunningTimer(userID uint) (*models.TimeEntry, error) {
+	return s.runningTimer(database.GetDB(), userID)
+}
+
+// AddEntry records time spent on a sub-task without a timer.
+func (s *TimeService) AddEntry(taskID, subTaskID, userID, workspaceID uint, req models.TimeEntryRequest) (*models.TimeEntry, error) {
+	db := database.GetDB()
+
+	if _, err := s.findSubTask(db, taskID, subTaskID, workspaceID); err != nil {
+		return nil, err
+	}
+	if !req.EndedAt.After(req.StartedAt) || req.EndedAt.After(time.Now()) {
+		return nil, ErrInvalidTimeEntry
+	}
+
+	endedAt := req.EndedAt
+	entry := models.TimeEntry{
+		TaskID:    taskID,
+		SubTaskID: subTaskID,
+		UserID:    userID,
+		StartedAt: req.StartedAt,
+		EndedAt:   &endedAt,
+		Note:      strings.TrimSpace(req.Note),
+	}
+	if err := db.Create(&entry).Error; err != nil {
+		return nil, fmt.Errorf("failed to add time entry: %w", err)
+	}
+
+	return &entry, nil
+}
+
+// ListEntries lists the time entries of a task, newest first.
+func (s *TimeService) ListEntries(taskID, workspaceID uint) ([]models.TimeEntry, error) {
+	db := database.GetDB()
+
+	var task models.Task
+	if err := db.Scopes(inWorkspace(workspaceID)).Select("id").First(&task, taskID).Error; err != nil {
+		return nil, fmt.Errorf("failed to find task: %w", err)
+	}
+
+	entries := []models.TimeEntry{}
+	if err := db.Where("task_id = ?", taskID).Order("started_at DESC, id DESC").Find(&entries).Error; err != nil {
+		return nil, fmt.Errorf("failed to get time entries: %w", err)
+	}
+
+	return entries, nil
+}
+
+// DeleteEntry removes one of userID's time entries, running or not.
+func (s *TimeService) DeleteEntry(taskID, entryID, userID, workspaceID uint) error {
+	db := database.GetDB()
+
+	var task models.Task
+	if err := db.Scopes(inWorkspace(workspaceID)).Select("id").First(&task, taskID).Error; err != nil {
+		return fmt.Errorf("failed to find task: %w", err)
+	}
+
+	var entry models.TimeEntry
+	if err := db.Where("task_id = ?", taskID).First(&entry, entryID).Error; err != nil {
+		return fmt.Errorf("failed to find time entry: %w", err)
+	}
+	if entry.UserID != userID {
+		return ErrNotEntryOwner
+	}
+
+	if err := db.Delete(&entry).Error; err != nil {
+		return fmt.Errorf("failed to delete time entry: %w", err)
+	}
+	return nil
+}
+
+// GetTaskTime compares the time tracked on each of a task's sub-tasks with
+// its estimate. Running timers count up to now.
+func (s *TimeService) GetTaskTime(taskID, workspaceID uint) (*models.TaskTime, error) {
+	db := database.GetDB()
+
+	var task models.Task
+	if err := db.Scopes(inWorkspace(workspaceID)).Preload("SubTasks", orderSubTasks).First(&task, taskID).Error; err != nil {
+		return nil, fmt.Errorf("failed to find task: %w", err)
+	}
+
+	var entries []models.TimeEntry
+	if err := db.Where("task_id = ?", taskID).Find(&entries).Error; err != nil {
+		return nil, fmt.Errorf("failed to get time entries: %w", err)
+	}
+
+	taskTime := summarizeTime(task, entries, false, time.Now())
+	return &taskTime, nil
+}
+
+// GetReport compares actual with estimated hours for every task in
+// workspaceID. With userID set, only that user's entries, and the sub-tasks
+// they tracked time on, are counted.
+func (s *TimeService) GetReport(workspaceID uint, userID *uint) (*models.TimeReport, error) {
+	db := database.GetDB()
+
+	var tasks []models.Task
+	if err := db.Scopes(inWorkspace(workspaceID)).Preload("SubTasks", orderSubTasks).Order("id").Find(&tasks).Error; err != nil {
+		return nil, fmt.Errorf("failed to get tasks: %w", err)
+	}
+
+	ids := make([]uint, len(tasks))
+	for i, task := range tasks {
+		ids[i] = task.ID
+	}
+
+	var entries []models.TimeEntry
+	query := db.Where("task_id IN ?", ids)
+	if userID != nil {
+		query = query.Where("user_id = ?", *userID)
+	}
+	if err := query.Find(&entries).Error; err != nil {
+		return nil, fmt.Errorf("failed to get time entries: %w", err)
+	}
+
+	byTask := make(map[uint][]models.TimeEntry)
+	for _, entry := range entries {
+		byTask[entry.TaskID] = append(byTask[entry.TaskID], entry)
+	}
+
+	now := time.Now()
+	report := &models.TimeReport{
+		WorkspaceID: workspaceID,
+		UserID:      userID,
+		Tasks:       []models.TaskTime{},
+	}
+	for _, task := range tasks {
+		taskTime := summarizeTime(task, byTask[task.ID], userID != nil, now)
+		if userID != nil && len(taskTime.SubTasks) == 0 {
+			continue
+		}
+		report.EstimatedHours += taskTime.EstimatedHours
+		report.ActualHours += taskTime.ActualHours
+		report.Tasks = append(report.Tasks, taskTime)
+	}
+	report.ActualHours = roundHours(report.ActualHours)
+	report.VarianceHours = roundHours(report.ActualHours - float64(report.EstimatedHours))
+
+	return report, nil
+}
+
+func (s *TimeService) findSubTask(db *gorm.DB, taskID, subTaskID, workspaceID uint) (*models.SubTask, error) {
+	var task models.Task
+	if err := db.Scopes(inWorkspace(workspaceID)).Select("id").First(&task, taskID).Error; err != nil {
+		return nil, fmt.Errorf("failed to find task: %w", err)
+	}
+
+	var subTask models.SubTask
+	if err := db.Where("task_id = ?", taskID).First(&subTask, subTaskID).Error; err != nil {
+		return nil, fmt.Errorf("failed to find sub-task: %w", err)
+	}
+	return &subTask, nil
+}
+
+// stopTimers stops the running timers matched by query, such as those on a
+// task being trashed.
+func stopTimers(tx *gorm.DB, now time.Time, query string, args ...interface{}) error {
+	if err := tx.Model(&models.TimeEntry{}).Where("ended_at IS NULL").Where(query, args...).
+		Update("ended_at", now).Error; err != nil {
+		return fmt.Errorf("failed to stop timers: %w", err)
+	}
+	return nil
+}
+
+func (s *TimeService) runningTimer(db *gorm.DB, userID uint) (*models.TimeEntry, error) {
+	var entries []models.TimeEntry
+	if err := db.Where("user_id = ? AND ended_at IS NULL", userID).Limit(1).Find(&entries).Error; err != nil {
+		return nil, fmt.Errorf("failed to check running timer: %w", err)
+	}
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	return &entries[0], nil
+}
+
+// summarizeTime totals entries per sub-task of task. With trackedOnly, sub-tasks
+// without entries are left out.
+func summarizeTime(task models.Task, entries []models.TimeEntry, trackedOnly bool, now time.Time) models.TaskTime {
+	type tracked struct {
+		duration time.Duration
+		entries  int
+		running  bool
+	}
+	bySubTask := make(map[uint]*tracked)
+	for _, entry := range entries {
+		t, ok := bySubTask[entry.SubTaskID]
+		if !ok {
+			t = &tracked{}
+			bySubTask[entry.SubTaskID] = t
+		}
+		t.duration += entry.Duration(now)
+		t.entries++
+		t.running = t.running || entry.Running()
+	}
+
+	taskTime := models.TaskTime{
+		TaskID:   task.ID,
+		Title:    task.Title,
+		Status:   task.Status,
+		SubTasks: []models.SubTaskTime{},
+	}
+	for _, subTask := range task.SubTasks {
+		t, ok := bySubTask[subTask.ID]
+		if !ok {
+			if trackedOnly {
+				continue
+			}
+			t = &tracked{}
+		}
+
+		actual := roundHours(t.duration.Hours())
+		taskTime.EstimatedHours += subTask.EstimatedHours
+		taskTime.ActualHours += actual
+		taskTime.SubTasks = append(taskTime.SubTasks, models.SubTaskTime{
+			SubTaskID:      subTask.ID,
+			Title:          subTask.Title,
+			Status:         subTask.Status,
+			EstimatedHours: subTask.EstimatedHours,
+			ActualHours:    actual,
+			VarianceHours:  roundHours(actual - float64(subTask.EstimatedHours)),
+			Entries:        t.entries,
+			Running:        t.running,
+		})
+	}
+	taskTime.ActualHours = roundHours(taskTime.ActualHours)
+	taskTime.VarianceHours = roundHours(taskTime.ActualHours - float64(taskTime.EstimatedHours))
+
+	return taskTime
+}
+
+// roundHours rounds to hundredths of an hour for reporting.
+func roundHours(hours float64) float64 {
+	return math.Round(hours*100) / 100
+}
+
+func orderSubTasks(db *gorm.DB) *gorm.DB {
+	return db.Order(`"order", id`)
+}
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"task-manager/internal/config"
+	"task-manager/internal/database"
+	"task-manager/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func (s *TaskService) GetTrash(workspaceID uint) ([]models.TrashedTask, error) {
+	db := database.GetDB()
+	var tasks []models.Task
+
+	if err := db.Unscoped().Scopes(inWorkspace(workspaceID)).
+		Preload("SubTasks", func(tx *gorm.DB) *gorm.DB { return tx.Unscoped() }).
+		Where("deleted_at IS NOT NULL").
+		Order("deleted_at DESC").
+		Find(&tasks).Error; err != nil {
+		return nil, fmt.Errorf("failed to get trash: %w", err)
+	}
+
+	retentionDays := config.GetConfig().Trash.RetentionDays
+
+	trash := make([]models.TrashedTask, 0, len(tasks))
+	for _, task := range tasks {
+		item := models.TrashedTask{
+			Task:      task,
+			DeletedAt: task.DeletedAt.Time,
+		}
+		if retentionDays > 0 {
+			purgeAt := task.DeletedAt.Time.AddDate(0, 0, retentionDays)
+			item.PurgeAt = &purgeAt
+		}
+		trash = append(trash, item)
+	}
+
+	return trash, nil
+}
+
+// RestoreTask brings a task back from the trash together with the sub-tasks
+// that were deleted alongside it.
+func (s *TaskService) RestoreTask(id uint, workspaceID uint) (*models.TaskResponse, error) {
+	db := database.GetDB()
+	var task models.Task
+
+	if err := db.Unscoped().Scopes(inWorkspace(workspaceID)).Where("deleted_at IS NOT NULL").First(&task, id).Error; err != nil {
+		return nil, fmt.Errorf("failed to find deleted task: %w", err)
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&models.SubTask{}).
+			Where("task_id = ? AND deleted_at = ?", id, task.DeletedAt.Time).
+			Update("deleted_at", nil).Error; err != nil {
+			return fmt.Errorf("failed to restore sub-tasks: %w", err)
+		}
+
+		if err := tx.Unscoped().Model(&models.Task{}).Where("id = ?", id).Update("deleted_at", nil).Error; err != nil {
+			return fmt.Errorf("failed to restore task: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Time has passed in the trash, so the old plan is likely stale
+	if _, _, err := replanFrom(db, id, time.Now()); err != nil {
+		return nil, err
+	}
+
+	return s.GetTaskByID(id, workspaceID)
+}
+
+// PurgeTask permanently removes a task that is already in the trash.
+func (s *TaskService) PurgeTask(id uint, workspaceID uint) error {
+	db := database.GetDB()
+	var task models.Task
+
+	if err := db.Unscoped().Scopes(inWorkspace(workspaceID)).Where("deleted_at IS NOT NULL").First(&task, id).Error; err != nil {
+		return fmt.Errorf("failed to find deleted task: %w", err)
+	}
+
+	return s.purgeTasks(db, []uint{id})
+}
+
+// EmptyTrash permanently removes every task in the workspace's trash and returns
+// how many were purged.
+func (s *TaskService) EmptyTrash(workspaceID uint) (int, error) {
+	return s.purgeDeletedBefore(time.Now(), inWorkspace(workspaceID))
+}
+
+// PurgeExpiredTasks permanently removes tasks that have been in the trash
+// longer than the configured retention period.
+func (s *TaskService) PurgeExpiredTasks() (int, error) {
+	retentionDays := config.GetConfig().Trash.RetentionDays
+	if retentionDays <= 0 {
+		return 0, nil
+	}
+
+	return s.purgeDeletedBefore(time.Now().AddDate(0, 0, -retentionDays))
+}
+
+// StartTrashRetention runs PurgeExpiredTasks in the background on the
+// configured interval. It does nothing when retention is disabled.
+func (s *TaskService) StartTrashRetention() {
+	cfg := config.GetConfig().Trash
+	if cfg.RetentionDays <= 0 {
+		return
+	}
+
+	interval := cfg.PurgeInterval
+	if interval <= 0 {
+		interval = time.Hour
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if purged, err := s.PurgeExpiredTasks(); err != nil {
+				log.Printf("Trash retention failed: %v", err)
+			} else if purged > 0 {
+				log.Printf("Trash retention purged %d task(s)", purged)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+func (s *TaskService) purgeDeletedBefore(cutoff time.Time, scopes ...func(*gorm.DB) *gorm.DB) (int, error) {
+	db := database.GetDB()
+	var ids []uint
+
+	if err := db.Unscoped().Model(&models.Task{}).Scopes(scopes...).
+		Where("deleted_at IS NOT NULL AND deleted_at <= ?", cutoff).
+		Pluck("id", &ids).Error; err != nil {
+		return 0, fmt.Errorf("failed to find expired tasks: %w", err)
+	}
+
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	if err := s.purgeTasks(db, ids); err != nil {
+		return 0, err
+	}
+
+	return len(ids), nil
+}
+
+func (s *TaskService) purgeTasks(db *gorm.DB, ids []uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table("task_watchers").Where("task_id IN ?", ids).Delete(nil).Error; err != nil {
+			return fmt.Errorf("failed to purge watchers: %w", err)
+		}
+
+		if err := tx.Where("task_id IN ?", ids).Delete(&models.TaskHistory{}).Error; err != nil {
+			return fmt.Errorf("failed to purge task history: %w", err)
+		}
+
+		if err := tx.Where("task_id IN ?", ids).Delete(&models.TimeEntry{}).Error; err != nil {
+			return fmt.Errorf("failed to purge time entries: %w", err)
+		}
+
+		if err := tx.Unscoped().Where("task_id IN ?", ids).Delete(&models.SubTask{}).Error; err != nil {
+			return fmt.Errorf("failed to purge sub-tasks: %w", err)
+		}
+
+		if err := tx.Unscoped().Where("id IN ?", ids).Delete(&models.Task{}).Error; err != nil {
+			return fmt.Errorf("failed to purge tasks: %w", err)
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,271 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"task-manager/internal/config"
+	"task-manager/internal/database"
+	"task-manager/internal/models"
+	"time"
+)
+
+// UrgencyPolicy scores how urgent a task is, from 0 to 100, and derives the
+// priority a deadline implies.
+type UrgencyPolicy interface {
+	Name() string
+	Score(task models.Task, now time.Time) float64
+	Priority(deadline, now time.Time) models.Priority
+}
+
+// urgencyCurves turn the hours left until a deadline into a score from 0 to
+// 100, given the policy horizon in hours. They are only called with hoursLeft
+// above 0. New curves must also be listed in config.UrgencyCurves.
+var urgencyCurves = map[string]func(hoursLeft, horizon float64) float64{
+	"exponential": func(hoursLeft, horizon float64) float64 {
+		return 100 * (1 - math.Exp(-horizon/hoursLeft))
+	},
+	"linear": func(hoursLeft, horizon float64) float64 {
+		return 100 * math.Max(0, 1-hoursLeft/horizon)
+	},
+}
+
+// NewUrgencyPolicy builds the policy described by cfg. Priority thresholds
+// the policy leaves unset come from scheduling. Scores count the working time
+// of calendar, or wall-clock time when it is nil; priority thresholds are
+// always wall-clock.
+func NewUrgencyPolicy(cfg config.UrgencyPolicyConfig, scheduling config.SchedulingConfig, calendar *WorkingCalendar) UrgencyPolicy {
+	if cfg.UrgentWithin == 0 {
+		cfg.UrgentWithin = scheduling.UrgentWithin
+	}
+	if cfg.HighWithin == 0 {
+		cfg.HighWithin = scheduling.HighWithin
+	}
+	if cfg.MediumWithin == 0 {
+		cfg.MediumWithin = scheduling.MediumWithin
+	}
+	curve, ok := urgencyCurves[cfg.Curve]
+	if !ok {
+		curve = urgencyCurves["exponential"]
+	}
+	return &weightedPolicy{cfg: cfg, curve: curve, calendar: calendar}
+}
+
+// weightedPolicy is the configurable policy: a deadline curve blended with
+// remaining work, adjusted for manual priority, blocking and status.
+type weightedPolicy struct {
+	cfg      config.UrgencyPolicyConfig
+	curve    func(hoursLeft, horizon float64) float64
+	calendar *WorkingCalendar
+}
+
+func (p *weightedPolicy) Name() string {
+	return p.cfg.Name
+}
+
+func (p *weightedPolicy) Score(task models.Task, now time.Time) float64 {
+	statusWeight := 1.0
+	if weight, ok := p.cfg.StatusWeights[task.Status.String()]; ok {
+		statusWeight = weight
+	}
+	if statusWeight == 0 {
+		return 0
+	}
+
+	hoursLeft := p.calendar.Between(now, task.Deadline).Hours()
+	score := 100.0 // Overdue
+	if hoursLeft > 0 {
+		score = p.curve(hoursLeft, p.cfg.Horizon.Hours())
+	}
+
+	remaining, blocked := remainingWork(task)
+	if w := p.cfg.RemainingWorkWeight; w > 0 {
+		workScore := 100.0
+		if hoursLeft > 0 {
+			workScore = math.Min(100*remaining/hoursLeft, 100)
+		}
+		score = (1-w)*score + w*workScore
+	}
+
+	if task.PriorityOverride {
+		score += p.cfg.ManualPriorityBoost * float64(task.Priority)
+	}
+
+	score *= 1 - p.cfg.BlockedWeight*blocked
+	score *= statusWeight
+
+	return math.Max(0, math.Min(score, 100))
+}
+
+func (p *weightedPolicy) Priority(deadline, now time.Time) models.Priority {
+	timeUntilDeadline := deadline.Sub(now)
+
+	switch {
+	case timeUntilDeadline <= p.cfg.UrgentWithin:
+		return models.PriorityUrgent
+	case timeUntilDeadline <= p.cfg.HighWithin:
+		return models.PriorityHigh
+	case timeUntilDeadline <= p.cfg.MediumWithin:
+		return models.PriorityMedium
+	default:
+		return models.PriorityLow
+	}
+}
+
+// remainingWork sums the estimated hours of a task's open sub-tasks and
+// returns the share of them that wait on an unfinished dependency.
+func remainingWork(task models.Task) (hours float64, blocked float64) {
+	open := make(map[uint]bool, len(task.SubTasks))
+	for _, subTask := range task.SubTasks {
+		if isOpen(subTask.Status) {
+			open[subTask.ID] = true
+		}
+	}
+	if len(open) == 0 {
+		return 0, 0
+	}
+
+	waiting := 0
+	for _, subTask := range task.SubTasks {
+		if !open[subTask.ID] {
+			continue
+		}
+		hours += float64(subTask.EstimatedHours)
+		for _, dependency := range subTask.Dependencies {
+			if open[dependency] {
+				waiting++
+				break
+			}
+		}
+	}
+
+	return hours, float64(waiting) / float64(len(open))
+}
+
+func isOpen(status models.TaskStatus) bool {
+	return status == models.StatusPending || status == models.StatusInProgress
+}
+
+// urgencyPolicies resolves each workspace's policy and working calendar once
+// per operation against a single config snapshot.
+type urgencyPolicies struct {
+	cfg         *config.Config
+	byWorkspace map[uint]UrgencyPolicy
+	calendars   map[uint]*WorkingCalendar
+}
+
+func newUrgencyPolicies() *urgencyPolicies {
+	return &urgencyPolicies{
+		cfg:         config.GetConfig(),
+		byWorkspace: make(map[uint]UrgencyPolicy),
+		calendars:   make(map[uint]*WorkingCalendar),
+	}
+}
+
+// named returns the policy called name, or the default policy, counting
+// time on calendar.
+func (p *urgencyPolicies) named(name string, calendar *WorkingCalendar) UrgencyPolicy {
+	return NewUrgencyPolicy(p.cfg.Urgency.Policy(name), p.cfg.Scheduling, calendar)
+}
+
+func (p *urgencyPolicies) forWorkspace(workspaceID uint) (UrgencyPolicy, error) {
+	if policy, ok := p.byWorkspace[workspaceID]; ok {
+		return policy, nil
+	}
+
+	var workspace models.Workspace
+	if err := database.GetDB().Select("id", "urgency_policy", "calendar_id").First(&workspace, workspaceID).Error; err != nil {
+		return nil, fmt.Errorf("failed to get workspace: %w", err)
+	}
+
+	calendar, err := loadWorkingCalendar(workspace.CalendarID)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := p.named(workspace.UrgencyPolicy, calendar)
+	p.byWorkspace[workspaceID] = policy
+	p.calendars[workspaceID] = calendar
+	return policy, nil
+}
+
+// calendar returns the workspace's working calendar, nil for wall-clock time.
+func (p *urgencyPolicies) calendar(workspaceID uint) (*WorkingCalendar, error) {
+	if _, err := p.forWorkspace(workspaceID); err != nil {
+		return nil, err
+	}
+	return p.calendars[workspaceID], nil
+}
+
+// PreviewUrgency scores the open and finished tasks of a workspace under each
+// named policy, or every configured policy when names is empty, without
+// changing anything.
+func (s *TaskService) PreviewUrgency(workspaceID uint, names []string) (*models.UrgencyPreview, error) {
+	policies := newUrgencyPolicies()
+	cfg := policies.cfg.Urgency
+
+	if len(names) == 0 {
+		for _, policy := range cfg.Policies {
+			names = append(names, policy.Name)
+		}
+	}
+	for _, name := range names {
+		if !cfg.HasPolicy(name) {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownUrgencyPolicy, name)
+		}
+	}
+
+	current, err := policies.forWorkspace(workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	calendar, err := policies.calendar(workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	var tasks []models.Task
+	if err := database.GetDB().Scopes(inWorkspace(workspaceID)).Preload("SubTasks").Order("id").Find(&tasks).Error; err != nil {
+		return nil, fmt.Errorf("failed to get tasks: %w", err)
+	}
+
+	now := time.Now()
+	preview := &models.UrgencyPreview{
+		Current:  current.Name(),
+		Policies: names,
+		Tasks:    make([]models.UrgencyPreviewTask, len(tasks)),
+	}
+	for i, task := range tasks {
+		preview.Tasks[i] = models.UrgencyPreviewTask{
+			TaskID:   task.ID,
+			Title:    task.Title,
+			Status:   task.Status,
+			Deadline: task.Deadline,
+			Priority: task.Priority,
+			Scores:   make(map[string]models.UrgencyPreviewScore, len(names)),
+		}
+	}
+
+	for _, name := range names {
+		policy := policies.named(name, calendar)
+		order := make([]int, len(tasks))
+		scores := make([]float64, len(tasks))
+		for i, task := range tasks {
+			order[i] = i
+			scores[i] = policy.Score(task, now)
+		}
+		sort.SliceStable(order, func(a, b int) bool {
+			return scores[order[a]] > scores[order[b]]
+		})
+
+		for rank, i := range order {
+			preview.Tasks[i].Scores[name] = models.UrgencyPreviewScore{
+				UrgencyScore: scores[i],
+				Priority:     policy.Priority(tasks[i].Deadline, now),
+				Rank:         rank + 1,
+			}
+		}
+	}
+
+	return preview, nil
+}
@@ -0,0 +1,313 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"task-manager/internal/database"
+	"task-manager/internal/models"
+	"time"
+
+	// Calendars name IANA timezones; embed the database so they resolve on
+	// hosts without zoneinfo installed.
+	_ "time/tzdata"
+)
+
+const dateLayout = "2006-01-02"
+
+// maxCalendarDays bounds how far ahead working time is searched, so a
+// calendar with every working day on holiday cannot loop forever.
+const maxCalendarDays = 10 * 366
+
+// WorkingCalendar measures time in working hours. A nil *WorkingCalendar
+// measures wall-clock time, so callers need no special case for workspaces
+// without a calendar.
+type WorkingCalendar struct {
+	loc      *time.Location
+	days     [7]bool
+	start    int // minutes after midnight
+	end      int
+	holidays map[string]bool
+	dates    []time.Time
+}
+
+func newWorkingCalendar(calendar models.Calendar) (*WorkingCalendar, error) {
+	loc, err := time.LoadLocation(calendar.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load timezone %q: %w", calendar.Timezone, err)
+	}
+	start, err := parseClock(calendar.DayStart)
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseClock(calendar.DayEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &WorkingCalendar{
+		loc:      loc,
+		start:    start,
+		end:      end,
+		holidays: make(map[string]bool, len(calendar.Holidays)),
+	}
+	for _, name := range calendar.WorkingDays {
+		if day, ok := parseWeekday(name); ok {
+			c.days[day] = true
+		}
+	}
+	for _, holiday := range calendar.Holidays {
+		date, err := time.ParseInLocation(dateLayout, holiday.Date, loc)
+		if err != nil {
+			continue
+		}
+		c.holidays[holiday.Date] = true
+		c.dates = append(c.dates, date)
+	}
+
+	return c, nil
+}
+
+// loadWorkingCalendar returns the calendar with the given ID, or nil for
+// wall-clock time when id is nil.
+func loadWorkingCalendar(id *uint) (*WorkingCalendar, error) {
+	if id == nil {
+		return nil, nil
+	}
+
+	var calendar models.Calendar
+	if err := database.GetDB().Preload("Holidays").First(&calendar, *id).Error; err != nil {
+		return nil, fmt.Errorf("failed to load calendar: %w", err)
+	}
+	return newWorkingCalendar(calendar)
+}
+
+// HoursPerDay is the length of a working day, or 24 for wall-clock time.
+func (c *WorkingCalendar) HoursPerDay() float64 {
+	if c == nil {
+		return 24
+	}
+	return c.dayLength().Hours()
+}
+
+// HoursPerWeek is the working time in a week without holidays, or 168 for
+// wall-clock time.
+func (c *WorkingCalendar) HoursPerWeek() float64 {
+	if c == nil {
+		return 7 * 24
+	}
+	days := 0
+	for _, worked := range c.days {
+		if worked {
+			days++
+		}
+	}
+	return float64(days) * c.HoursPerDay()
+}
+
+// Between returns the working time from from to to. It is negative when to
+// is before from.
+func (c *WorkingCalendar) Between(from, to time.Time) time.Duration {
+	if c == nil {
+		return to.Sub(from)
+	}
+	if to.Before(from) {
+		return -c.Between(to, from)
+	}
+
+	from, to = from.In(c.loc), to.In(c.loc)
+	firstDay, lastDay := c.midnight(from), c.midnight(to)
+	if firstDay.Equal(lastDay) {
+		return c.workedOn(firstDay, from, to)
+	}
+
+	total := c.workedOn(firstDay, from, to) + c.workedOn(lastDay, from, to)
+
+	// Days strictly between the first and last are worked in full
+	next := firstDay.AddDate(0, 0, 1)
+	days := int(math.Round(lastDay.Sub(next).Hours() / 24))
+	total += time.Duration(c.workingDays(next, days)) * c.dayLength()
+
+	return total
+}
+
+// Add returns the moment d of working time after from. With d zero it is
+// the next moment work can happen, which is from itself during working
+// hours.
+func (c *WorkingCalendar) Add(from time.Time, d time.Duration) time.Time {
+	if c == nil {
+		return from.Add(d)
+	}
+
+	from = from.In(c.loc)
+	day := c.midnight(from)
+	for i := 0; i < maxCalendarDays; i++ {
+		if open, close, ok := c.window(day, from); ok {
+			available := close.Sub(open)
+			if d <= available {
+				return open.Add(d)
+			}
+			d -= available
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+
+	// A calendar without working days never gets the work done
+	return day.Add(d)
+}
+
+// Blocks splits the time from from to to into its stretches of working
+// time, one per working day.
+func (c *WorkingCalendar) Blocks(from, to time.Time) [][2]time.Time {
+	if c == nil {
+		if !to.After(from) {
+			return nil
+		}
+		return [][2]time.Time{{from, to}}
+	}
+
+	var blocks [][2]time.Time
+	from, to = from.In(c.loc), to.In(c.loc)
+	for day := c.midnight(from); day.Before(to) && len(blocks) < maxCalendarDays; day = day.AddDate(0, 0, 1) {
+		open, close, ok := c.window(day, from)
+		if !ok || !open.Before(to) {
+			continue
+		}
+		if to.Before(close) {
+			close = to
+		}
+		blocks = append(blocks, [2]time.Time{open, close})
+	}
+	return blocks
+}
+
+// window returns the working hours of day from from on, reporting false
+// when none are left.
+func (c *WorkingCalendar) window(day, from time.Time) (time.Time, time.Time, bool) {
+	if !c.isWorkingDay(day) {
+		return time.Time{}, time.Time{}, false
+	}
+
+	open, close := c.at(day, c.start), c.at(day, c.end)
+	if from.After(open) {
+		open = from
+	}
+	return open, close, close.After(open)
+}
+
+func (c *WorkingCalendar) dayLength() time.Duration {
+	return time.Duration(c.end-c.start) * time.Minute
+}
+
+func (c *WorkingCalendar) midnight(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, c.loc)
+}
+
+func (c *WorkingCalendar) at(day time.Time, minutes int) time.Time {
+	y, m, d := day.Date()
+	return time.Date(y, m, d, 0, minutes, 0, 0, c.loc)
+}
+
+func (c *WorkingCalendar) isWorkingDay(day time.Time) bool {
+	return c.days[day.Weekday()] && !c.holidays[day.Format(dateLayout)]
+}
+
+// workedOn returns the working time on day that falls between from and to.
+func (c *WorkingCalendar) workedOn(day, from, to time.Time) time.Duration {
+	if !c.isWorkingDay(day) {
+		return 0
+	}
+
+	open, close := c.at(day, c.start), c.at(day, c.end)
+	if from.After(open) {
+		open = from
+	}
+	if to.Before(close) {
+		close = to
+	}
+	if !close.After(open) {
+		return 0
+	}
+	return close.Sub(open)
+}
+
+// workingDays counts the working days among the n days starting at start.
+func (c *WorkingCalendar) workingDays(start time.Time, n int) int {
+	if n <= 0 {
+		return 0
+	}
+
+	perWeek := 0
+	for _, worked := range c.days {
+		if worked {
+			perWeek++
+		}
+	}
+	count := n / 7 * perWeek
+	for i := 0; i < n%7; i++ {
+		if c.days[(int(start.Weekday())+i)%7] {
+			count++
+		}
+	}
+
+	end := start.AddDate(0, 0, n)
+	for _, date := range c.dates {
+		if !date.Before(start) && date.Before(end) && c.days[date.Weekday()] {
+			count--
+		}
+	}
+
+	return count
+}
+
+// remainingTime describes the time left until deadline, counted on calendar.
+func remainingTime(calendar *WorkingCalendar, deadline, now time.Time) (string, models.RemainingTime) {
+	left := calendar.Between(now, deadline)
+	remaining := models.RemainingTime{
+		HoursPerDay: calendar.HoursPerDay(),
+		WorkingTime: calendar != nil,
+	}
+	if left <= 0 {
+		remaining.Overdue = true
+		return "Overdue", remaining
+	}
+
+	day := time.Duration(remaining.HoursPerDay * float64(time.Hour))
+	remaining.Seconds = int64(left.Seconds())
+	remaining.Days = int(left / day)
+	remaining.Hours = int(left % day / time.Hour)
+	remaining.Minutes = int(left % time.Hour / time.Minute)
+
+	unit := "days"
+	hours := "hours"
+	if remaining.WorkingTime {
+		unit = "working days"
+		hours = "working hours"
+	}
+	if remaining.Days > 0 {
+		return fmt.Sprintf("%d %s, %d hours", remaining.Days, unit, remaining.Hours), remaining
+	}
+	return fmt.Sprintf("%d %s", remaining.Hours, hours), remaining
+}
+
+// parseClock parses "HH:MM" into minutes after midnight.
+func parseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q is not a time such as \"09:00\"", ErrInvalidWorkingHours, value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func parseWeekday(name string) (time.Weekday, bool) {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if name == weekdayName(day) {
+			return day, true
+		}
+	}
+	return time.Sunday, false
+}
+
+func weekdayName(day time.Weekday) string {
+	return [...]string{"sunday", "monday", "tuesday", "wednesday", "thursday", "friday", "saturday"}[day]
+}
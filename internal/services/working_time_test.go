@@ -0,0 +1,140 @@
+package services
+
+import (
+	"task-manager/internal/models"
+	"testing"
+	"time"
+)
+
+// testCalendar works Monday to Friday, 09:00 to 17:00 in Berlin, with
+// Christmas Day, a Saturday and New Year's Day off. Berlin switches to
+// summer time on Sunday 2024-03-31.
+func testCalendar(t *testing.T) *WorkingCalendar {
+	t.Helper()
+
+	calendar, err := newWorkingCalendar(models.Calendar{
+		Timezone:    "Europe/Berlin",
+		WorkingDays: []string{"monday", "tuesday", "wednesday", "thursday", "friday"},
+		DayStart:    "09:00",
+		DayEnd:      "17:00",
+		Holidays: []models.Holiday{
+			{Date: "2024-12-25"},
+			{Date: "2024-12-28"},
+			{Date: "2025-01-01"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("newWorkingCalendar: %v", err)
+	}
+	return calendar
+}
+
+func berlin(t *testing.T, value string) time.Time {
+	t.Helper()
+
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatalf("LoadLocation: %v", err)
+	}
+	at, err := time.ParseInLocation("2006-01-02 15:04", value, loc)
+	if err != nil {
+		t.Fatalf("ParseInLocation(%q): %v", value, err)
+	}
+	return at
+}
+
+func TestWorkingCalendarBetween(t *testing.T) {
+	calendar := testCalendar(t)
+
+	tests := []struct {
+		name     string
+		from, to string
+		want     time.Duration
+	}{
+		{"within one day", "2024-03-25 10:00", "2024-03-25 12:30", 150 * time.Minute},
+		{"outside working hours", "2024-03-25 07:00", "2024-03-25 18:00", 8 * time.Hour},
+		{"over a weekend", "2024-03-22 15:00", "2024-03-25 11:00", 4 * time.Hour},
+		{"backwards", "2024-03-25 11:00", "2024-03-22 15:00", -4 * time.Hour},
+		{"across the switch to summer time", "2024-03-29 09:00", "2024-04-02 17:00", 24 * time.Hour},
+		{"weekday holiday", "2024-12-24 09:00", "2024-12-26 17:00", 16 * time.Hour},
+		{"holiday on a weekend", "2024-12-27 09:00", "2024-12-30 17:00", 16 * time.Hour},
+		{"several weeks", "2024-12-16 09:00", "2025-01-03 17:00", 13 * 8 * time.Hour},
+		{"weekend only", "2024-03-23 09:00", "2024-03-24 17:00", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := calendar.Between(berlin(t, tt.from), berlin(t, tt.to)); got != tt.want {
+				t.Errorf("Between(%s, %s) = %v, want %v", tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWorkingCalendarAdd(t *testing.T) {
+	calendar := testCalendar(t)
+
+	tests := []struct {
+		name string
+		from string
+		d    time.Duration
+		want string
+	}{
+		{"within one day", "2024-03-25 10:00", 2 * time.Hour, "2024-03-25 12:00"},
+		{"zero during working hours", "2024-03-25 10:00", 0, "2024-03-25 10:00"},
+		{"zero on a weekend", "2024-03-23 10:00", 0, "2024-03-25 09:00"},
+		{"zero after hours", "2024-03-25 17:30", 0, "2024-03-26 09:00"},
+		{"ending at closing time", "2024-03-25 09:00", 8 * time.Hour, "2024-03-25 17:00"},
+		{"over a weekend", "2024-03-22 16:00", 3 * time.Hour, "2024-03-25 11:00"},
+		{"across the switch to summer time", "2024-03-29 16:00", 2 * time.Hour, "2024-04-01 10:00"},
+		{"skipping a holiday", "2024-12-24 16:00", 2 * time.Hour, "2024-12-26 10:00"},
+		{"holiday on a weekend", "2024-12-27 16:00", 2 * time.Hour, "2024-12-30 10:00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := calendar.Add(berlin(t, tt.from), tt.d)
+			if want := berlin(t, tt.want); !got.Equal(want) {
+				t.Errorf("Add(%s, %v) = %v, want %v", tt.from, tt.d, got, want)
+			}
+		})
+	}
+}
+
+func TestWorkingCalendarWorkingDays(t *testing.T) {
+	calendar := testCalendar(t)
+
+	tests := []struct {
+		name  string
+		start string
+		n     int
+		want  int
+	}{
+		{"none", "2024-12-23 00:00", 0, 0},
+		{"week with a weekday holiday", "2024-12-23 00:00", 7, 4},
+		{"weekend with a holiday", "2024-12-28 00:00", 2, 0},
+		{"partial week from a Saturday", "2024-12-28 00:00", 4, 2},
+		{"three weeks", "2024-12-16 00:00", 21, 13},
+		{"across the switch to summer time", "2024-03-29 00:00", 5, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := calendar.workingDays(berlin(t, tt.start), tt.n); got != tt.want {
+				t.Errorf("workingDays(%s, %d) = %d, want %d", tt.start, tt.n, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWallClockCalendar(t *testing.T) {
+	var calendar *WorkingCalendar
+	from := time.Date(2024, 3, 30, 12, 0, 0, 0, time.UTC)
+
+	if got := calendar.Between(from, from.Add(30*time.Hour)); got != 30*time.Hour {
+		t.Errorf("Between = %v, want 30h", got)
+	}
+	if got := calendar.Add(from, 30*time.Hour); !got.Equal(from.Add(30 * time.Hour)) {
+		t.Errorf("Add = %v, want %v", got, from.Add(30*time.Hour))
+	}
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"task-manager/internal/config"
+	"task-manager/internal/database"
+	"task-manager/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const maxWorkloadWeeks = 52
+
+type WorkloadService struct{}
+
+func NewWorkloadService() *WorkloadService {
+	return &WorkloadService{}
+}
+
+// GetWorkload sums the estimated hours of open sub-tasks assigned to userID
+// in workspaceID, per week, for weeks weeks starting with the week of from.
+// A sub-task without its own assignee belongs to its task's assignee. Work
+// is placed in the week its task is due; overdue work lands in the first
+// week.
+func (s *WorkloadService) GetWorkload(userID, workspaceID uint, from time.Time, weeks int) (*models.WorkloadResponse, error) {
+	db := database.GetDB()
+
+	if weeks <= 0 {
+		weeks = 4
+	}
+	if weeks > maxWorkloadWeeks {
+		weeks = maxWorkloadWeeks
+	}
+
+	var user models.User
+	if err := db.First(&user, userID).Error; err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if _, err := NewWorkspaceService().membership(db, workspaceID, userID); err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", gorm.ErrRecordNotFound)
+	}
+
+	open := []models.TaskStatus{models.StatusPending, models.StatusInProgress}
+
+	var tasks []models.Task
+	if err := db.Scopes(inWorkspace(workspaceID)).
+		Preload("SubTasks", "status IN ?", open).
+		Where("status IN ?", open).
+		Order("deadline").
+		Find(&tasks).Error; err != nil {
+		return nil, fmt.Errorf("failed to get tasks: %w", err)
+	}
+
+	capacity := config.GetConfig().Workload.WeeklyCapacityHours
+	firstWeek := startOfWeek(from)
+
+	response := &models.WorkloadResponse{
+		UserID:        user.ID,
+		Username:      user.Username,
+		WorkspaceID:   workspaceID,
+		CapacityHours: capacity,
+		Weeks:         make([]models.WeekLoad, weeks),
+	}
+	for i := range response.Weeks {
+		response.Weeks[i] = models.WeekLoad{
+			WeekStart:     firstWeek.AddDate(0, 0, 7*i),
+			CapacityHours: capacity,
+			Items:         []models.WorkloadItem{},
+		}
+	}
+
+	now := time.Now()
+	for _, task := range tasks {
+		// Round because weeks spanning a DST change are not exactly 168h
+		week := int(math.Round(startOfWeek(task.Deadline).Sub(firstWeek).Hours() / (24 * 7)))
+		if week < 0 {
+			week = 0
+		}
+		if week >= weeks {
+			continue
+		}
+
+		for _, subTask := range task.SubTasks {
+			if responsibleFor(task, subTask) != userID {
+				continue
+			}
+
+			load := &response.Weeks[week]
+			load.PlannedHours += subTask.EstimatedHours
+			load.Items = append(load.Items, models.WorkloadItem{
+				SubTaskID:      subTask.ID,
+				TaskID:         task.ID,
+				Title:          subTask.Title,
+				TaskTitle:      task.Title,
+				EstimatedHours: subTask.EstimatedHours,
+				Deadline:       task.Deadline,
+				Overdue:        task.Deadline.Before(now),
+			})
+		}
+	}
+
+	for i := range response.Weeks {
+		load := &response.Weeks[i]
+		if capacity > 0 {
+			load.Utilization = float64(load.PlannedHours) / float64(capacity)
+		}
+		load.Overallocated = load.PlannedHours > capacity
+		if load.Overallocated {
+			response.Overallocated = true
+		}
+	}
+
+	return response, nil
+}
+
+// startOfWeek returns midnight on the Monday of t's week, in t's location.
+func startOfWeek(t time.Time) time.Time {
+	daysSinceMonday := (int(t.Weekday()) + 6) % 7
+	year, month, day := t.Date()
+	return time.Date(year, month, day-daysSinceMonday, 0, 0, 0, 0, t.Location())
+}
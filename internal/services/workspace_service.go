@@ -0,0 +1,763 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"task-manager/internal/config"
+	"task-manager/internal/database"
+	"task-manager/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrNotWorkspaceMember   = errors.New("you are not a member of this workspace")
+	ErrNotWorkspaceOwner    = errors.New("only workspace owners can do this")
+	ErrLastWorkspaceOwner   = errors.New("cannot remove the last owner of a workspace")
+	ErrAlreadyMember        = errors.New("user is already a member of this workspace")
+	ErrInvitationPending    = errors.New("user already has a pending invitation")
+	ErrInvalidWorkspaceRole = errors.New("workspace role must be owner or member")
+	ErrTeamMemberNotInSpace = errors.New("team members must belong to the workspace")
+	ErrInvalidLLMBaseURL    = errors.New("base_url must be an http(s) URL")
+	ErrLLMKeyRequired       = errors.New("an api_key is required when base_url is overridden")
+	ErrUnknownUrgencyPolicy = errors.New("unknown urgency policy")
+	ErrCalendarNotInSpace   = errors.New("calendar does not belong to this workspace")
+)
+
+type WorkspaceService struct{}
+
+func NewWorkspaceService() *WorkspaceService {
+	return &WorkspaceService{}
+}
+
+// ResolveWorkspace returns the workspace a request acts on. requestedID 0
+// selects the user's oldest workspace, normally their personal one.
+func (s *WorkspaceService) ResolveWorkspace(userID, requestedID uint) (uint, error) {
+	db := database.GetDB()
+
+	if requestedID == 0 {
+		var membership models.WorkspaceMembership
+		if err := db.Where("user_id = ?", userID).Order("workspace_id").First(&membership).Error; err != nil {
+			return 0, ErrNotWorkspaceMember
+		}
+		return membership.WorkspaceID, nil
+	}
+
+	if _, err := s.membership(db, requestedID, userID); err != nil {
+		return 0, err
+	}
+	return requestedID, nil
+}
+
+func (s *WorkspaceService) ListWorkspaces(userID uint) ([]models.WorkspaceSummary, error) {
+	var memberships []models.WorkspaceMembership
+	db := database.GetDB()
+
+	if err := db.Where("user_id = ?", userID).Order("workspace_id").Find(&memberships).Error; err != nil {
+		return nil, fmt.Errorf("failed to get workspaces: %w", err)
+	}
+
+	summaries := make([]models.WorkspaceSummary, 0, len(memberships))
+	for _, membership := range memberships {
+		var workspace models.Workspace
+		if err := db.First(&workspace, membership.WorkspaceID).Error; err != nil {
+			continue
+		}
+		summaries = append(summaries, models.WorkspaceSummary{Workspace: workspace, Role: membership.Role})
+	}
+
+	return summaries, nil
+}
+
+// CreateWorkspace creates a workspace owned by userID.
+func (s *WorkspaceService) CreateWorkspace(userID uint, req models.WorkspaceRequest) (*models.WorkspaceSummary, error) {
+	workspace := models.Workspace{Name: strings.TrimSpace(req.Name), CreatedBy: userID}
+
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&workspace).Error; err != nil {
+			return fmt.Errorf("failed to create workspace: %w", err)
+		}
+		if err := tx.Create(&models.WorkspaceMembership{
+			WorkspaceID: workspace.ID,
+			UserID:      userID,
+			Role:        models.WorkspaceOwner,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to add workspace owner: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.WorkspaceSummary{Workspace: workspace, Role: models.WorkspaceOwner}, nil
+}
+
+func (s *WorkspaceService) GetWorkspace(id, userID uint) (*models.WorkspaceSummary, error) {
+	db := database.GetDB()
+
+	membership, err := s.membership(db, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var workspace models.Workspace
+	if err := db.First(&workspace, id).Error; err != nil {
+		return nil, fmt.Errorf("failed to get workspace: %w", err)
+	}
+
+	return &models.WorkspaceSummary{Workspace: workspace, Role: membership.Role}, nil
+}
+
+func (s *WorkspaceService) RenameWorkspace(id, userID uint, req models.WorkspaceRequest) (*models.WorkspaceSummary, error) {
+	db := database.GetDB()
+
+	if err := s.requireOwner(db, id, userID); err != nil {
+		return nil, err
+	}
+	if err := db.Model(&models.Workspace{}).Where("id = ?", id).Update("name", strings.TrimSpace(req.Name)).Error; err != nil {
+		return nil, fmt.Errorf("failed to rename workspace: %w", err)
+	}
+
+	return s.GetWorkspace(id, userID)
+}
+
+func (s *WorkspaceService) ListMembers(id, userID uint) ([]models.WorkspaceMembership, error) {
+	db := database.GetDB()
+
+	if _, err := s.membership(db, id, userID); err != nil {
+		return nil, err
+	}
+
+	var members []models.WorkspaceMembership
+	if err := db.Preload("User").Where("workspace_id = ?", id).Order("id").Find(&members).Error; err != nil {
+		return nil, fmt.Errorf("failed to get members: %w", err)
+	}
+	return members, nil
+}
+
+// UpdateMemberRole changes a member's workspace role. Only owners may do it
+// and the last owner cannot be demoted.
+func (s *WorkspaceService) UpdateMemberRole(id, actorID, memberID uint, roleName string) (*models.WorkspaceMembership, error) {
+	role, ok := models.ParseWorkspaceRole(roleName)
+	if !ok {
+		return nil, ErrInvalidWorkspaceRole
+	}
+
+	var member *models.WorkspaceMembership
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := s.requireOwner(tx, id, actorID); err != nil {
+			return err
+		}
+
+		var err error
+		member, err = s.membership(tx, id, memberID)
+		if err != nil {
+			return fmt.Errorf("failed to find member: %w", gorm.ErrRecordNotFound)
+		}
+
+		if member.Role == models.WorkspaceOwner && role != models.WorkspaceOwner {
+			if err := s.requireAnotherOwner(tx, id); err != nil {
+				return err
+			}
+		}
+
+		member.Role = role
+		if err := tx.Model(member).Update("role", role).Error; err != nil {
+			return fmt.Errorf("failed to update member role: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return member, nil
+}
+
+// RemoveMember removes memberID from the workspace. Owners can remove
+// anyone; members can only remove themselves.
+func (s *WorkspaceService) RemoveMember(id, actorID, memberID uint) error {
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if actorID != memberID {
+			if err := s.requireOwner(tx, id, actorID); err != nil {
+				return err
+			}
+		}
+
+		member, err := s.membership(tx, id, memberID)
+		if err != nil {
+			return fmt.Errorf("failed to find member: %w", gorm.ErrRecordNotFound)
+		}
+
+		if member.Role == models.WorkspaceOwner {
+			if err := s.requireAnotherOwner(tx, id); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Delete(member).Error; err != nil {
+			return fmt.Errorf("failed to remove member: %w", err)
+		}
+
+		// Drop them from the workspace's teams as well
+		var teamIDs []uint
+		if err := tx.Model(&models.Team{}).Where("workspace_id = ?", id).Pluck("id", &teamIDs).Error; err != nil {
+			return fmt.Errorf("failed to find teams: %w", err)
+		}
+		if len(teamIDs) > 0 {
+			if err := tx.Table("team_members").Where("team_id IN ? AND user_id = ?", teamIDs, memberID).
+				Delete(nil).Error; err != nil {
+				return fmt.Errorf("failed to remove member from teams: %w", err)
+			}
+		}
+
+		// Their timers on the workspace's tasks would otherwise run on
+		return stopTimers(tx, time.Now(), "user_id = ? AND task_id IN (?)", memberID,
+			tx.Model(&models.Task{}).Unscoped().Select("id").Where("workspace_id = ?", id))
+	})
+}
+
+// Invite offers membership to an existing user.
+func (s *WorkspaceService) Invite(id, actorID uint, req models.InvitationRequest) (*models.WorkspaceInvitation, error) {
+	role := models.WorkspaceMember
+	if req.Role != "" {
+		var ok bool
+		if role, ok = models.ParseWorkspaceRole(req.Role); !ok {
+			return nil, ErrInvalidWorkspaceRole
+		}
+	}
+
+	db := database.GetDB()
+	if err := s.requireOwner(db, id, actorID); err != nil {
+		return nil, err
+	}
+
+	var invitee models.User
+	if err := db.Where("username = ?", strings.TrimSpace(req.Username)).First(&invitee).Error; err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+
+	if _, err := s.membership(db, id, invitee.ID); err == nil {
+		return nil, ErrAlreadyMember
+	}
+
+	var pending int64
+	if err := db.Model(&models.WorkspaceInvitation{}).
+		Where("workspace_id = ? AND user_id = ?", id, invitee.ID).
+		Count(&pending).Error; err != nil {
+		return nil, fmt.Errorf("failed to check invitations: %w", err)
+	}
+	if pending > 0 {
+		return nil, ErrInvitationPending
+	}
+
+	invitation := models.WorkspaceInvitation{
+		WorkspaceID: id,
+		UserID:      invitee.ID,
+		Role:        role,
+		InvitedBy:   actorID,
+	}
+	if err := db.Create(&invitation).Error; err != nil {
+		return nil, fmt.Errorf("failed to create invitation: %w", err)
+	}
+
+	if err := db.Preload("Workspace").First(&invitation, invitation.ID).Error; err != nil {
+		return nil, fmt.Errorf("failed to load invitation: %w", err)
+	}
+	return &invitation, nil
+}
+
+// ListWorkspaceInvitations returns the pending invitations of a workspace.
+func (s *WorkspaceService) ListWorkspaceInvitations(id, actorID uint) ([]models.WorkspaceInvitation, error) {
+	db := database.GetDB()
+
+	if err := s.requireOwner(db, id, actorID); err != nil {
+		return nil, err
+	}
+
+	var invitations []models.WorkspaceInvitation
+	if err := db.Preload("Workspace").Where("workspace_id = ?", id).Order("id").Find(&invitations).Error; err != nil {
+		return nil, fmt.Errorf("failed to get invitations: %w", err)
+	}
+	return invitations, nil
+}
+
+func (s *WorkspaceService) RevokeInvitation(id, actorID, invitationID uint) error {
+	db := database.GetDB()
+
+	if err := s.requireOwner(db, id, actorID); err != nil {
+		return err
+	}
+
+	result := db.Where("id = ? AND workspace_id = ?", invitationID, id).Delete(&models.WorkspaceInvitation{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to revoke invitation: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("failed to revoke invitation: %w", gorm.ErrRecordNotFound)
+	}
+	return nil
+}
+
+// ListUserInvitations returns the invitations waiting for userID.
+func (s *WorkspaceService) ListUserInvitations(userID uint) ([]models.WorkspaceInvitation, error) {
+	var invitations []models.WorkspaceInvitation
+	if err := database.GetDB().Preload("Workspace").Where("user_id = ?", userID).Order("id").
+		Find(&invitations).Error; err != nil {
+		return nil, fmt.Errorf("failed to get invitations: %w", err)
+	}
+	return invitations, nil
+}
+
+// AcceptInvitation makes userID a member and removes the invitation.
+func (s *WorkspaceService) AcceptInvitation(invitationID, userID uint) (*models.WorkspaceSummary, error) {
+	var invitation models.WorkspaceInvitation
+
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ? AND user_id = ?", invitationID, userID).First(&invitation).Error; err != nil {
+			return fmt.Errorf("failed to find invitation: %w", err)
+		}
+
+		if _, err := s.membership(tx, invitation.WorkspaceID, userID); err != nil {
+			if err := tx.Create(&models.WorkspaceMembership{
+				WorkspaceID: invitation.WorkspaceID,
+				UserID:      userID,
+				Role:        invitation.Role,
+			}).Error; err != nil {
+				return fmt.Errorf("failed to join workspace: %w", err)
+			}
+		}
+
+		if err := tx.Delete(&invitation).Error; err != nil {
+			return fmt.Errorf("failed to remove invitation: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetWorkspace(invitation.WorkspaceID, userID)
+}
+
+func (s *WorkspaceService) DeclineInvitation(invitationID, userID uint) error {
+	result := database.GetDB().Where("id = ? AND user_id = ?", invitationID, userID).Delete(&models.WorkspaceInvitation{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to decline invitation: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("failed to decline invitation: %w", gorm.ErrRecordNotFound)
+	}
+	return nil
+}
+
+func (s *WorkspaceService) ListTeams(id, userID uint) ([]models.Team, error) {
+	db := database.GetDB()
+
+	if _, err := s.membership(db, id, userID); err != nil {
+		return nil, err
+	}
+
+	var teams []models.Team
+	if err := db.Preload("Members").Where("workspace_id = ?", id).Order("id").Find(&teams).Error; err != nil {
+		return nil, fmt.Errorf("failed to get teams: %w", err)
+	}
+	return teams, nil
+}
+
+func (s *WorkspaceService) CreateTeam(id, actorID uint, req models.TeamRequest) (*models.Team, error) {
+	team := models.Team{WorkspaceID: id}
+	return s.saveTeam(&team, actorID, req)
+}
+
+// UpdateTeam renames a team and replaces its members.
+func (s *WorkspaceService) UpdateTeam(id, actorID, teamID uint, req models.TeamRequest) (*models.Team, error) {
+	var team models.Team
+	if err := database.GetDB().Where("id = ? AND workspace_id = ?", teamID, id).First(&team).Error; err != nil {
+		return nil, fmt.Errorf("failed to find team: %w", err)
+	}
+	return s.saveTeam(&team, actorID, req)
+}
+
+func (s *WorkspaceService) DeleteTeam(id, actorID, teamID uint) error {
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := s.requireOwner(tx, id, actorID); err != nil {
+			return err
+		}
+
+		var team models.Team
+		if err := tx.Where("id = ? AND workspace_id = ?", teamID, id).First(&team).Error; err != nil {
+			return fmt.Errorf("failed to find team: %w", err)
+		}
+		if err := tx.Model(&team).Association("Members").Clear(); err != nil {
+			return fmt.Errorf("failed to remove team members: %w", err)
+		}
+		if err := tx.Delete(&team).Error; err != nil {
+			return fmt.Errorf("failed to delete team: %w", err)
+		}
+		return nil
+	})
+}
+
+func (s *WorkspaceService) saveTeam(team *models.Team, actorID uint, req models.TeamRequest) (*models.Team, error) {
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := s.requireOwner(tx, team.WorkspaceID, actorID); err != nil {
+			return err
+		}
+
+		var members []models.User
+		if len(req.UserIDs) > 0 {
+			if err := tx.Where("id IN (?)", tx.Model(&models.WorkspaceMembership{}).
+				Select("user_id").
+				Where("workspace_id = ? AND user_id IN ?", team.WorkspaceID, req.UserIDs)).
+				Find(&members).Error; err != nil {
+				return fmt.Errorf("failed to find team members: %w", err)
+			}
+			if len(members) != len(uniqueIDs(req.UserIDs)) {
+				return ErrTeamMemberNotInSpace
+			}
+		}
+
+		team.Name = strings.TrimSpace(req.Name)
+		if err := tx.Save(team).Error; err != nil {
+			return fmt.Errorf("failed to save team: %w", err)
+		}
+		if err := tx.Model(team).Association("Members").Replace(members); err != nil {
+			return fmt.Errorf("failed to save team members: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := database.GetDB().Preload("Members").First(team, team.ID).Error; err != nil {
+		return nil, fmt.Errorf("failed to load team: %w", err)
+	}
+	return team, nil
+}
+
+// GetLLMSettings returns the workspace's LLM overrides with the API key
+// masked.
+func (s *WorkspaceService) GetLLMSettings(id, userID uint) (*models.WorkspaceLLMSettings, error) {
+	db := database.GetDB()
+
+	if err := s.requireOwner(db, id, userID); err != nil {
+		return nil, err
+	}
+
+	var workspace models.Workspace
+	if err := db.First(&workspace, id).Error; err != nil {
+		return nil, fmt.Errorf("failed to get workspace: %w", err)
+	}
+
+	apiKey, err := openSecret(workspace.LLMAPIKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.WorkspaceLLMSettings{
+		Model:     workspace.LLMModel,
+		BaseURL:   workspace.LLMBaseURL,
+		APIKey:    config.MaskSecret(apiKey),
+		APIKeySet: apiKey != "",
+		UseLLM:    workspace.UseLLM,
+	}, nil
+}
+
+// UpdateLLMSettings changes the workspace's LLM overrides. A workspace that
+// points base_url elsewhere must bring its own key so the global one is
+// never sent to that server. The key is stored encrypted.
+func (s *WorkspaceService) UpdateLLMSettings(id, userID uint, patch models.WorkspaceLLMPatch) (*models.WorkspaceLLMSettings, error) {
+	db := database.GetDB()
+
+	if err := s.requireOwner(db, id, userID); err != nil {
+		return nil, err
+	}
+
+	var workspace models.Workspace
+	if err := db.First(&workspace, id).Error; err != nil {
+		return nil, fmt.Errorf("failed to get workspace: %w", err)
+	}
+
+	if patch.Model != nil {
+		workspace.LLMModel = strings.TrimSpace(*patch.Model)
+	}
+	if patch.BaseURL != nil {
+		baseURL := strings.TrimSpace(*patch.BaseURL)
+		if baseURL != "" {
+			u, err := url.Parse(baseURL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return nil, ErrInvalidLLMBaseURL
+			}
+		}
+		workspace.LLMBaseURL = baseURL
+	}
+	apiKey, err := openSecret(workspace.LLMAPIKey)
+	if err != nil {
+		return nil, err
+	}
+	// A masked key echoed back from GetLLMSettings is not a change
+	if patch.APIKey != nil && *patch.APIKey != config.MaskSecret(apiKey) {
+		apiKey = *patch.APIKey
+	}
+	if workspace.LLMBaseURL != "" && apiKey == "" {
+		return nil, ErrLLMKeyRequired
+	}
+	if workspace.LLMAPIKey, err = sealSecret(apiKey); err != nil {
+		return nil, err
+	}
+	if patch.UseLLM != nil {
+		workspace.UseLLM = patch.UseLLM
+	}
+	if patch.InheritUseLLM {
+		workspace.UseLLM = nil
+	}
+
+	if err := db.Model(&workspace).
+		Select("LLMModel", "LLMBaseURL", "LLMAPIKey", "UseLLM").
+		Updates(&workspace).Error; err != nil {
+		return nil, fmt.Errorf("failed to save LLM settings: %w", err)
+	}
+
+	return s.GetLLMSettings(id, userID)
+}
+
+// GetUrgencyPolicy reports the workspace's urgency policy. A policy that was
+// removed from the config falls back to the default one.
+func (s *WorkspaceService) GetUrgencyPolicy(id, userID uint) (*models.WorkspaceUrgencyPolicy, error) {
+	db := database.GetDB()
+
+	if _, err := s.membership(db, id, userID); err != nil {
+		return nil, err
+	}
+
+	var workspace models.Workspace
+	if err := db.First(&workspace, id).Error; err != nil {
+		return nil, fmt.Errorf("failed to get workspace: %w", err)
+	}
+
+	cfg := config.GetConfig().Urgency
+	available := make([]string, 0, len(cfg.Policies))
+	for _, policy := range cfg.Policies {
+		available = append(available, policy.Name)
+	}
+
+	return &models.WorkspaceUrgencyPolicy{
+		Policy:    workspace.UrgencyPolicy,
+		Effective: cfg.Policy(workspace.UrgencyPolicy).Name,
+		Available: available,
+	}, nil
+}
+
+func (s *WorkspaceService) SetUrgencyPolicy(id, userID uint, req models.UrgencyPolicyRequest) (*models.WorkspaceUrgencyPolicy, error) {
+	db := database.GetDB()
+
+	if err := s.requireOwner(db, id, userID); err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSpace(req.Policy)
+	if name != "" && !config.GetConfig().Urgency.HasPolicy(name) {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownUrgencyPolicy, name)
+	}
+
+	if err := db.Model(&models.Workspace{}).Where("id = ?", id).Update("urgency_policy", name).Error; err != nil {
+		return nil, fmt.Errorf("failed to save urgency policy: %w", err)
+	}
+
+	return s.GetUrgencyPolicy(id, userID)
+}
+
+// SetCalendar picks the calendar whose working time the workspace counts. A
+// nil calendarID goes back to wall-clock time.
+func (s *WorkspaceService) SetCalendar(id, userID uint, calendarID *uint) (*models.WorkspaceSummary, error) {
+	db := database.GetDB()
+
+	if err := s.requireOwner(db, id, userID); err != nil {
+		return nil, err
+	}
+
+	if err := s.requireCalendar(db, id, calendarID); err != nil {
+		return nil, err
+	}
+
+	if err := db.Model(&models.Workspace{}).Where("id = ?", id).Update("calendar_id", calendarID).Error; err != nil {
+		return nil, fmt.Errorf("failed to save calendar: %w", err)
+	}
+
+	if err := replanWorkspace(db, id); err != nil {
+		return nil, err
+	}
+
+	return s.GetWorkspace(id, userID)
+}
+
+// SetMemberCalendar picks the calendar a member works by; nil falls back to
+// the workspace's. Owners may set it for anyone, members for themselves.
+func (s *WorkspaceService) SetMemberCalendar(id, actorID, memberID uint, calendarID *uint) (*models.WorkspaceMembership, error) {
+	db := database.GetDB()
+
+	if actorID != memberID {
+		if err := s.requireOwner(db, id, actorID); err != nil {
+			return nil, err
+		}
+	}
+
+	member, err := s.membership(db, id, memberID)
+	if err != nil {
+		if errors.Is(err, ErrNotWorkspaceMember) {
+			return nil, fmt.Errorf("failed to find member: %w", gorm.ErrRecordNotFound)
+		}
+		return nil, err
+	}
+
+	if err := s.requireCalendar(db, id, calendarID); err != nil {
+		return nil, err
+	}
+
+	if err := db.Model(member).Update("calendar_id", calendarID).Error; err != nil {
+		return nil, fmt.Errorf("failed to save member calendar: %w", err)
+	}
+
+	if err := replanWorkspace(db, id); err != nil {
+		return nil, err
+	}
+
+	if err := db.Preload("User").First(member, member.ID).Error; err != nil {
+		return nil, fmt.Errorf("failed to get member: %w", err)
+	}
+	return member, nil
+}
+
+// requireCalendar checks that calendarID, when set, belongs to the
+// workspace.
+func (s *WorkspaceService) requireCalendar(db *gorm.DB, workspaceID uint, calendarID *uint) error {
+	if calendarID == nil {
+		return nil
+	}
+
+	var count int64
+	if err := db.Model(&models.Calendar{}).Where("id = ? AND workspace_id = ?", *calendarID, workspaceID).
+		Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to find calendar: %w", err)
+	}
+	if count == 0 {
+		return ErrCalendarNotInSpace
+	}
+	return nil
+}
+
+// LLMOverride returns a function that applies the workspace's LLM settings
+// on top of the global ones. A workspace base_url is only ever sent the
+// workspace's own key.
+func (s *WorkspaceService) LLMOverride(id uint) (func(*config.OpenAIConfig), error) {
+	var workspace models.Workspace
+	if err := database.GetDB().First(&workspace, id).Error; err != nil {
+		return nil, fmt.Errorf("failed to get workspace: %w", err)
+	}
+	apiKey, err := openSecret(workspace.LLMAPIKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(settings *config.OpenAIConfig) {
+		if workspace.LLMModel != "" {
+			settings.Model = workspace.LLMModel
+		}
+		// The global key only ever goes to the global base_url
+		if workspace.LLMBaseURL != "" {
+			settings.BaseURL = workspace.LLMBaseURL
+			settings.APIKey = ""
+		}
+		if apiKey != "" {
+			settings.APIKey = apiKey
+		}
+		if workspace.UseLLM != nil {
+			settings.UseLLM = *workspace.UseLLM
+		}
+	}, nil
+}
+
+// SealLLMKeys encrypts workspace LLM keys stored before keys were encrypted.
+func (s *WorkspaceService) SealLLMKeys() error {
+	db := database.GetDB()
+
+	var workspaces []models.Workspace
+	if err := db.Where("llm_api_key <> '' AND llm_api_key NOT LIKE ?", sealedPrefix+"%").Find(&workspaces).Error; err != nil {
+		return fmt.Errorf("failed to find workspace keys: %w", err)
+	}
+
+	for _, workspace := range workspaces {
+		sealed, err := sealSecret(workspace.LLMAPIKey)
+		if err != nil {
+			return err
+		}
+		if err := db.Model(&workspace).Update("llm_api_key", sealed).Error; err != nil {
+			return fmt.Errorf("failed to encrypt workspace key: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (s *WorkspaceService) membership(db *gorm.DB, workspaceID, userID uint) (*models.WorkspaceMembership, error) {
+	var membership models.WorkspaceMembership
+	err := db.Where("workspace_id = ? AND user_id = ?", workspaceID, userID).First(&membership).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotWorkspaceMember
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to check workspace membership: %w", err)
+	}
+	return &membership, nil
+}
+
+// IsOwner reports whether a user owns a workspace.
+func (s *WorkspaceService) IsOwner(workspaceID, userID uint) (bool, error) {
+	err := s.requireOwner(database.GetDB(), workspaceID, userID)
+	if errors.Is(err, ErrNotWorkspaceOwner) || errors.Is(err, ErrNotWorkspaceMember) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (s *WorkspaceService) requireOwner(db *gorm.DB, workspaceID, userID uint) error {
+	membership, err := s.membership(db, workspaceID, userID)
+	if err != nil {
+		return err
+	}
+	if membership.Role != models.WorkspaceOwner {
+		return ErrNotWorkspaceOwner
+	}
+	return nil
+}
+
+func (s *WorkspaceService) requireAnotherOwner(db *gorm.DB, workspaceID uint) error {
+	var owners int64
+	if err := db.Model(&models.WorkspaceMembership{}).
+		Where("workspace_id = ? AND role = ?", workspaceID, models.WorkspaceOwner).
+		Count(&owners).Error; err != nil {
+		return fmt.Errorf("failed to count owners: %w", err)
+	}
+	if owners <= 1 {
+		return ErrLastWorkspaceOwner
+	}
+	return nil
+}
+
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	unique := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
@@ -1,41 +1,96 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	"log"
+	"net/http"
+	"os"
+	"strings"
 	"task-manager/internal/config"
 	"task-manager/internal/database"
 	"task-manager/internal/handlers"
+	"task-manager/internal/middleware"
+	"task-manager/internal/services"
 
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
+	defaultConfigPath := "configs/config.yaml"
+	if path := os.Getenv("TASKMANAGER_CONFIG"); path != "" {
+		defaultConfigPath = path
+	}
+	configPath := flag.String("config", defaultConfigPath, "path to the YAML config file")
+	flag.Parse()
+
 	// Load configuration
-	if err := config.LoadConfig("configs/config.yaml"); err != nil {
+	if err := config.LoadConfig(*configPath); err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
+	// Admin commands run instead of the server
+	if flag.NArg() > 0 {
+		if err := runCommand(flag.Arg(0), flag.Args()[1:]); err != nil {
+			log.Fatalf("%s failed: %v", flag.Arg(0), err)
+		}
+		return
+	}
+
 	// Initialize database
 	if err := database.InitDatabase(); err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
 
+	// Make sure tokens can be signed
+	if err := config.EnsureJWTSecret(); err != nil {
+		log.Fatalf("Failed to set up authentication: %v", err)
+	}
+
+	// Make sure secrets stored in the database are encrypted
+	if err := config.EnsureEncryptionKey(); err != nil {
+		log.Fatalf("Failed to set up encryption: %v", err)
+	}
+	if err := services.NewWorkspaceService().SealLLMKeys(); err != nil {
+		log.Fatalf("Failed to encrypt workspace LLM keys: %v", err)
+	}
+
+	// Hot reload configuration. Most settings are read per request; the
+	// listen port and database connection need a restart.
+	config.Subscribe(func(old, new *config.Config) {
+		if old.Server.Port != new.Server.Port || old.Server.TLS != new.Server.TLS ||
+			strings.Join(old.Server.TrustedProxies, ",") != strings.Join(new.Server.TrustedProxies, ",") ||
+			old.Database.Type != new.Database.Type || old.Database.Name != new.Database.Name {
+			log.Printf("Server port, TLS, trusted proxies or database settings changed, restart to apply them")
+		}
+	})
+	config.Watch(config.GetConfig().Server.ConfigWatchInterval)
+
+	// Start scheduled backups
+	database.StartBackupSchedule()
+
+	// Start trash retention and priority recalculation jobs
+	taskService := services.NewTaskService()
+	taskService.StartTrashRetention()
+	taskService.StartPriorityRecalculation()
+	taskService.StartRecurrenceGeneration()
+
 	// Set up Gin router
 	router := gin.Default()
+	if err := router.SetTrustedProxies(config.GetConfig().Server.TrustedProxies); err != nil {
+		log.Fatalf("Failed to set trusted proxies: %v", err)
+	}
 
-	// Enable CORS
-	router.Use(func(c *gin.Context) {
-		c.Header("Access-Control-Allow-Origin", "*")
-		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization")
+	// CORS, security headers, request size and rate limits
+	router.Use(middleware.SecurityHeaders(), middleware.CORS(), middleware.BodyLimit(), middleware.RateLimit())
 
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(204)
-			return
-		}
+	// Register auth routes
+	authHandler := handlers.NewAuthHandler()
+	authHandler.RegisterRoutes(router)
 
-		c.Next()
-	})
+	// Register personal access token routes
+	tokenHandler := handlers.NewTokenHandler()
+	tokenHandler.RegisterRoutes(router)
 
 	// Register routes
 	taskHandler := handlers.NewTaskHandler()
@@ -45,6 +100,38 @@ func main() {
 	configHandler := handlers.NewConfigHandler()
 	configHandler.RegisterRoutes(router)
 
+	// Register export/import routes
+	exportHandler := handlers.NewExportHandler()
+	exportHandler.RegisterRoutes(router)
+
+	// Register backup routes
+	backupHandler := handlers.NewBackupHandler()
+	backupHandler.RegisterRoutes(router)
+
+	// Register workspace routes
+	workspaceHandler := handlers.NewWorkspaceHandler()
+	workspaceHandler.RegisterRoutes(router)
+
+	// Register working calendar routes
+	calendarHandler := handlers.NewCalendarHandler()
+	calendarHandler.RegisterRoutes(router)
+
+	// Register time tracking routes
+	timeHandler := handlers.NewTimeHandler()
+	timeHandler.RegisterRoutes(router)
+
+	// Register scheduling routes
+	scheduleHandler := handlers.NewScheduleHandler()
+	scheduleHandler.RegisterRoutes(router)
+
+	// Register insights routes
+	insightsHandler := handlers.NewInsightsHandler()
+	insightsHandler.RegisterRoutes(router)
+
+	// Register user and workload routes
+	userHandler := handlers.NewUserHandler()
+	userHandler.RegisterRoutes(router)
+
 	// Health check endpoint
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{
@@ -55,8 +142,54 @@ func main() {
 
 	// Start server
 	cfg := config.GetConfig()
+	if tls := cfg.Server.TLS; tls.Enabled {
+		if tls.RedirectHTTP {
+			go func() {
+				log.Printf("Redirecting HTTP on port %s to HTTPS", tls.HTTPPort)
+				if err := http.ListenAndServe(":"+tls.HTTPPort, middleware.RedirectToHTTPS(cfg.Server.Port)); err != nil {
+					log.Printf("HTTP redirect server stopped: %v", err)
+				}
+			}()
+		}
+
+		log.Printf("Starting HTTPS server on port %s", cfg.Server.Port)
+		if err := router.RunTLS(":"+cfg.Server.Port, tls.CertFile, tls.KeyFile); err != nil {
+			log.Fatalf("Failed to start server: %v", err)
+		}
+		return
+	}
+
 	log.Printf("Starting server on port %s", cfg.Server.Port)
 	if err := router.Run(":" + cfg.Server.Port); err != nil {
 		log.Fatalf("Failed to start server: %v", err)
 	}
 }
+
+func runCommand(name string, args []string) error {
+	switch name {
+	case "backup":
+		// A new process would open a fresh, empty in-memory database
+		if database.IsMemoryDatabase() {
+			return fmt.Errorf("cannot back up an in-memory database from the command line, use POST /api/v1/admin/backups on the running server")
+		}
+		if err := database.InitDatabase(); err != nil {
+			return err
+		}
+		backup, err := database.Backup()
+		if err != nil {
+			return err
+		}
+		log.Printf("Backup written to %s", backup.Path)
+	case "restore":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: restore <backup-file>")
+		}
+		if err := database.Restore(args[0]); err != nil {
+			return err
+		}
+		log.Printf("Database restored from %s", args[0])
+	default:
+		return fmt.Errorf("unknown command %q, expected backup or restore", name)
+	}
+	return nil
+}